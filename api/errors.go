@@ -36,4 +36,13 @@ var (
 		"not configured with entry credits")
 	ErrorPendingDisabled = jsonrpc2.NewError(-32807, "Pending Transactions Disabled",
 		"fatd is not tracking pending transactions")
+	ErrorSubscriptionNotFound = jsonrpc2.NewError(-32808, "Subscription Not Found",
+		"no matching subscription was found")
+	ErrorUnauthorized = jsonrpc2.NewError(-32809, "Unauthorized",
+		"API key does not have the required scope")
+	ErrorRateLimited = jsonrpc2.NewError(-32810, "Rate Limited", nil)
+	ErrorFactomd     = jsonrpc2.NewError(-32811, "Factomd Error", nil)
+
+	ErrorTooManySubscriptions = jsonrpc2.NewError(-32812, "Too Many Subscriptions",
+		"unsubscribe from an existing subscription first")
 )
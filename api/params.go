@@ -23,6 +23,11 @@
 package api
 
 import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"regexp"
 	"strings"
 	"time"
 
@@ -82,13 +87,38 @@ func (p ParamsToken) ValidChainID() *factom.Bytes32 {
 	return p.ChainID
 }
 
+// ParamsPagination selects a page of results using either Page and Limit, or
+// a Cursor and Limit.
+//
+// A Cursor, when used, is the "nextcursor" returned with the previous page of
+// results, or an empty string for the first page. Unlike Page, a Cursor is
+// not affected by new results that arrive while paging. Methods that support
+// a Cursor return their results in a ResultPage when one is used.
 type ParamsPagination struct {
-	Page  *uint  `json:"page,omitempty"`
-	Limit uint   `json:"limit,omitempty"`
-	Order string `json:"order,omitempty"`
+	Page   *uint   `json:"page,omitempty"`
+	Limit  uint    `json:"limit,omitempty"`
+	Order  string  `json:"order,omitempty"`
+	Cursor *string `json:"cursor,omitempty"`
+
+	cursorID *int64
 }
 
 func (p *ParamsPagination) IsValid() error {
+	if p.Cursor != nil {
+		if p.Page != nil {
+			return jsonrpc2.ErrorInvalidParams(
+				`cannot use "page" with "cursor"`)
+		}
+		if len(*p.Cursor) > 0 {
+			key, err := base64.RawURLEncoding.DecodeString(*p.Cursor)
+			if err != nil || len(key) != 8 {
+				return jsonrpc2.ErrorInvalidParams(
+					`invalid "cursor"`)
+			}
+			p.cursorID = new(int64)
+			*p.cursorID = int64(binary.BigEndian.Uint64(key))
+		}
+	}
 	if p.Page == nil {
 		p.Page = new(uint)
 		*p.Page = 1
@@ -111,11 +141,73 @@ func (p *ParamsPagination) IsValid() error {
 	return nil
 }
 
+// UseCursor returns true if a Cursor was provided.
+func (p ParamsPagination) UseCursor() bool {
+	return p.Cursor != nil
+}
+
+// CursorID returns the id of the last result of the previous page, which is
+// nil for the first page. IsValid must be called first.
+func (p ParamsPagination) CursorID() *int64 {
+	return p.cursorID
+}
+
+// isValidWithoutCursor returns an error if a Cursor is used with a method
+// that does not support one.
+func (p ParamsPagination) isValidWithoutCursor() error {
+	if p.UseCursor() {
+		return jsonrpc2.ErrorInvalidParams(`"cursor" is not supported`)
+	}
+	return nil
+}
+
+// NewCursor returns the opaque cursor for the page of results following the
+// result with the given id.
+func NewCursor(id int64) string {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(id))
+	return base64.RawURLEncoding.EncodeToString(key)
+}
+
+// ParamsHeight optionally scopes a request to the state of a token as of a
+// particular DBlock, identified by either its Height or its Timestamp.
+type ParamsHeight struct {
+	Height    *uint32 `json:"height,omitempty"`
+	Timestamp *int64  `json:"timestamp,omitempty"`
+}
+
+func (p ParamsHeight) IsValid() error {
+	if p.Height != nil && p.Timestamp != nil {
+		return jsonrpc2.ErrorInvalidParams(
+			`cannot use "height" with "timestamp"`)
+	}
+	return nil
+}
+
+// IsSet returns true if either Height or Timestamp is set.
+func (p ParamsHeight) IsSet() bool {
+	return p.Height != nil || p.Timestamp != nil
+}
+
+// isValidWithPending returns an error if p is set along with includePending,
+// since pending entries are not part of any DBlock.
+func (p ParamsHeight) isValidWithPending(includePending bool) error {
+	if err := p.IsValid(); err != nil {
+		return err
+	}
+	if includePending && p.IsSet() {
+		return jsonrpc2.ErrorInvalidParams(
+			`cannot use "includepending" with "height" or "timestamp"`)
+	}
+	return nil
+}
+
 // ParamsGetTransaction is used to query for a single particular transaction
 // with the given Entry Hash.
 type ParamsGetTransaction struct {
 	ParamsToken
-	Hash *factom.Bytes32 `json:"entryhash"`
+	Hash           *factom.Bytes32 `json:"entryhash"`
+	IncludeInvalid bool            `json:"includeinvalid,omitempty"`
 }
 
 func (p ParamsGetTransaction) IsValid() error {
@@ -128,6 +220,27 @@ func (p ParamsGetTransaction) IsValid() error {
 	return nil
 }
 
+// ParamsGetTransactionProof is used to request proof that the transaction
+// with the given Entry Hash is anchored in the Factom blockchain.
+type ParamsGetTransactionProof struct {
+	ParamsGetTransaction
+}
+
+func (p ParamsGetTransactionProof) IsValid() error {
+	if err := p.ParamsGetTransaction.IsValid(); err != nil {
+		return err
+	}
+	if p.IncludePending {
+		return jsonrpc2.ErrorInvalidParams(
+			`"includepending" is not supported`)
+	}
+	if p.IncludeInvalid {
+		return jsonrpc2.ErrorInvalidParams(
+			`"includeinvalid" is not supported`)
+	}
+	return nil
+}
+
 type ParamsGetTransactions struct {
 	ParamsToken
 	ParamsPagination
@@ -136,6 +249,13 @@ type ParamsGetTransactions struct {
 	Addresses []factom.FAAddress `json:"addresses,omitempty"`
 	StartHash *factom.Bytes32    `json:"entryhash,omitempty"`
 	ToFrom    string             `json:"tofrom,omitempty"`
+
+	// Inclusive bounds on the DBlock height and the timestamp, in unix
+	// seconds, of the returned transactions.
+	FromHeight    *uint32 `json:"fromheight,omitempty"`
+	ToHeight      *uint32 `json:"toheight,omitempty"`
+	FromTimestamp *int64  `json:"fromtimestamp,omitempty"`
+	ToTimestamp   *int64  `json:"totimestamp,omitempty"`
 }
 
 func (p *ParamsGetTransactions) IsValid() error {
@@ -159,6 +279,17 @@ func (p *ParamsGetTransactions) IsValid() error {
 		return jsonrpc2.ErrorInvalidParams(
 			`"tofrom" value must be either "to" or "from"`)
 	}
+
+	if p.FromHeight != nil && p.ToHeight != nil &&
+		*p.FromHeight > *p.ToHeight {
+		return jsonrpc2.ErrorInvalidParams(
+			`"fromheight" may not be greater than "toheight"`)
+	}
+	if p.FromTimestamp != nil && p.ToTimestamp != nil &&
+		*p.FromTimestamp > *p.ToTimestamp {
+		return jsonrpc2.ErrorInvalidParams(
+			`"fromtimestamp" may not be greater than "totimestamp"`)
+	}
 	return nil
 }
 
@@ -177,8 +308,26 @@ func (p ParamsGetNFToken) IsValid() error {
 	return nil
 }
 
+// ParamsGetInvalidTransactions is used to query for invalid transactions along
+// with the reasons they were rejected.
+type ParamsGetInvalidTransactions struct {
+	ParamsToken
+	ParamsPagination
+}
+
+func (p *ParamsGetInvalidTransactions) IsValid() error {
+	if err := p.ParamsToken.IsValid(); err != nil {
+		return err
+	}
+	if err := p.ParamsPagination.IsValid(); err != nil {
+		return err
+	}
+	return p.isValidWithoutCursor()
+}
+
 type ParamsGetBalance struct {
 	ParamsToken
+	ParamsHeight
 	Address *factom.FAAddress `json:"address,omitempty"`
 }
 
@@ -186,6 +335,9 @@ func (p ParamsGetBalance) IsValid() error {
 	if err := p.ParamsToken.IsValid(); err != nil {
 		return err
 	}
+	if err := p.ParamsHeight.isValidWithPending(p.IncludePending); err != nil {
+		return err
+	}
 	if p.Address == nil {
 		return jsonrpc2.ErrorInvalidParams(`required: "address"`)
 	}
@@ -193,6 +345,7 @@ func (p ParamsGetBalance) IsValid() error {
 }
 
 type ParamsGetBalances struct {
+	ParamsHeight
 	Address        *factom.FAAddress `json:"address,omitempty"`
 	IncludePending bool              `json:"includepending,omitempty"`
 }
@@ -203,7 +356,7 @@ func (p ParamsGetBalances) IsValid() error {
 	if p.Address == nil {
 		return jsonrpc2.ErrorInvalidParams(`required: "address"`)
 	}
-	return nil
+	return p.ParamsHeight.isValidWithPending(p.IncludePending)
 }
 func (p ParamsGetBalances) ValidChainID() *factom.Bytes32 {
 	return nil
@@ -212,6 +365,7 @@ func (p ParamsGetBalances) ValidChainID() *factom.Bytes32 {
 type ParamsGetNFBalance struct {
 	ParamsToken
 	ParamsPagination
+	ParamsHeight
 	Address *factom.FAAddress `json:"address,omitempty"`
 }
 
@@ -219,6 +373,9 @@ func (p *ParamsGetNFBalance) IsValid() error {
 	if err := p.ParamsToken.IsValid(); err != nil {
 		return err
 	}
+	if err := p.ParamsHeight.isValidWithPending(p.IncludePending); err != nil {
+		return err
+	}
 	if err := p.ParamsPagination.IsValid(); err != nil {
 		return err
 	}
@@ -228,9 +385,75 @@ func (p *ParamsGetNFBalance) IsValid() error {
 	return nil
 }
 
+type ParamsGetStats struct {
+	ParamsToken
+	ParamsHeight
+}
+
+func (p ParamsGetStats) IsValid() error {
+	if err := p.ParamsToken.IsValid(); err != nil {
+		return err
+	}
+	return p.ParamsHeight.isValidWithPending(p.IncludePending)
+}
+
+type ParamsGetStateHash struct {
+	ParamsToken
+	ParamsHeight
+}
+
+func (p ParamsGetStateHash) IsValid() error {
+	if err := p.ParamsToken.IsValid(); err != nil {
+		return err
+	}
+	return p.ParamsHeight.isValidWithPending(p.IncludePending)
+}
+
+// ParamsGetHolders is used to get a page of the addresses holding a token,
+// ordered by balance, along with a summary of the distribution of balances.
+// The Order defaults to "desc" so that the largest holders are first.
+type ParamsGetHolders struct {
+	ParamsToken
+	ParamsPagination
+
+	// Top is the number of largest holders to compute the share of the
+	// circulating supply for. Defaults to 10.
+	Top uint `json:"top,omitempty"`
+
+	// NFTokenCount includes the number of NFTokens held by each address.
+	// Only valid for FAT-1 tokens.
+	NFTokenCount bool `json:"nftokencount,omitempty"`
+}
+
+func (p *ParamsGetHolders) IsValid() error {
+	if err := p.ParamsToken.IsValid(); err != nil {
+		return err
+	}
+	if err := p.ParamsPagination.IsValid(); err != nil {
+		return err
+	}
+	if err := p.isValidWithoutCursor(); err != nil {
+		return err
+	}
+	if len(p.Order) == 0 {
+		p.Order = "desc"
+	}
+	if p.Top == 0 {
+		p.Top = 10
+	}
+	return nil
+}
+
 type ParamsGetAllNFTokens struct {
 	ParamsToken
 	ParamsPagination
+
+	// MetadataFilter is a list of predicates on the NFToken metadata,
+	// which must all be true for an NFToken to be returned. See
+	// ParseMetadataFilter for the syntax.
+	MetadataFilter []string `json:"metadatafilter,omitempty"`
+
+	filters []MetadataFilter
 }
 
 func (p *ParamsGetAllNFTokens) IsValid() error {
@@ -240,6 +463,120 @@ func (p *ParamsGetAllNFTokens) IsValid() error {
 	if err := p.ParamsPagination.IsValid(); err != nil {
 		return err
 	}
+	p.filters = make([]MetadataFilter, len(p.MetadataFilter))
+	for i, expr := range p.MetadataFilter {
+		f, err := ParseMetadataFilter(expr)
+		if err != nil {
+			return jsonrpc2.ErrorInvalidParams(
+				fmt.Sprintf(`"metadatafilter"[%v]: %v`, i, err))
+		}
+		p.filters[i] = f
+	}
+	return nil
+}
+
+// Filters returns the parsed MetadataFilter. IsValid must be called first.
+func (p ParamsGetAllNFTokens) Filters() []MetadataFilter {
+	return p.filters
+}
+
+// MetadataFilter is a predicate comparing the value at a JSON Path within
+// NFToken metadata to a JSON scalar Value.
+type MetadataFilter struct {
+	Path  string
+	Op    string
+	Value interface{}
+}
+
+var (
+	metadataFilterRegexp = regexp.MustCompile(
+		`^\s*(\$\S*?)\s*(==|!=|<=|>=|<|>)\s*(.+?)\s*$`)
+	jsonPathRegexp = regexp.MustCompile(
+		`^\$(\.[A-Za-z_][A-Za-z0-9_]*|\."[^"]*"|\[[0-9]+\])*$`)
+)
+
+// ParseMetadataFilter parses a MetadataFilter from an expression of the form
+// `<path> <op> <value>`, for example `$.rarity == "gold"`.
+//
+// The path must start with "$" and may only use object keys and array
+// indexes, such as `$.stats.power` or `$.tags[0]`. The op must be one of
+// "==", "!=", "<", "<=", ">" or ">=". The value must be a JSON string, number,
+// boolean or null. A null value may only be used with "==" or "!=" to match
+// metadata with or without a value at the path.
+func ParseMetadataFilter(expr string) (MetadataFilter, error) {
+	var f MetadataFilter
+	m := metadataFilterRegexp.FindStringSubmatch(expr)
+	if m == nil {
+		return f, fmt.Errorf(`expected "<path> <op> <value>"`)
+	}
+	f.Path, f.Op = m[1], m[2]
+	if !jsonPathRegexp.MatchString(f.Path) {
+		return f, fmt.Errorf("invalid JSON path: %q", f.Path)
+	}
+	if err := json.Unmarshal([]byte(m[3]), &f.Value); err != nil {
+		return f, fmt.Errorf("invalid JSON value: %w", err)
+	}
+	switch f.Value.(type) {
+	case string, float64, bool:
+	case nil:
+		if f.Op != "==" && f.Op != "!=" {
+			return f, fmt.Errorf(
+				`null may only be compared with "==" or "!="`)
+		}
+	default:
+		return f, fmt.Errorf("value must be a string, number, boolean or null")
+	}
+	return f, nil
+}
+
+// ParamsSearchNFTokens is used to search the string values within the
+// metadata of the NFTokens of a FAT-1 token. Results are ordered by
+// relevance, so "order" may not be used.
+type ParamsSearchNFTokens struct {
+	ParamsToken
+	ParamsPagination
+
+	// Query is a list of whitespace separated terms, which must all be
+	// present. A term ending in "*" matches any word with that prefix.
+	Query string `json:"query,omitempty"`
+}
+
+func (p *ParamsSearchNFTokens) IsValid() error {
+	if err := p.ParamsToken.IsValid(); err != nil {
+		return err
+	}
+	if err := p.ParamsPagination.IsValid(); err != nil {
+		return err
+	}
+	if err := p.isValidWithoutCursor(); err != nil {
+		return err
+	}
+	if len(p.Order) > 0 {
+		return jsonrpc2.ErrorInvalidParams(
+			`"order" may not be used, results are ordered by relevance`)
+	}
+	return validSearchQuery(p.Query)
+}
+
+// ParamsSearchTokens is used to search the TokenID, Symbol, and the string
+// values within the Issuance metadata of all issued tokens tracked by fatd.
+type ParamsSearchTokens struct {
+	// Query has the same syntax as ParamsSearchNFTokens.Query.
+	Query string `json:"query,omitempty"`
+
+	IncludePending bool `json:"includepending,omitempty"`
+}
+
+func (p ParamsSearchTokens) IsValid() error {
+	return validSearchQuery(p.Query)
+}
+func (p ParamsSearchTokens) GetIncludePending() bool       { return p.IncludePending }
+func (p ParamsSearchTokens) ValidChainID() *factom.Bytes32 { return nil }
+
+func validSearchQuery(query string) error {
+	if len(strings.Trim(query, " \t\n\r*")) == 0 {
+		return jsonrpc2.ErrorInvalidParams(`required: "query"`)
+	}
 	return nil
 }
 
@@ -294,3 +631,141 @@ func (p *ParamsSendTransaction) IsValid() error {
 func (p ParamsSendTransaction) Entry() factom.Entry {
 	return p.entry
 }
+
+// ParamsComposeTransaction is used to compose the content of an unsigned
+// transaction so that it may be signed offline. The Inputs, Outputs and
+// TokenMetadata must be formatted for the type of the token.
+type ParamsComposeTransaction struct {
+	ParamsToken
+	Inputs        json.RawMessage `json:"inputs"`
+	Outputs       json.RawMessage `json:"outputs"`
+	TokenMetadata json.RawMessage `json:"tokenmetadata,omitempty"`
+	Metadata      json.RawMessage `json:"metadata,omitempty"`
+}
+
+func (p ParamsComposeTransaction) IsValid() error {
+	if err := p.ParamsToken.IsValid(); err != nil {
+		return err
+	}
+	if p.IncludePending {
+		return jsonrpc2.ErrorInvalidParams(
+			`"includepending" is not supported`)
+	}
+	if len(p.Inputs) == 0 || len(p.Outputs) == 0 {
+		return jsonrpc2.ErrorInvalidParams(`required: "inputs" and "outputs"`)
+	}
+	return nil
+}
+
+// RCDSignature is an RCD and the signature of the message hash for its
+// RCD/Sig ID, as returned by compose-transaction.
+type RCDSignature struct {
+	RCD       factom.Bytes `json:"rcd"`
+	Signature factom.Bytes `json:"signature"`
+}
+
+// ParamsAssembleTransaction is used to assemble the final transaction entry
+// from the Content and TimestampSalt returned by compose-transaction and the
+// Signatures of the returned message hashes, in the same order.
+type ParamsAssembleTransaction struct {
+	ParamsToken
+	Content       factom.Bytes   `json:"content"`
+	TimestampSalt string         `json:"timestampsalt"`
+	Signatures    []RCDSignature `json:"signatures"`
+}
+
+func (p ParamsAssembleTransaction) IsValid() error {
+	if err := p.ParamsToken.IsValid(); err != nil {
+		return err
+	}
+	if p.IncludePending {
+		return jsonrpc2.ErrorInvalidParams(
+			`"includepending" is not supported`)
+	}
+	if len(p.Content) == 0 || len(p.TimestampSalt) == 0 ||
+		len(p.Signatures) == 0 {
+		return jsonrpc2.ErrorInvalidParams(
+			`required: "content", "timestampsalt" and "signatures"`)
+	}
+	for i, sig := range p.Signatures {
+		if len(sig.RCD) == 0 || len(sig.Signature) == 0 {
+			return jsonrpc2.ErrorInvalidParams(fmt.Sprintf(
+				`"signatures"[%v]: required: "rcd" and "signature"`,
+				i))
+		}
+	}
+	return nil
+}
+
+// ParamsGetSubmissionStatus is used to look up a transaction submitted with
+// send-transaction by either its entry hash or its commit txid.
+type ParamsGetSubmissionStatus struct {
+	Hash *factom.Bytes32 `json:"entryhash,omitempty"`
+	TxID *factom.Bytes32 `json:"txid,omitempty"`
+}
+
+func (p ParamsGetSubmissionStatus) IsValid() error {
+	if (p.Hash == nil) == (p.TxID == nil) {
+		return jsonrpc2.ErrorInvalidParams(
+			`required: exactly one of "entryhash" or "txid"`)
+	}
+	return nil
+}
+func (p ParamsGetSubmissionStatus) GetIncludePending() bool       { return false }
+func (p ParamsGetSubmissionStatus) ValidChainID() *factom.Bytes32 { return nil }
+
+type ParamsSubscribeAddress struct {
+	ParamsToken
+	Address *factom.FAAddress `json:"address,omitempty"`
+}
+
+func (p ParamsSubscribeAddress) IsValid() error {
+	if p.Address == nil {
+		return jsonrpc2.ErrorInvalidParams(`required: "address"`)
+	}
+	if p.ChainID == nil && len(p.TokenID) == 0 && p.IssuerChainID == nil {
+		// Subscribe to all chains.
+		return nil
+	}
+	return p.ParamsToken.IsValid()
+}
+
+func (p ParamsSubscribeAddress) ValidChainID() *factom.Bytes32 {
+	if p.ChainID == nil && len(p.TokenID) == 0 && p.IssuerChainID == nil {
+		return nil
+	}
+	return p.ParamsToken.ValidChainID()
+}
+
+type ParamsUnsubscribe struct {
+	Subscription *uint64 `json:"subscription"`
+}
+
+func (p ParamsUnsubscribe) IsValid() error {
+	if p.Subscription == nil {
+		return jsonrpc2.ErrorInvalidParams(`required: "subscription"`)
+	}
+	return nil
+}
+func (p ParamsUnsubscribe) GetIncludePending() bool       { return false }
+func (p ParamsUnsubscribe) ValidChainID() *factom.Bytes32 { return nil }
+
+// ParamsRewind is used to rewind a token chain to the given DBlock Height.
+type ParamsRewind struct {
+	ParamsToken
+	Height *uint32 `json:"height"`
+}
+
+func (p ParamsRewind) IsValid() error {
+	if err := p.ParamsToken.IsValid(); err != nil {
+		return err
+	}
+	if p.IncludePending {
+		return jsonrpc2.ErrorInvalidParams(
+			`"includepending" is not supported`)
+	}
+	if p.Height == nil {
+		return jsonrpc2.ErrorInvalidParams(`required: "height"`)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+// MIT License
+//
+// Copyright 2018 Canonical Ledgers, LLC
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package api_test
+
+import (
+	"testing"
+
+	jsonrpc2 "github.com/AdamSLevy/jsonrpc2/v14"
+	"github.com/Factom-Asset-Tokens/fatd/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParamsPaginationCursor(t *testing.T) {
+	str := func(s string) *string { return &s }
+	page := func(p uint) *uint { return &p }
+	for _, test := range []struct {
+		Name   string
+		Params api.ParamsPagination
+		Err    string
+		ID     *int64
+	}{{
+		Name:   "no cursor",
+		Params: api.ParamsPagination{},
+	}, {
+		Name:   "first page",
+		Params: api.ParamsPagination{Cursor: str("")},
+	}, {
+		Name:   "next page",
+		Params: api.ParamsPagination{Cursor: str(api.NewCursor(1000))},
+		ID:     func() *int64 { id := int64(1000); return &id }(),
+	}, {
+		Name:   "zero",
+		Params: api.ParamsPagination{Cursor: str("AAAAAAAAAAA")},
+		ID:     new(int64),
+	}, {
+		Name: "page",
+		Params: api.ParamsPagination{
+			Cursor: str(api.NewCursor(1)), Page: page(2)},
+		Err: `cannot use "page" with "cursor"`,
+	}, {
+		Name:   "invalid base64",
+		Params: api.ParamsPagination{Cursor: str("AAAA*AAAAAA")},
+		Err:    `invalid "cursor"`,
+	}, {
+		Name:   "padded base64",
+		Params: api.ParamsPagination{Cursor: str("AAAAAAAAAAA=")},
+		Err:    `invalid "cursor"`,
+	}, {
+		Name:   "short",
+		Params: api.ParamsPagination{Cursor: str("AAAAAAAAAA")},
+		Err:    `invalid "cursor"`,
+	}, {
+		Name:   "long",
+		Params: api.ParamsPagination{Cursor: str("AAAAAAAAAAAA")},
+		Err:    `invalid "cursor"`,
+	}} {
+		t.Run(test.Name, func(t *testing.T) {
+			params := test.Params
+			err := params.IsValid()
+			if len(test.Err) > 0 {
+				require.Error(t, err)
+				assert.Equal(t, test.Err,
+					err.(jsonrpc2.Error).Data)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.Params.Cursor != nil,
+				params.UseCursor())
+			assert.Equal(t, test.ID, params.CursorID())
+		})
+	}
+}
+
+func TestNewCursor(t *testing.T) {
+	for _, id := range []int64{0, 1, 255, 1 << 32, 1<<63 - 1} {
+		cursor := api.NewCursor(id)
+		params := api.ParamsPagination{Cursor: &cursor}
+		require.NoError(t, params.IsValid())
+		require.NotNil(t, params.CursorID())
+		assert.Equal(t, id, *params.CursorID())
+	}
+}
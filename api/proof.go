@@ -0,0 +1,241 @@
+// MIT License
+//
+// Copyright 2018 Canonical Ledgers, LLC
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package api
+
+import (
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/Factom-Asset-Tokens/factom"
+)
+
+// MerkleStep is a single step in a MerklePath. The running hash is
+// concatenated with Hash, on the left of it if Left is true, otherwise on the
+// right, and then hashed with sha256.
+type MerkleStep struct {
+	Hash *factom.Bytes32 `json:"hash"`
+	Left bool            `json:"left,omitempty"`
+}
+
+// MerklePath is the path of sibling hashes from a leaf up to a Merkle root.
+type MerklePath []MerkleStep
+
+// NewMerklePath returns the MerklePath for leaves[i] in the Merkle tree of
+// leaves, as constructed by factomd. Odd nodes are hashed with themselves.
+// The leaves must already be hashed, if required.
+func NewMerklePath(leaves []factom.Bytes32, i int) MerklePath {
+	var path MerklePath
+	level := leaves
+	for len(level) > 1 {
+		sibling := i ^ 1
+		if sibling >= len(level) {
+			sibling = i
+		}
+		hash := level[sibling]
+		path = append(path, MerkleStep{Hash: &hash, Left: sibling < i})
+
+		next := make([]factom.Bytes32, (len(level)+1)/2)
+		for j := range next {
+			left := level[2*j]
+			right := left
+			if 2*j+1 < len(level) {
+				right = level[2*j+1]
+			}
+			next[j] = hashPair(&left, &right)
+		}
+		level = next
+		i /= 2
+	}
+	return path
+}
+
+// Root returns the Merkle root computed by following path from leaf.
+func (path MerklePath) Root(leaf factom.Bytes32) (factom.Bytes32, error) {
+	hash := leaf
+	for _, step := range path {
+		if step.Hash == nil {
+			return hash, fmt.Errorf("missing hash")
+		}
+		if step.Left {
+			hash = hashPair(step.Hash, &hash)
+		} else {
+			hash = hashPair(&hash, step.Hash)
+		}
+	}
+	return hash, nil
+}
+
+// NewEntryPath returns the MerklePath from entryHash to the KeyMR of the EBlock
+// with the given raw data. The last step is the EBlock header hash.
+func NewEntryPath(ebData []byte, entryHash *factom.Bytes32) (MerklePath, error) {
+	if len(ebData) < factom.EBlockHeaderSize {
+		return nil, fmt.Errorf("invalid EBlock length")
+	}
+	// The EBlock body may contain minute markers, which are included in
+	// the Merkle tree.
+	leaves := objects(ebData[factom.EBlockHeaderSize:])
+	i := indexOf(leaves, entryHash)
+	if i < 0 {
+		return nil, fmt.Errorf("entry %v is not in the EBlock", entryHash)
+	}
+	headerHash := factom.ComputeEBlockHeaderHash(ebData)
+	return append(NewMerklePath(leaves, i),
+		MerkleStep{Hash: &headerHash, Left: true}), nil
+}
+
+// NewEBlockPath returns the MerklePath from sha256(ChainID|KeyMR) of the EBlock
+// for chainID to the KeyMR of the DBlock with the given raw data. The last step
+// is the DBlock header hash.
+func NewEBlockPath(dbData []byte, chainID *factom.Bytes32) (MerklePath, error) {
+	if len(dbData) < factom.DBlockHeaderSize {
+		return nil, fmt.Errorf("invalid DBlock length")
+	}
+	// DBlock leaves are sha256(ChainID|KeyMR).
+	pairs := objects(dbData[factom.DBlockHeaderSize:])
+	leaves := make([]factom.Bytes32, len(pairs)/2)
+	i := -1
+	for j := range leaves {
+		leaves[j] = sha256.Sum256(append(pairs[2*j][:], pairs[2*j+1][:]...))
+		if pairs[2*j] == *chainID {
+			i = j
+		}
+	}
+	if i < 0 {
+		return nil, fmt.Errorf("chain %v is not in the DBlock", chainID)
+	}
+	headerHash := factom.ComputeDBlockHeaderHash(dbData)
+	return append(NewMerklePath(leaves, i),
+		MerkleStep{Hash: &headerHash, Left: true}), nil
+}
+
+// objects splits data into 32 byte hashes.
+func objects(data []byte) []factom.Bytes32 {
+	objs := make([]factom.Bytes32, len(data)/32)
+	for i := range objs {
+		copy(objs[i][:], data[i*32:])
+	}
+	return objs
+}
+
+func indexOf(hashes []factom.Bytes32, hash *factom.Bytes32) int {
+	for i := range hashes {
+		if hashes[i] == *hash {
+			return i
+		}
+	}
+	return -1
+}
+
+func hashPair(left, right *factom.Bytes32) factom.Bytes32 {
+	data := make([]byte, 0, 2*len(left))
+	data = append(data, left[:]...)
+	data = append(data, right[:]...)
+	return sha256.Sum256(data)
+}
+
+// ResultGetTransactionProof proves that the Entry with EntryHash is included
+// in the EBlock with EBlockKeyMR, which is in turn included in the DBlock with
+// DBlockKeyMR.
+//
+// EntryPath leads from the EntryHash to the EBlockKeyMR. EBlockPath leads from
+// sha256(ChainID|EBlockKeyMR) to the DBlockKeyMR. The last step of each path
+// combines the block's header hash with its body Merkle root.
+type ResultGetTransactionProof struct {
+	EntryHash    *factom.Bytes32 `json:"entryhash"`
+	ChainID      *factom.Bytes32 `json:"chainid"`
+	EBlock       factom.Bytes    `json:"eblock"`
+	EBlockKeyMR  *factom.Bytes32 `json:"ebkeymr"`
+	EntryPath    MerklePath      `json:"entrypath"`
+	DBlockKeyMR  *factom.Bytes32 `json:"dbkeymr"`
+	DBlockHeight uint32          `json:"dbheight"`
+	EBlockPath   MerklePath      `json:"ebpath"`
+}
+
+// Verify that the proof is internally consistent. This does not require
+// access to any Factom node.
+//
+// The EBlock is authenticated by computing its KeyMR, which must equal
+// EBlockKeyMR, and the last step of EntryPath must be the EBlock header hash.
+// Only then is the EBlock height compared with DBlockHeight.
+//
+// The caller should separately ensure that EntryHash matches the transaction
+// Entry in question, and that DBlockKeyMR matches the DBlock at DBlockHeight
+// as reported by a trusted Factom node.
+func (p ResultGetTransactionProof) Verify() error {
+	if p.EntryHash == nil || p.ChainID == nil ||
+		p.EBlockKeyMR == nil || p.DBlockKeyMR == nil {
+		return fmt.Errorf("incomplete proof")
+	}
+
+	// The KeyMR is computed from the EBlock data since it is not set.
+	eb := factom.EBlock{ChainID: p.ChainID}
+	if err := eb.UnmarshalBinary(p.EBlock); err != nil {
+		return fmt.Errorf("factom.EBlock.UnmarshalBinary(): %w", err)
+	}
+	if *eb.KeyMR != *p.EBlockKeyMR {
+		return fmt.Errorf("EBlock does not match the EBlock KeyMR")
+	}
+	if len(p.EntryPath) == 0 {
+		return fmt.Errorf("invalid entry path: empty")
+	}
+	headerHash := factom.ComputeEBlockHeaderHash(p.EBlock)
+	last := p.EntryPath[len(p.EntryPath)-1]
+	if last.Hash == nil || *last.Hash != headerHash || !last.Left {
+		return fmt.Errorf(
+			"entry path does not end with the EBlock header hash")
+	}
+	if eb.Height != p.DBlockHeight {
+		return fmt.Errorf("EBlock height does not match DBlock height")
+	}
+	if !containsEntry(eb, p.EntryHash) {
+		return fmt.Errorf("entry is not in the EBlock")
+	}
+
+	keyMR, err := p.EntryPath.Root(*p.EntryHash)
+	if err != nil {
+		return fmt.Errorf("invalid entry path: %w", err)
+	}
+	if keyMR != *p.EBlockKeyMR {
+		return fmt.Errorf("entry path does not lead to the EBlock KeyMR")
+	}
+
+	leaf := sha256.Sum256(append(p.ChainID[:], p.EBlockKeyMR[:]...))
+	dbKeyMR, err := p.EBlockPath.Root(leaf)
+	if err != nil {
+		return fmt.Errorf("invalid eblock path: %w", err)
+	}
+	if dbKeyMR != *p.DBlockKeyMR {
+		return fmt.Errorf("eblock path does not lead to the DBlock KeyMR")
+	}
+
+	return nil
+}
+
+func containsEntry(eb factom.EBlock, hash *factom.Bytes32) bool {
+	for _, e := range eb.Entries {
+		if *e.Hash == *hash {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,255 @@
+// MIT License
+//
+// Copyright 2018 Canonical Ledgers, LLC
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package api_test
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/Factom-Asset-Tokens/factom"
+	. "github.com/Factom-Asset-Tokens/fatd/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func b32(s string) *factom.Bytes32 {
+	b32 := factom.NewBytes32(s)
+	return &b32
+}
+
+// mainnet DBlocks and their EBlocks, from the factom package tests.
+var dBlockTests = []struct {
+	Height  uint32
+	KeyMR   *factom.Bytes32
+	Data    factom.Bytes
+	EBlocks map[factom.Bytes32]*factom.Bytes32 // ChainID: KeyMR
+}{{
+	Height: 1000,
+	KeyMR:  b32("cd45e38f53c090a03513f0c67afb93c774a064a5614a772cd079f31b3db4d011"),
+	Data: factom.NewBytes(
+		"00fa92e5a2f2eaf170a2da9e4956a40231ed7255c6c6e5ada1ed746fc5ab3a0b" +
+			"79b8c700367a49467be900ba00daedd7d9cf2b1a07f839360e859e1f3d78c467" +
+			"01d3ad1507974595bf9b73dbec9ff5d5744cbf6410d66b837924208a0b8b84e5" +
+			"4fc4aad660016ea716000003e800000004000000000000000000000000000000" +
+			"000000000000000000000000000000000a3d92dc70f4cfd4fe464e18962057d7" +
+			"1924679cc866fe37f4b8023d292d9f34ce000000000000000000000000000000" +
+			"000000000000000000000000000000000c0526c1fdb9e0813e297a331891815e" +
+			"d893cb5a9cff15529197f13932ed9f9547000000000000000000000000000000" +
+			"000000000000000000000000000000000f526aca5f63bfb59188bae1fc367411" +
+			"a123bcc1d5a3c23c710b66b46703542855df3ade9eec4b08d5379cc64270c30e" +
+			"a7315d8a8a1a69efe2b98a60ecdd69e604f08c42bc44c09ac26c349bef8ee80d" +
+			"2ffb018cfa3e769107b2413792fa9bd642"),
+	EBlocks: map[factom.Bytes32]*factom.Bytes32{
+		*b32("000000000000000000000000000000000000000000000000000000000000000a"): b32("3d92dc70f4cfd4fe464e18962057d71924679cc866fe37f4b8023d292d9f34ce"),
+		*b32("000000000000000000000000000000000000000000000000000000000000000c"): b32("0526c1fdb9e0813e297a331891815ed893cb5a9cff15529197f13932ed9f9547"),
+		*b32("000000000000000000000000000000000000000000000000000000000000000f"): b32("526aca5f63bfb59188bae1fc367411a123bcc1d5a3c23c710b66b46703542855"),
+		*b32("df3ade9eec4b08d5379cc64270c30ea7315d8a8a1a69efe2b98a60ecdd69e604"): b32("f08c42bc44c09ac26c349bef8ee80d2ffb018cfa3e769107b2413792fa9bd642"),
+	},
+}, {
+	Height: 10000,
+	KeyMR:  b32("3670a63eb8051b925213a4a350e8d37d87e43da8a577a609d7fd30629b73a3aa"),
+	Data: factom.NewBytes(
+		"00fa92e5a26b0b1f81709569b0786df580962b65a00bc9f5ca78276828daf44a" +
+			"00fdbac5594e623a42c8f79fb74e070c185a8b1ad840b315508089b7a3414b32" +
+			"d42bc3b4895c7e9df048518d48be91324dbd2860dc6f685c733515c30b5467d6" +
+			"005c4f97140170075a0000271000000005000000000000000000000000000000" +
+			"000000000000000000000000000000000a9ac34d410e50f7151d297a5068fa36" +
+			"7a6c7e1ab18922fe128bb60ce3382f90de000000000000000000000000000000" +
+			"000000000000000000000000000000000cb2bae18160eeb695f8039b54667727" +
+			"32b19803e809d92978efe6e736247e12e0000000000000000000000000000000" +
+			"000000000000000000000000000000000f3b253ec8e25c25752fbc9ae5382bbf" +
+			"953051792b3805ed22f0f92734647aeefe23985c922e9cdd5ec09c7f52a7c715" +
+			"bc9e26295778ead5d54e30a0a6215783c85fa74871f36fb59b226ba06ca5d9a2" +
+			"2bb0295fe1714635cfa7080dd35ed117944bf71c177e71504032ab84023d8afc" +
+			"16e302de970e6be110dac20adbf9a197467f0fc1f1b295c190f1f8b7d605f7b0" +
+			"800b42c1259a0fd10bcee302345d70134c"),
+	EBlocks: map[factom.Bytes32]*factom.Bytes32{
+		*b32("000000000000000000000000000000000000000000000000000000000000000a"): b32("9ac34d410e50f7151d297a5068fa367a6c7e1ab18922fe128bb60ce3382f90de"),
+		*b32("000000000000000000000000000000000000000000000000000000000000000c"): b32("b2bae18160eeb695f8039b5466772732b19803e809d92978efe6e736247e12e0"),
+		*b32("000000000000000000000000000000000000000000000000000000000000000f"): b32("3b253ec8e25c25752fbc9ae5382bbf953051792b3805ed22f0f92734647aeefe"),
+		*b32("23985c922e9cdd5ec09c7f52a7c715bc9e26295778ead5d54e30a0a6215783c8"): b32("5fa74871f36fb59b226ba06ca5d9a22bb0295fe1714635cfa7080dd35ed11794"),
+		*b32("4bf71c177e71504032ab84023d8afc16e302de970e6be110dac20adbf9a19746"): b32("7f0fc1f1b295c190f1f8b7d605f7b0800b42c1259a0fd10bcee302345d70134c"),
+	},
+}}
+
+func TestNewEBlockPath(t *testing.T) {
+	for _, test := range dBlockTests {
+		for chainID, keyMR := range test.EBlocks {
+			chainID := chainID
+			path, err := NewEBlockPath(test.Data, &chainID)
+			require.NoError(t, err)
+			leaf := sha256.Sum256(append(chainID[:], keyMR[:]...))
+			root, err := path.Root(leaf)
+			require.NoError(t, err)
+			assert.Equalf(t, *test.KeyMR, root,
+				"DBlock %v, EBlock %v", test.Height, chainID)
+		}
+		_, err := NewEBlockPath(test.Data, b32(
+			"b54c4310530dc4dd361101644fa55cb10aec561e7874a7b786ea3b66f2c6fdfb"))
+		assert.Error(t, err, "chain not in DBlock")
+	}
+}
+
+// mainnet EBlock at height 191436 for the FAT-0 chain b54c4310..., which
+// contains three entries and two minute markers.
+var (
+	eBlockChainID = b32("b54c4310530dc4dd361101644fa55cb10aec561e7874a7b786ea3b66f2c6fdfb")
+	eBlockKeyMR   = b32("99a4f4e47d8cdfaec60623a73f4f2818c99c152baa7d241cff0b59302eb0d0a4")
+	eBlockHeight  = uint32(191436)
+	eBlockData    = factom.NewBytes(
+		"b54c4310530dc4dd361101644fa55cb10aec561e7874a7b786ea3b66f2c6fdfb" +
+			"8e006c49b7efb91ba2bcb762ce37c498e6db44d7de7702005f9a150ac07ebf50" +
+			"8fa1652370ead5f15322918ae3f1f8507f282c511b542dcc8522bc248e2a604d" +
+			"ac79d84bcc7e28075533139975809022c86b07c8655a5aec2521edcfd2558ac8" +
+			"000000170002ebcc000000051e725d1277771f8c4434cd03529263718792a4d7" +
+			"8cbcdaf713af388087ad162842c47211b701acc845226e41209669ba106390f2" +
+			"3163b985e42a6ac8924e3da90000000000000000000000000000000000000000" +
+			"00000000000000000000000335763c96871dc0bdcba6a79e4789955ad0c3637c" +
+			"219077f0b182a64b900bce840000000000000000000000000000000000000000" +
+			"000000000000000000000006")
+	eBlockEntries = []*factom.Bytes32{
+		b32("1e725d1277771f8c4434cd03529263718792a4d78cbcdaf713af388087ad1628"),
+		b32("42c47211b701acc845226e41209669ba106390f23163b985e42a6ac8924e3da9"),
+		b32("35763c96871dc0bdcba6a79e4789955ad0c3637c219077f0b182a64b900bce84"),
+	}
+)
+
+func TestNewEntryPath(t *testing.T) {
+	for _, entryHash := range eBlockEntries {
+		path, err := NewEntryPath(eBlockData, entryHash)
+		require.NoError(t, err)
+		root, err := path.Root(*entryHash)
+		require.NoError(t, err)
+		assert.Equalf(t, *eBlockKeyMR, root, "Entry %v", entryHash)
+	}
+	_, err := NewEntryPath(eBlockData, eBlockChainID)
+	assert.Error(t, err, "entry not in EBlock")
+}
+
+// newProof returns a valid proof for entryHash in the mainnet EBlock. The
+// DBlock at height 191436 is not included here, so the EBlock is appended to
+// the body of mainnet DBlock 10000 to build the EBlock path. Verify does not
+// depend on the DBlock header, only on the resulting DBlock KeyMR.
+func newProof(t *testing.T, entryHash *factom.Bytes32) ResultGetTransactionProof {
+	entryPath, err := NewEntryPath(eBlockData, entryHash)
+	require.NoError(t, err)
+
+	dbData := append(factom.Bytes{}, dBlockTests[1].Data...)
+	dbData = append(dbData, eBlockChainID[:]...)
+	dbData = append(dbData, eBlockKeyMR[:]...)
+	ebPath, err := NewEBlockPath(dbData, eBlockChainID)
+	require.NoError(t, err)
+	leaf := sha256.Sum256(append(eBlockChainID[:], eBlockKeyMR[:]...))
+	dbKeyMR, err := ebPath.Root(leaf)
+	require.NoError(t, err)
+
+	return ResultGetTransactionProof{
+		EntryHash:    entryHash,
+		ChainID:      eBlockChainID,
+		EBlock:       eBlockData,
+		EBlockKeyMR:  eBlockKeyMR,
+		EntryPath:    entryPath,
+		DBlockKeyMR:  &dbKeyMR,
+		DBlockHeight: eBlockHeight,
+		EBlockPath:   ebPath,
+	}
+}
+
+func TestResultGetTransactionProofVerify(t *testing.T) {
+	for _, entryHash := range eBlockEntries {
+		assert.NoErrorf(t, newProof(t, entryHash).Verify(),
+			"Entry %v", entryHash)
+	}
+
+	entryHash := eBlockEntries[1]
+	for _, test := range []struct {
+		Name   string
+		Tamper func(*ResultGetTransactionProof)
+		Err    string
+	}{{
+		Name:   "incomplete",
+		Tamper: func(p *ResultGetTransactionProof) { p.DBlockKeyMR = nil },
+		Err:    "incomplete proof",
+	}, {
+		Name: "wrong EBlock KeyMR",
+		Tamper: func(p *ResultGetTransactionProof) {
+			p.EBlockKeyMR = b32("5fa74871f36fb59b226ba06ca5d9a22bb0295fe1714635cfa7080dd35ed11794")
+		},
+		Err: "EBlock does not match the EBlock KeyMR",
+	}, {
+		Name: "EBlock height changed",
+		Tamper: func(p *ResultGetTransactionProof) {
+			// The DB Height is bytes 4 to 8 after the first four
+			// hashes and the EB Sequence.
+			p.EBlock = append(factom.Bytes{}, p.EBlock...)
+			p.EBlock[32*4+4+3]++
+			p.DBlockHeight++
+		},
+		Err: "EBlock does not match the EBlock KeyMR",
+	}, {
+		Name: "DBlock height",
+		Tamper: func(p *ResultGetTransactionProof) {
+			p.DBlockHeight++
+		},
+		Err: "EBlock height does not match DBlock height",
+	}, {
+		Name: "no EBlock header hash",
+		Tamper: func(p *ResultGetTransactionProof) {
+			p.EntryPath = p.EntryPath[:len(p.EntryPath)-1]
+		},
+		Err: "entry path does not end with the EBlock header hash",
+	}, {
+		Name: "EBlock header hash on the right",
+		Tamper: func(p *ResultGetTransactionProof) {
+			p.EntryPath = append(MerklePath{}, p.EntryPath...)
+			p.EntryPath[len(p.EntryPath)-1].Left = false
+		},
+		Err: "entry path does not end with the EBlock header hash",
+	}, {
+		Name: "minute marker",
+		Tamper: func(p *ResultGetTransactionProof) {
+			p.EntryHash = b32("0000000000000000000000000000000000000000000000000000000000000003")
+		},
+		Err: "entry is not in the EBlock",
+	}, {
+		Name: "entry path sibling",
+		Tamper: func(p *ResultGetTransactionProof) {
+			p.EntryPath = append(MerklePath{}, p.EntryPath...)
+			p.EntryPath[0].Hash = eBlockEntries[2]
+		},
+		Err: "entry path does not lead to the EBlock KeyMR",
+	}, {
+		Name: "eblock path sibling",
+		Tamper: func(p *ResultGetTransactionProof) {
+			p.EBlockPath = append(MerklePath{}, p.EBlockPath...)
+			p.EBlockPath[0].Hash = eBlockKeyMR
+		},
+		Err: "eblock path does not lead to the DBlock KeyMR",
+	}} {
+		t.Run(test.Name, func(t *testing.T) {
+			p := newProof(t, entryHash)
+			test.Tamper(&p)
+			assert.EqualError(t, p.Verify(), test.Err)
+		})
+	}
+}
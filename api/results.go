@@ -40,10 +40,11 @@ type ResultGetIssuance struct {
 }
 
 type ResultGetTransaction struct {
-	Hash      *factom.Bytes32 `json:"entryhash"`
-	Timestamp int64           `json:"timestamp"`
-	Tx        interface{}     `json:"data"`
-	Pending   bool            `json:"pending,omitempty"`
+	Hash          *factom.Bytes32 `json:"entryhash"`
+	Timestamp     int64           `json:"timestamp"`
+	Tx            interface{}     `json:"data"`
+	Pending       bool            `json:"pending,omitempty"`
+	InvalidReason string          `json:"invalidreason,omitempty"`
 }
 
 type ResultGetBalances map[factom.Bytes32]uint64
@@ -84,6 +85,36 @@ type ResultGetStats struct {
 	NonZeroBalances          int64  `json:"nonzerobalances, omitempty"`
 }
 
+type ResultGetStateHash struct {
+	StateHash   *factom.Bytes32 `json:"statehash"`
+	EBlockKeyMR *factom.Bytes32 `json:"ebkeymr"`
+	Height      uint32          `json:"height"`
+	Timestamp   int64           `json:"timestamp"`
+}
+
+type Holder struct {
+	Address      *factom.FAAddress `json:"address"`
+	Balance      uint64            `json:"balance"`
+	NFTokenCount int64             `json:"nftokencount,omitempty"`
+}
+
+type ResultGetHolders struct {
+	Holders     []Holder `json:"holders"`
+	NumHolders  int64    `json:"numholders"`
+	Circulating uint64   `json:"circulating"`
+	Gini        float64  `json:"gini"`
+	Top         uint     `json:"top"`
+	TopShare    float64  `json:"topshare"`
+}
+
+// ResultPage is returned by methods that support a cursor when
+// ParamsPagination.Cursor is used. NextCursor is empty when there are no more
+// results.
+type ResultPage struct {
+	Results    interface{} `json:"results"`
+	NextCursor string      `json:"nextcursor,omitempty"`
+}
+
 type ResultGetNFToken struct {
 	NFTokenID  fat1.NFTokenID    `json:"id"`
 	Owner      *factom.FAAddress `json:"owner,omitempty"`
@@ -92,6 +123,31 @@ type ResultGetNFToken struct {
 	CreationTx *factom.Bytes32   `json:"creationtx"`
 }
 
+// Type values for NFTokenTransfer.
+const (
+	NFTokenTransferCreation = "creation"
+	NFTokenTransferTransfer = "transfer"
+	NFTokenTransferBurn     = "burn"
+)
+
+// NFTokenTransfer is a change of ownership of an NFToken. From is omitted for
+// the creation of the NFToken and To is omitted if it was burned.
+type NFTokenTransfer struct {
+	Type      string            `json:"type"`
+	Hash      *factom.Bytes32   `json:"entryhash"`
+	Timestamp int64             `json:"timestamp"`
+	Pending   bool              `json:"pending,omitempty"`
+	From      *factom.FAAddress `json:"from,omitempty"`
+	To        *factom.FAAddress `json:"to,omitempty"`
+}
+
+type ResultGetNFTokenHistory struct {
+	NFTokenID fat1.NFTokenID    `json:"id"`
+	Metadata  json.RawMessage   `json:"metadata,omitempty"`
+	Burned    bool              `json:"burned,omitempty"`
+	History   []NFTokenTransfer `json:"history"`
+}
+
 type ResultGetDaemonProperties struct {
 	FatdVersion string           `json:"fatdversion"`
 	APIVersion  string           `json:"apiversion"`
@@ -102,3 +158,83 @@ type ResultGetSyncStatus struct {
 	Sync    uint32 `json:"syncheight"`
 	Current uint32 `json:"factomheight"`
 }
+
+// SigningHash is the message hash that must be signed by the key with the
+// RCDHash for the transaction to be valid. For normal transactions, Address
+// is the input address with the RCDHash. For coinbase transactions, the
+// RCDHash is the issuer's ID1 Key.
+type SigningHash struct {
+	RCDSigID int               `json:"rcdsigid"`
+	RCDHash  *factom.Bytes32   `json:"rcdhash"`
+	Address  *factom.FAAddress `json:"address,omitempty"`
+	MsgHash  factom.Bytes      `json:"msghash"`
+}
+
+type ResultSendTransaction struct {
+	ChainID *factom.Bytes32 `json:"chainid"`
+	TxID    *factom.Bytes32 `json:"txid,omitempty"`
+	Hash    *factom.Bytes32 `json:"entryhash"`
+}
+
+type ResultComposeTransaction struct {
+	ChainID       *factom.Bytes32 `json:"chainid"`
+	Content       factom.Bytes    `json:"content"`
+	TimestampSalt string          `json:"timestampsalt"`
+	SigningHashes []SigningHash   `json:"signinghashes"`
+}
+
+type ResultAssembleTransaction struct {
+	ChainID *factom.Bytes32 `json:"chainid"`
+	Hash    *factom.Bytes32 `json:"entryhash"`
+	ExtIDs  []factom.Bytes  `json:"extids"`
+	Content factom.Bytes    `json:"content"`
+	Raw     factom.Bytes    `json:"raw"`
+}
+
+// Status values for ResultGetSubmissionStatus.
+const (
+	SubmissionStatusCommitted = "committed"
+	SubmissionStatusRevealed  = "revealed"
+	SubmissionStatusPending   = "pending"
+	SubmissionStatusConfirmed = "confirmed"
+	SubmissionStatusDropped   = "dropped"
+)
+
+type ResultGetSubmissionStatus struct {
+	ChainID       *factom.Bytes32 `json:"chainid"`
+	Hash          *factom.Bytes32 `json:"entryhash"`
+	TxID          *factom.Bytes32 `json:"txid"`
+	Status        string          `json:"status"`
+	Valid         bool            `json:"valid"`
+	InvalidReason string          `json:"invalidreason,omitempty"`
+	Reveals       int64           `json:"reveals"`
+	Submitted     int64           `json:"submitted"`
+	Updated       int64           `json:"updated"`
+}
+
+// Status values for EventTransaction.
+const (
+	EventStatusPending   = "pending"
+	EventStatusConfirmed = "confirmed"
+	EventStatusDropped   = "dropped"
+)
+
+type EventTransaction struct {
+	ChainID   *factom.Bytes32 `json:"chainid"`
+	Hash      *factom.Bytes32 `json:"entryhash"`
+	Timestamp int64           `json:"timestamp"`
+	Status    string          `json:"status"`
+	Valid     bool            `json:"valid"`
+	Tx        interface{}     `json:"data,omitempty"`
+}
+
+type EventSync ResultGetSyncStatus
+
+type ResultSubscribe struct {
+	Subscription uint64 `json:"subscription"`
+}
+
+type EventNotification struct {
+	Subscription uint64      `json:"subscription"`
+	Result       interface{} `json:"result"`
+}
@@ -29,8 +29,8 @@ import (
 
 	"github.com/Factom-Asset-Tokens/factom"
 	"github.com/Factom-Asset-Tokens/fatd/api"
-	"github.com/Factom-Asset-Tokens/factom/fat0"
 	"github.com/Factom-Asset-Tokens/factom/fat1"
+	"github.com/Factom-Asset-Tokens/fatd/internal/standard"
 	"github.com/posener/complete"
 	"github.com/spf13/cobra"
 )
@@ -142,8 +142,11 @@ func getBalance(cmd *cobra.Command, _ []string) {
 		"get-stats", params, &stats); err != nil {
 		errLog.Fatal(err)
 	}
-	switch stats.Issuance.Type {
-	case fat0.Type:
+	std, ok := standard.Get(stats.Issuance.Type)
+	if !ok {
+		errLog.Fatalf("unsupported token type: %v", stats.Issuance.Type)
+	}
+	if !std.NFTokens() {
 		var params api.ParamsGetBalance
 		params.ChainID = paramsToken.ChainID
 		params.IncludePending = paramsToken.IncludePending
@@ -162,7 +165,7 @@ func getBalance(cmd *cobra.Command, _ []string) {
 				fmt.Println(adr, balance)
 			}
 		}
-	case fat1.Type:
+	} else {
 		var params api.ParamsGetNFBalance
 		params.Limit = math.MaxUint64
 		params.ChainID = paramsToken.ChainID
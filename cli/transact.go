@@ -31,9 +31,9 @@ import (
 	jsonrpc2 "github.com/AdamSLevy/jsonrpc2/v14"
 	"github.com/Factom-Asset-Tokens/factom"
 	"github.com/Factom-Asset-Tokens/factom/fat"
-	"github.com/Factom-Asset-Tokens/factom/fat0"
 	"github.com/Factom-Asset-Tokens/factom/fat1"
 	"github.com/Factom-Asset-Tokens/fatd/api"
+	"github.com/Factom-Asset-Tokens/fatd/internal/standard"
 	"github.com/posener/complete"
 	"github.com/spf13/cobra"
 )
@@ -149,6 +149,10 @@ var transactCmplCmd = complete.Command{
 
 var signingSet []factom.RCDSigner
 
+// transactTxs returns the transaction populated by the flags of the transact
+// subcommand for each fat.Type.
+var transactTxs = make(map[fat.Type]func() interface{})
+
 func validateTransactFlags(cmd *cobra.Command, args []string) error {
 	if err := validateChainIDFlags(cmd, args); err != nil {
 		return err
@@ -178,23 +182,14 @@ func validateTransactFlags(cmd *cobra.Command, args []string) error {
 	// All subsequent errors are not issues with correct use of flags, so
 	// avoid printing Usage() by calling os.Fata() instead of returning.
 
+	std := standard.MustGet(cmdType)
+	tx := transactTxs[cmdType]()
+
 	// Populate all private keys
-	var numInputs int = 1
 	var inputAdrs []factom.FAAddress
 	if inputSet {
-		numInputs = len(fat0Tx.Inputs) + len(fat1Tx.Inputs)
-		inputAdrs = make([]factom.FAAddress, 0, numInputs)
-		switch cmdType {
-		case fat0.Type:
-			for fa := range fat0Tx.Inputs {
-				inputAdrs = append(inputAdrs, fa)
-			}
-		case fat1.Type:
-			for fa := range fat1Tx.Inputs {
-				inputAdrs = append(inputAdrs, fa)
-			}
-		}
-		signingSet = make([]factom.RCDSigner, numInputs)
+		inputAdrs, _ = std.Addresses(tx)
+		signingSet = make([]factom.RCDSigner, len(inputAdrs))
 		for i, fa := range inputAdrs {
 			fs, ok := privateAddress[fa]
 			if !ok {
@@ -212,35 +207,14 @@ func validateTransactFlags(cmd *cobra.Command, args []string) error {
 		}
 	} else {
 		signingSet = append(signingSet, sk1)
-		switch cmdType {
-		case fat0.Type:
-			fat0Tx.Inputs = make(fat0.AddressAmountMap, 1)
-			fat0Tx.Inputs[fat.Coinbase()] = fat0Tx.Outputs.Sum()
-		case fat1.Type:
-			fat1Tx.Inputs = make(fat1.AddressNFTokensMap, 1)
-			tkns, err := fat1Tx.Outputs.AllNFTokens()
-			if err != nil {
-				errLog.Fatal(err)
-			}
-			fat1Tx.Inputs[fat.Coinbase()] = tkns
+		var err error
+		if tx, err = std.Coinbase(tx); err != nil {
+			errLog.Fatal(err)
 		}
 	}
 
 	vrbLog.Printf("Preparing %v Transaction Entry...", cmdType)
-	var tx interface {
-		Sign(...factom.RCDSigner) (factom.Entry, error)
-	}
-	switch cmdType {
-	case fat0.Type:
-		fat0Tx.Entry.ChainID = paramsToken.ChainID
-		fat0Tx.Metadata = metadata
-		tx = &fat0Tx
-	case fat1.Type:
-		fat1Tx.Entry.ChainID = paramsToken.ChainID
-		fat1Tx.Metadata = metadata
-		tx = &fat1Tx
-	}
-	entry, err := tx.Sign(signingSet...)
+	entry, err := std.Sign(tx, paramsToken.ChainID, signingSet...)
 	if err != nil {
 		errLog.Fatal(err)
 	}
@@ -275,13 +249,7 @@ func validateTransactFlags(cmd *cobra.Command, args []string) error {
 					paramsGetBalance, &balance); err != nil {
 					errLog.Fatal(err)
 				}
-				var inputAmount uint64
-				switch cmdType {
-				case fat0.Type:
-					inputAmount = fat0Tx.Inputs[adr]
-				case fat1.Type:
-					inputAmount = uint64(len(fat1Tx.Inputs[adr]))
-				}
+				inputAmount := std.InputAmount(tx, adr)
 				if inputAmount > balance {
 					errLog.Fatalf(
 						"--input %v:%v has insufficient balance (%v)",
@@ -289,7 +257,7 @@ func validateTransactFlags(cmd *cobra.Command, args []string) error {
 				}
 			}
 		}
-		if inputSet && cmdType == fat1.Type {
+		if inputSet && std.NFTokens() {
 			params := api.ParamsGetNFBalance{ParamsToken: params}
 			params.Limit = math.MaxUint64
 			for _, adr := range inputAdrs {
@@ -301,7 +269,7 @@ func validateTransactFlags(cmd *cobra.Command, args []string) error {
 					errLog.Fatal(err)
 				}
 				if err := balance.ContainsAll(
-					fat1Tx.Inputs[adr]); err != nil {
+					std.InputNFTokens(tx, adr)); err != nil {
 					errLog.Fatalf("--input %v:%v balance: %v",
 						adr, addressValueStrMap[adr], err)
 				}
@@ -313,25 +281,16 @@ func validateTransactFlags(cmd *cobra.Command, args []string) error {
 			verifySK1Key(&sk1, stats.IssuerChainID)
 
 			vrbLog.Println("Validating coinbase transaction...")
-			var issuing uint64
-			switch cmdType {
-			case fat0.Type:
-				issuing = fat0Tx.Inputs.Sum()
-			case fat1.Type:
-				issuing = fat1Tx.Inputs.Sum()
-			}
+			issuing := std.InputAmount(tx, fat.Coinbase())
 			issued := stats.CirculatingSupply + stats.Burned
 			if stats.Issuance.Supply != -1 &&
 				issuing+issued > uint64(stats.Issuance.Supply) {
 				errLog.Fatal(
 					"invalid coinbase transaction: exceeds max supply")
 			}
-			if cmdType == fat1.Type {
+			if std.NFTokens() {
 				params := api.ParamsGetNFToken{ParamsToken: params}
-				tkns, err := fat1Tx.Inputs.AllNFTokens()
-				if err != nil {
-					errLog.Fatal(err)
-				}
+				tkns := std.InputNFTokens(tx, fat.Coinbase())
 				for tknID := range tkns {
 					params.NFTokenID = &tknID
 					err := FATClient.Request(context.Background(),
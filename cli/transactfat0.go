@@ -73,6 +73,10 @@ See 'fat-cli transact --help' for more information about transactions.
 		Run: func(_ *cobra.Command, _ []string) {},
 	}
 	transactCmd.AddCommand(cmd)
+	transactTxs[fat0.Type] = func() interface{} {
+		fat0Tx.Metadata = metadata
+		return fat0Tx
+	}
 	transactCmplCmd.Sub["fat0"] = transactFAT0CmplCmd
 	rootCmplCmd.Sub["help"].Sub["transact"].Sub["fat0"] = complete.Command{}
 
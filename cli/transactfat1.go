@@ -76,6 +76,10 @@ See 'fat-cli transact --help' for more information about transactions.
 		Run: func(_ *cobra.Command, _ []string) {},
 	}
 	transactCmd.AddCommand(cmd)
+	transactTxs[fat1.Type] = func() interface{} {
+		fat1Tx.Metadata = metadata
+		return fat1Tx
+	}
 	transactCmplCmd.Sub["fat1"] = transactFAT1CmplCmd
 	rootCmplCmd.Sub["help"].Sub["transact"].Sub["fat1"] = complete.Command{}
 
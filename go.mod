@@ -9,9 +9,11 @@ require (
 	github.com/AdamSLevy/sqlitechangeset v0.0.0-20191210201651-f95453d87aff
 	github.com/Factom-Asset-Tokens/factom v0.0.0-20200218011245-1ec32d463fe2
 	github.com/goji/httpauth v0.0.0-20160601135302-2da839ab0f4d
+	github.com/gorilla/websocket v1.4.1
 	github.com/mitchellh/go-homedir v1.1.0
 	github.com/nightlyone/lockfile v0.0.0-20200124072040-edb130adc195
 	github.com/posener/complete v1.2.3
+	github.com/prometheus/client_golang v1.5.1
 	github.com/rs/cors v1.7.0
 	github.com/sirupsen/logrus v1.4.2
 	github.com/spf13/cobra v0.0.5
@@ -20,6 +22,7 @@ require (
 	github.com/stretchr/testify v1.4.0
 	github.com/subchen/go-trylock/v2 v2.0.0
 	golang.org/x/sync v0.0.0-20190911185100-cd5d95a43a6e
+	golang.org/x/time v0.0.0-20191024005414-555d28b269f0
 )
 
 // Fixes a small annoyance when displaying defaults for custom Vars.
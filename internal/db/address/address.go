@@ -32,6 +32,11 @@ import (
 	"github.com/Factom-Asset-Tokens/factom"
 )
 
+// CoinbaseID is the row id of the coinbase address. The coinbase address is
+// always the first address added to a chain's "address" table, so that tokens
+// may be issued from and burned to it without looking up its id.
+const CoinbaseID = 1
+
 // CreateTable is a SQL string that creates the "address" table.
 const CreateTable = `CREATE TABLE "address" (
         "id"            INTEGER PRIMARY KEY,
@@ -39,6 +44,7 @@ const CreateTable = `CREATE TABLE "address" (
         "balance"       INTEGER NOT NULL
                         CONSTRAINT "insufficient balance" CHECK ("balance" >= 0)
 );
+CREATE INDEX "idx_address_balance" ON "address"("balance");
 `
 
 // Add adds add to the balance of adr, creating a new row in "address" if it
@@ -0,0 +1,104 @@
+// MIT License
+//
+// Copyright 2018 Canonical Ledgers, LLC
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package address
+
+import (
+	"crawshaw.io/sqlite"
+	"crawshaw.io/sqlite/sqlitex"
+	"github.com/Factom-Asset-Tokens/factom"
+)
+
+// CreateTableBalanceDelta is a SQL string that creates the
+// "address_balance_delta" table, which records the change in balance of each
+// address for each valid entry. Summing the deltas up to a given entry id
+// yields the balance of an address as of that entry.
+//
+// The "address_balance_delta" table has a foreign key reference to the
+// "address" and "entry" tables, which must exist first.
+const CreateTableBalanceDelta = `CREATE TABLE "address_balance_delta" (
+        "address_id"    INTEGER NOT NULL,
+        "entry_id"      INTEGER NOT NULL,
+        "delta"         INTEGER NOT NULL,
+
+        PRIMARY KEY("address_id", "entry_id"),
+
+        FOREIGN KEY("address_id") REFERENCES "address",
+        FOREIGN KEY("entry_id")   REFERENCES "entry"
+);
+CREATE INDEX "idx_address_balance_delta_entry_id" ON
+        "address_balance_delta"("entry_id");
+`
+
+// InsertBalanceDelta adds delta to the balance change of adrID for entryID,
+// creating the row in "address_balance_delta" if it does not exist.
+func InsertBalanceDelta(conn *sqlite.Conn,
+	adrID, entryID int64, delta int64) error {
+	stmt := conn.Prep(`INSERT INTO "address_balance_delta"
+                ("address_id", "entry_id", "delta") VALUES (?, ?, ?)
+                ON CONFLICT("address_id", "entry_id") DO
+                UPDATE SET "delta" = "delta" + "excluded"."delta";`)
+	stmt.BindInt64(1, adrID)
+	stmt.BindInt64(2, entryID)
+	stmt.BindInt64(3, delta)
+	_, err := stmt.Step()
+	return err
+}
+
+// SelectBalanceAt returns the balance of adr as of the entry with row id
+// entryID.
+func SelectBalanceAt(conn *sqlite.Conn,
+	adr *factom.FAAddress, entryID int64) (uint64, error) {
+	stmt := conn.Prep(`SELECT ifnull(sum("delta"), 0)
+                FROM "address_balance_delta" WHERE "address_id" = (
+                        SELECT "id" FROM "address" WHERE "address" = ?)
+                AND "entry_id" <= ?;`)
+	stmt.BindBytes(1, adr[:])
+	stmt.BindInt64(2, entryID)
+	bal, err := sqlitex.ResultInt64(stmt)
+	return uint64(bal), err
+}
+
+// SelectCirculatingAt returns the sum of the balances of all addresses, other
+// than the coinbase address, as of the entry with row id entryID.
+func SelectCirculatingAt(conn *sqlite.Conn, entryID int64) (uint64, error) {
+	stmt := conn.Prep(`SELECT ifnull(sum("delta"), 0)
+                FROM "address_balance_delta" WHERE "address_id" != ?
+                AND "entry_id" <= ?;`)
+	stmt.BindInt64(1, CoinbaseID)
+	stmt.BindInt64(2, entryID)
+	total, err := sqlitex.ResultInt64(stmt)
+	return uint64(total), err
+}
+
+// SelectCountAt returns the number of addresses, other than the coinbase
+// address, with a non zero balance as of the entry with row id entryID.
+func SelectCountAt(conn *sqlite.Conn, entryID int64) (int64, error) {
+	stmt := conn.Prep(`SELECT count(*) FROM (
+                SELECT sum("delta") AS "balance" FROM "address_balance_delta"
+                        WHERE "address_id" != ? AND "entry_id" <= ?
+                        GROUP BY "address_id")
+                WHERE "balance" > 0;`)
+	stmt.BindInt64(1, CoinbaseID)
+	stmt.BindInt64(2, entryID)
+	return sqlitex.ResultInt64(stmt)
+}
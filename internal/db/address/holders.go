@@ -0,0 +1,128 @@
+package address
+
+import (
+	"fmt"
+
+	"crawshaw.io/sqlite"
+	"github.com/AdamSLevy/sqlbuilder"
+	"github.com/Factom-Asset-Tokens/factom"
+)
+
+// Holder is an address with a non zero balance.
+type Holder struct {
+	Address factom.FAAddress
+	Balance uint64
+
+	// NumNFTokens is only populated by SelectHolders if requested.
+	NumNFTokens int64
+}
+
+// SelectHolders returns a page of all addresses, other than the coinbase
+// address, with a non zero balance, ordered by balance. Holders with equal
+// balances are ordered by when they first received tokens. If numNFTokens is
+// true, the number of NFTokens owned by each holder is also selected.
+func SelectHolders(conn *sqlite.Conn, numNFTokens bool,
+	order string, page, limit uint) ([]Holder, error) {
+	if page == 0 {
+		return nil, fmt.Errorf("invalid page")
+	}
+	var sql sqlbuilder.SQLBuilder
+	sql.Append(`SELECT "address", "balance", `)
+	if numNFTokens {
+		sql.Append(`(SELECT count(*) FROM "nftoken"
+                        WHERE "owner_id" = "address"."id")`)
+	} else {
+		sql.Append(`0`)
+	}
+	sql.Append(` FROM "address" WHERE "id" != 1 AND "balance" > 0`)
+	sql.OrderBy("balance", order)
+	sql.Append(`, "id"`)
+	sql.Paginate(page, limit)
+
+	stmt := sql.Prep(conn)
+	defer stmt.Reset()
+
+	var holders []Holder
+	for {
+		hasRow, err := stmt.Step()
+		if err != nil {
+			return nil, err
+		}
+		if !hasRow {
+			return holders, nil
+		}
+		var h Holder
+		if stmt.ColumnBytes(0, h.Address[:]) != len(h.Address) {
+			panic("invalid address length")
+		}
+		h.Balance = uint64(stmt.ColumnInt64(1))
+		h.NumNFTokens = stmt.ColumnInt64(2)
+		holders = append(holders, h)
+	}
+}
+
+// Distribution summarizes how the balances are distributed among all
+// addresses, other than the coinbase address.
+type Distribution struct {
+	// NumHolders is the number of addresses with a non zero balance.
+	NumHolders int64
+	// Circulating is the sum of all balances.
+	Circulating uint64
+	// Gini is the Gini coefficient of all non zero balances, where 0 is
+	// perfect equality and values approaching 1 indicate that the
+	// balances are held by few addresses.
+	Gini float64
+	// TopShare is the fraction of Circulating held by the top addresses
+	// by balance.
+	TopShare float64
+}
+
+// SelectDistribution returns the Distribution of all balances, with the
+// TopShare held by the top addresses.
+func SelectDistribution(conn *sqlite.Conn, top uint) (Distribution, error) {
+	var d Distribution
+	var err error
+	if d.NumHolders, err = SelectCount(conn, true); err != nil {
+		return d, err
+	}
+	if d.NumHolders == 0 {
+		return d, nil
+	}
+
+	// The balances are scanned in ascending order so that the Gini
+	// coefficient may be computed in a single pass:
+	//
+	//      G = 2*sum(i*x_i) / (n*sum(x_i)) - (n+1)/n
+	//
+	// The top holders are last, so everything up to them is summed to
+	// find their share.
+	numBottom := d.NumHolders - int64(top)
+	stmt := conn.Prep(`SELECT "balance" FROM "address"
+                WHERE "id" != 1 AND "balance" > 0 ORDER BY "balance";`)
+	defer stmt.Reset()
+	var i int64
+	var weighted float64
+	var bottom uint64
+	for {
+		hasRow, err := stmt.Step()
+		if err != nil {
+			return d, err
+		}
+		if !hasRow {
+			break
+		}
+		bal := uint64(stmt.ColumnInt64(0))
+		i++
+		weighted += float64(i) * float64(bal)
+		d.Circulating += bal
+		if i <= numBottom {
+			bottom += bal
+		}
+	}
+
+	n := float64(i)
+	total := float64(d.Circulating)
+	d.Gini = 2*weighted/(n*total) - (n+1)/n
+	d.TopShare = float64(d.Circulating-bottom) / total
+	return d, nil
+}
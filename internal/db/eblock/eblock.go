@@ -39,7 +39,8 @@ const CreateTable = `CREATE TABLE "eblock" (
         "db_height"     INTEGER NOT NULL UNIQUE,
         "db_key_mr"     BLOB NOT NULL UNIQUE,
         "timestamp"     INTEGER NOT NULL,
-        "data"          BLOB NOT NULL
+        "data"          BLOB NOT NULL,
+        "state_hash"    BLOB
 );
 `
 
@@ -126,6 +127,29 @@ func SelectBySequence(conn *sqlite.Conn, seq uint32) (factom.EBlock, error) {
 	return Select(stmt)
 }
 
+// SelectByValidEntryHash returns the factom.EBlock that contains the valid
+// Entry with the given hash, along with the DBlock KeyMR it was inserted with.
+func SelectByValidEntryHash(conn *sqlite.Conn,
+	hash *factom.Bytes32) (factom.EBlock, factom.Bytes32, error) {
+	var dbKeyMR factom.Bytes32
+	stmt := conn.Prep(
+		`SELECT "key_mr", "data", "timestamp", "db_key_mr" FROM "eblock"
+                        WHERE "seq" = (SELECT "eb_seq" FROM "entry"
+                                WHERE "valid" = true AND "hash" = ?);`)
+	stmt.BindBytes(1, hash[:])
+	defer stmt.Reset()
+	eb, err := Select(stmt)
+	if err != nil || !eb.IsPopulated() {
+		return eb, dbKeyMR, err
+	}
+
+	if stmt.ColumnBytes(3, dbKeyMR[:]) != len(dbKeyMR) {
+		panic("invalid db_key_mr length")
+	}
+
+	return eb, dbKeyMR, nil
+}
+
 // SelectKeyMR returns the KeyMR for the EBlock with sequence seq.
 func SelectKeyMR(conn *sqlite.Conn, seq uint32) (factom.Bytes32, error) {
 	var keyMR factom.Bytes32
@@ -189,3 +213,69 @@ func SelectLatest(conn *sqlite.Conn) (factom.EBlock, factom.Bytes32, error) {
 
 	return eb, dbKeyMR, nil
 }
+
+// SetStateHash sets the "state_hash" for the EBlock with sequence seq.
+func SetStateHash(conn *sqlite.Conn, seq uint32, hash *factom.Bytes32) error {
+	stmt := conn.Prep(`UPDATE "eblock" SET "state_hash" = ? WHERE "seq" = ?;`)
+	stmt.BindBytes(1, hash[:])
+	stmt.BindInt64(2, int64(seq))
+	_, err := stmt.Step()
+	return err
+}
+
+// SelectStateHash returns the "state_hash" of the EBlock with sequence seq, or
+// nil if it is not set.
+func SelectStateHash(conn *sqlite.Conn, seq uint32) (*factom.Bytes32, error) {
+	stmt := conn.Prep(`SELECT "state_hash" FROM "eblock" WHERE "seq" = ?;`)
+	stmt.BindInt64(1, int64(seq))
+	defer stmt.Reset()
+	hasRow, err := stmt.Step()
+	if err != nil || !hasRow || stmt.ColumnType(0) == sqlite.SQLITE_NULL {
+		return nil, err
+	}
+	hash := new(factom.Bytes32)
+	if stmt.ColumnBytes(0, hash[:]) != len(hash) {
+		panic("invalid state_hash length")
+	}
+	return hash, nil
+}
+
+// SelectStateHashAtHeight returns the latest factom.EBlock at or below the
+// given DBlock height, along with its "state_hash". The returned hash is nil
+// if the EBlock does not have a "state_hash".
+func SelectStateHashAtHeight(conn *sqlite.Conn,
+	height uint32) (factom.EBlock, *factom.Bytes32, error) {
+	stmt := conn.Prep(`SELECT "key_mr", "data", "timestamp", "state_hash"
+                FROM "eblock" WHERE "db_height" <= ?
+                ORDER BY "db_height" DESC LIMIT 1;`)
+	stmt.BindInt64(1, int64(height))
+	return selectStateHash(stmt)
+}
+
+// SelectStateHashAtTimestamp returns the latest factom.EBlock at or before ts,
+// along with its "state_hash". The returned hash is nil if the EBlock does not
+// have a "state_hash".
+func SelectStateHashAtTimestamp(conn *sqlite.Conn,
+	ts time.Time) (factom.EBlock, *factom.Bytes32, error) {
+	stmt := conn.Prep(`SELECT "key_mr", "data", "timestamp", "state_hash"
+                FROM "eblock" WHERE "timestamp" <= ?
+                ORDER BY "db_height" DESC LIMIT 1;`)
+	stmt.BindInt64(1, ts.Unix())
+	return selectStateHash(stmt)
+}
+
+func selectStateHash(stmt *sqlite.Stmt) (factom.EBlock, *factom.Bytes32, error) {
+	defer stmt.Reset()
+	eb, err := Select(stmt)
+	if err != nil || !eb.IsPopulated() {
+		return eb, nil, err
+	}
+	if stmt.ColumnType(3) == sqlite.SQLITE_NULL {
+		return eb, nil, nil
+	}
+	hash := new(factom.Bytes32)
+	if stmt.ColumnBytes(3, hash[:]) != len(hash) {
+		panic("invalid state_hash length")
+	}
+	return eb, hash, nil
+}
@@ -157,12 +157,94 @@ func SelectCount(conn *sqlite.Conn, validOnly bool) (int64, error) {
 	return sqlitex.ResultInt64(stmt)
 }
 
+// SelectIsValid returns the "valid" flag of the entry at row id.
+func SelectIsValid(conn *sqlite.Conn, id int64) (bool, error) {
+	stmt := conn.Prep(`SELECT "valid" FROM "entry" WHERE "id" = ?;`)
+	stmt.BindInt64(1, id)
+	valid, err := sqlitex.ResultInt(stmt)
+	return valid != 0, err
+}
+
+// SelectIsValidByEBlock returns the "valid" flag of every entry in the EBlock
+// with sequence ebSeq, in the order they were inserted.
+func SelectIsValidByEBlock(conn *sqlite.Conn, ebSeq uint32) ([]bool, error) {
+	stmt := conn.Prep(`SELECT "valid" FROM "entry" WHERE "eb_seq" = ?
+                ORDER BY "id";`)
+	stmt.BindInt64(1, int64(ebSeq))
+	defer stmt.Reset()
+	var valid []bool
+	for {
+		hasRow, err := stmt.Step()
+		if err != nil {
+			return nil, err
+		}
+		if !hasRow {
+			break
+		}
+		valid = append(valid, stmt.ColumnInt(0) != 0)
+	}
+	return valid, nil
+}
+
+// SelectMaxIDAtHeight returns the largest row id of all entries in EBlocks with
+// a DBlock height less than or equal to height. If there are no such entries,
+// 0 is returned.
+func SelectMaxIDAtHeight(conn *sqlite.Conn, height uint32) (int64, error) {
+	stmt := conn.Prep(`SELECT ifnull(max("entry"."id"), 0)
+                FROM "entry", "eblock" ON "entry"."eb_seq" = "eblock"."seq"
+                WHERE "eblock"."db_height" <= ?;`)
+	stmt.BindInt64(1, int64(height))
+	return sqlitex.ResultInt64(stmt)
+}
+
+// SelectMaxIDAtTimestamp returns the largest row id of all entries in EBlocks
+// with a DBlock timestamp less than or equal to ts. If there are no such
+// entries, 0 is returned.
+func SelectMaxIDAtTimestamp(conn *sqlite.Conn, ts time.Time) (int64, error) {
+	stmt := conn.Prep(`SELECT ifnull(max("entry"."id"), 0)
+                FROM "entry", "eblock" ON "entry"."eb_seq" = "eblock"."seq"
+                WHERE "eblock"."timestamp" <= ?;`)
+	stmt.BindInt64(1, ts.Unix())
+	return sqlitex.ResultInt64(stmt)
+}
+
+// SelectCountAt returns the number of valid entries with a row id less than
+// or equal to id.
+func SelectCountAt(conn *sqlite.Conn, id int64) (int64, error) {
+	stmt := conn.Prep(`SELECT count(*) FROM "entry"
+                WHERE "valid" = true AND "id" <= ?;`)
+	stmt.BindInt64(1, id)
+	return sqlitex.ResultInt64(stmt)
+}
+
+// SelectLatestValidAt returns the most recent valid factom.Entry with a row id
+// less than or equal to id.
+func SelectLatestValidAt(conn *sqlite.Conn, id int64) (factom.Entry, error) {
+	stmt := conn.Prep(SelectWhere + `"id" = (SELECT max("id") FROM "entry"
+                WHERE "valid" = true AND "id" <= ?);`)
+	stmt.BindInt64(1, id)
+	defer stmt.Reset()
+	return Select(stmt)
+}
+
 func init() {
 	sqlbuilder.LimitMaxDefault = uint(flag.APIMaxLimit)
 }
 
-// SelectByAddress returns all the factom.Entry where adrs and nfTkns were
-// involved in the valid transaction, for the given pagination range.
+// Range bounds the entries returned by SelectByAddress by the DBlock height of
+// their EBlock and by their timestamp, in unix seconds. All bounds are
+// inclusive and any nil bound is ignored.
+type Range struct {
+	FromHeight, ToHeight       *uint32
+	FromTimestamp, ToTimestamp *int64
+}
+
+// SelectByAddress returns all the factom.Entry, and their row ids, where adrs
+// and nfTkns were involved in the valid transaction, within rng, for the given
+// pagination range.
+//
+// If afterID is not nil, only entries after the row id afterID, in the given
+// order, are returned.
 //
 // Pages start at 1.
 //
@@ -170,13 +252,25 @@ func init() {
 // package that is more specific to FAT0 and FAT1.
 func SelectByAddress(conn *sqlite.Conn, startHash *factom.Bytes32,
 	adrs []factom.FAAddress, nfTkns fat1.NFTokens,
-	toFrom, order string,
-	page, limit uint) ([]factom.Entry, error) {
+	toFrom string, rng Range, afterID *int64, order string,
+	page, limit uint) ([]factom.Entry, []int64, error) {
 	if page == 0 {
-		return nil, fmt.Errorf("invalid page")
+		return nil, nil, fmt.Errorf("invalid page")
 	}
 	var sql sqlbuilder.SQLBuilder
-	sql.Append(SelectWhere + `"valid" = true`)
+	// Select the "id" after the columns that Select expects.
+	sql.Append(`SELECT "hash", "data", "timestamp", "id" FROM "entry"
+                WHERE "valid" = true`)
+	if afterID != nil {
+		op := ">"
+		if strings.ToLower(order) == "desc" {
+			op = "<"
+		}
+		sql.Append(` AND "id" `+op+` ?`, func(s *sqlite.Stmt, p int) int {
+			s.BindInt64(p, *afterID)
+			return 1
+		})
+	}
 	if startHash != nil {
 		sql.Append(` AND "id" >= (SELECT "id" FROM "entry" WHERE "hash" = ?)`,
 			func(s *sqlite.Stmt, p int) int {
@@ -184,6 +278,37 @@ func SelectByAddress(conn *sqlite.Conn, startHash *factom.Bytes32,
 				return 1
 			})
 	}
+	if rng.FromHeight != nil || rng.ToHeight != nil {
+		sql.WriteString(` AND "eb_seq" IN (
+                                SELECT "seq" FROM "eblock" WHERE true`) // 1 open (
+		if rng.FromHeight != nil {
+			sql.Append(` AND "db_height" >= ?`,
+				func(s *sqlite.Stmt, p int) int {
+					s.BindInt64(p, int64(*rng.FromHeight))
+					return 1
+				})
+		}
+		if rng.ToHeight != nil {
+			sql.Append(` AND "db_height" <= ?`,
+				func(s *sqlite.Stmt, p int) int {
+					s.BindInt64(p, int64(*rng.ToHeight))
+					return 1
+				})
+		}
+		sql.WriteString(`)`) // 0 open (
+	}
+	if rng.FromTimestamp != nil {
+		sql.Append(` AND "timestamp" >= ?`, func(s *sqlite.Stmt, p int) int {
+			s.BindInt64(p, *rng.FromTimestamp)
+			return 1
+		})
+	}
+	if rng.ToTimestamp != nil {
+		sql.Append(` AND "timestamp" <= ?`, func(s *sqlite.Stmt, p int) int {
+			s.BindInt64(p, *rng.ToTimestamp)
+			return 1
+		})
+	}
 	var to bool
 	switch strings.ToLower(toFrom) {
 	case "to":
@@ -194,8 +319,8 @@ func SelectByAddress(conn *sqlite.Conn, startHash *factom.Bytes32,
 	}
 	if len(nfTkns) > 0 {
 		sql.WriteString(` AND "id" IN (
-                                SELECT "entry_id" FROM "nf_token_address_transactions"
-                                        WHERE "nf_tkn_id" IN (`) // 2 open (
+                                SELECT "entry_id" FROM "nftoken_address_tx"
+                                        WHERE "nftoken_id" IN (`) // 2 open (
 		sql.BindNParams(len(nfTkns), func(s *sqlite.Stmt, p int) int {
 			i := 0
 			for nfTkn := range nfTkns {
@@ -226,7 +351,7 @@ func SelectByAddress(conn *sqlite.Conn, startHash *factom.Bytes32,
 		sql.WriteString(`)`) // 0 open {
 	} else if len(adrs) > 0 {
 		sql.WriteString(` AND "id" IN (
-                                SELECT "entry_id" FROM "address_transactions"
+                                SELECT "entry_id" FROM "address_tx"
                                         WHERE "address_id" IN (
                                                 SELECT "id" FROM "address"
                                                         WHERE "address" IN (`) // 3 open (
@@ -253,18 +378,20 @@ func SelectByAddress(conn *sqlite.Conn, startHash *factom.Bytes32,
 	defer stmt.Reset()
 
 	var entry []factom.Entry
+	var ids []int64
 	for {
 		e, err := Select(stmt)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		if !e.IsPopulated() {
 			break
 		}
 		entry = append(entry, e)
+		ids = append(ids, stmt.ColumnInt64(3))
 	}
 
-	return entry, nil
+	return entry, ids, nil
 }
 
 // CheckUniquelyValid returns true if there are no valid entry earlier than
@@ -0,0 +1,95 @@
+package entry
+
+import (
+	"fmt"
+
+	"crawshaw.io/sqlite"
+	"github.com/AdamSLevy/sqlbuilder"
+	"github.com/Factom-Asset-Tokens/factom"
+)
+
+// CreateTableInvalid is a SQL string that creates the "entry_invalid" table,
+// which records the reason that an entry was rejected as an invalid
+// transaction or issuance.
+//
+// The "entry_invalid" table has a foreign key reference to the "entry" table,
+// which must exist first.
+const CreateTableInvalid = `CREATE TABLE "entry_invalid" (
+        "entry_id"      INTEGER PRIMARY KEY,
+        "reason"        TEXT NOT NULL,
+
+        FOREIGN KEY("entry_id") REFERENCES "entry"
+);
+`
+
+// InsertInvalid records the reason that the entry at row id is invalid.
+func InsertInvalid(conn *sqlite.Conn, id int64, reason string) error {
+	stmt := conn.Prep(`INSERT INTO "entry_invalid"
+                ("entry_id", "reason") VALUES (?, ?);`)
+	stmt.BindInt64(1, id)
+	stmt.BindText(2, reason)
+	_, err := stmt.Step()
+	return err
+}
+
+// SelectInvalidWhere is a SQL fragment for retrieving invalid entries along
+// with the reason they are invalid with SelectInvalid().
+const SelectInvalidWhere = `SELECT "hash", "data", "timestamp", "reason"
+        FROM "entry", "entry_invalid" ON "id" = "entry_id" WHERE `
+
+// SelectInvalid returns the next invalid factom.Entry and the reason it is
+// invalid from the given prepared Stmt.
+//
+// The Stmt must be created with a SQL string starting with
+// SelectInvalidWhere.
+func SelectInvalid(stmt *sqlite.Stmt) (factom.Entry, string, error) {
+	e, err := Select(stmt)
+	if err != nil || !e.IsPopulated() {
+		return e, "", err
+	}
+	return e, stmt.ColumnText(3), nil
+}
+
+// SelectInvalidByHash returns the latest invalid factom.Entry with hash and
+// the reason it is invalid.
+func SelectInvalidByHash(conn *sqlite.Conn,
+	hash *factom.Bytes32) (factom.Entry, string, error) {
+	stmt := conn.Prep(SelectInvalidWhere +
+		`"hash" = ? ORDER BY "id" DESC LIMIT 1;`)
+	stmt.BindBytes(1, hash[:])
+	defer stmt.Reset()
+	return SelectInvalid(stmt)
+}
+
+// SelectAllInvalid returns all invalid factom.Entry and the reasons they are
+// invalid, for the given pagination range.
+//
+// Pages start at 1.
+func SelectAllInvalid(conn *sqlite.Conn, order string,
+	page, limit uint) ([]factom.Entry, []string, error) {
+	if page == 0 {
+		return nil, nil, fmt.Errorf("invalid page")
+	}
+	var sql sqlbuilder.SQLBuilder
+	sql.Append(SelectInvalidWhere + `true`)
+	sql.OrderByPaginate("id", order, page, limit)
+
+	stmt := sql.Prep(conn)
+	defer stmt.Reset()
+
+	var entries []factom.Entry
+	var reasons []string
+	for {
+		e, reason, err := SelectInvalid(stmt)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !e.IsPopulated() {
+			break
+		}
+		entries = append(entries, e)
+		reasons = append(reasons, reason)
+	}
+
+	return entries, reasons, nil
+}
@@ -28,12 +28,15 @@ import (
 	"os"
 	"strings"
 
+	"crawshaw.io/sqlite"
+	"crawshaw.io/sqlite/sqlitex"
 	"github.com/Factom-Asset-Tokens/factom"
 	"github.com/Factom-Asset-Tokens/factom/fat"
 	"github.com/Factom-Asset-Tokens/fatd/internal/db/address"
 	"github.com/Factom-Asset-Tokens/fatd/internal/db/entry"
 	"github.com/Factom-Asset-Tokens/fatd/internal/db/metadata"
 	_log "github.com/Factom-Asset-Tokens/fatd/internal/log"
+	"github.com/Factom-Asset-Tokens/fatd/internal/standard"
 )
 
 type FATChain struct {
@@ -71,7 +74,11 @@ func NewFATChain(ctx context.Context, dbPath, tokenID string,
 		return
 	}
 
-	// Ensure that the coinbase address has rowid = 1.
+	if err = createStandardTables(chain.Conn); err != nil {
+		return
+	}
+
+	// Ensure that the coinbase address has rowid = address.CoinbaseID.
 	coinbase := fat.Coinbase()
 	if _, err = address.Add(chain.Conn, &coinbase, 0); err != nil {
 		return
@@ -102,10 +109,26 @@ func OpenFATChain(ctx context.Context,
 	}()
 	chain.DBFile = fname
 
+	if err = createStandardTables(chain.Conn); err != nil {
+		return
+	}
+
 	err = chain.load()
 	return
 }
 
+// createStandardTables creates the tables of all registered token standards.
+// These are created outside of the migrations so that they need not be
+// versioned with the core schema.
+func createStandardTables(conn *sqlite.Conn) error {
+	if sql := standard.CreateTables(); sql != "" {
+		if err := sqlitex.ExecScript(conn, sql); err != nil {
+			return fmt.Errorf("standard.CreateTables(): %w", err)
+		}
+	}
+	return nil
+}
+
 func (chain *FATChain) load() error {
 	if err := chain.FactomChain.load(); err != nil {
 		return err
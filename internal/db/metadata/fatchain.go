@@ -26,6 +26,7 @@ import (
 	"fmt"
 
 	"crawshaw.io/sqlite"
+	"crawshaw.io/sqlite/sqlitex"
 	"github.com/Factom-Asset-Tokens/factom"
 	"github.com/Factom-Asset-Tokens/factom/fat"
 	"github.com/Factom-Asset-Tokens/fatd/internal/db/entry"
@@ -91,6 +92,13 @@ func SetInitEntryID(conn *sqlite.Conn, id int64) error {
 	return err
 }
 
+// SelectInitEntryID returns the "init_entry_id", or 0 if the chain has not
+// yet been issued.
+func SelectInitEntryID(conn *sqlite.Conn) (int64, error) {
+	stmt := conn.Prep(`SELECT ifnull("init_entry_id", 0) FROM "fat_chain";`)
+	return sqlitex.ResultInt64(stmt)
+}
+
 func AddNumIssued(conn *sqlite.Conn, add uint64) error {
 	stmt := conn.Prep(`UPDATE "fat_chain" SET
                 "num_issued" = "num_issued" + ?;`)
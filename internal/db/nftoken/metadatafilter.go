@@ -0,0 +1,81 @@
+package nftoken
+
+import (
+	"fmt"
+
+	"crawshaw.io/sqlite"
+	"github.com/AdamSLevy/sqlbuilder"
+)
+
+// MetadataFilter is a predicate on the value at the JSON Path within the
+// NFToken metadata, evaluated with the SQLite JSON1 json_extract function.
+//
+// Op is one of "==", "!=", "<", "<=", ">" or ">=". Value must be a string,
+// float64, bool, or nil, as decoded by encoding/json. A nil Value may only be
+// used with "==" or "!=" and matches NFTokens without a value at Path.
+type MetadataFilter struct {
+	Path  string
+	Op    string
+	Value interface{}
+}
+
+var sqlOps = map[string]string{
+	"==": "=",
+	"!=": "!=",
+	"<":  "<",
+	"<=": "<=",
+	">":  ">",
+	">=": ">=",
+}
+
+// appendWhere appends the SQL for f to sql.
+func (f MetadataFilter) appendWhere(sql *sqlbuilder.SQLBuilder) error {
+	op, ok := sqlOps[f.Op]
+	if !ok {
+		return fmt.Errorf("invalid operator: %q", f.Op)
+	}
+	bindPath := func(s *sqlite.Stmt, p int) int {
+		s.BindText(p, f.Path)
+		return 1
+	}
+	if f.Value == nil {
+		switch f.Op {
+		case "==":
+			sql.Append(` AND json_extract("metadata", ?) IS NULL`, bindPath)
+		case "!=":
+			sql.Append(` AND json_extract("metadata", ?) IS NOT NULL`,
+				bindPath)
+		default:
+			return fmt.Errorf("invalid operator for null: %q", f.Op)
+		}
+		return nil
+	}
+
+	var bindValue sqlbuilder.BindFunc
+	switch v := f.Value.(type) {
+	case string:
+		bindValue = func(s *sqlite.Stmt, p int) int {
+			s.BindText(p, v)
+			return 1
+		}
+	case float64:
+		bindValue = func(s *sqlite.Stmt, p int) int {
+			if v == float64(int64(v)) {
+				s.BindInt64(p, int64(v))
+			} else {
+				s.BindFloat(p, v)
+			}
+			return 1
+		}
+	case bool:
+		bindValue = func(s *sqlite.Stmt, p int) int {
+			s.BindBool(p, v)
+			return 1
+		}
+	default:
+		return fmt.Errorf("invalid value type: %T", f.Value)
+	}
+	sql.Append(fmt.Sprintf(` AND json_extract("metadata", ?) %v ?`, op),
+		bindPath, bindValue)
+	return nil
+}
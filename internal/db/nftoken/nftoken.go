@@ -28,6 +28,7 @@ package nftoken
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	"crawshaw.io/sqlite"
 	"crawshaw.io/sqlite/sqlitex"
@@ -162,16 +163,36 @@ func SelectData(conn *sqlite.Conn, nfID fat1.NFTokenID) (
 }
 
 // SelectDataAll returns the nfIDs, owner addresses, creation entry hashes, and
-// the NFToken metadata for the given pagination range of NFTokens.
+// the NFToken metadata for the given pagination range of NFTokens whose
+// metadata matches all filters.
+//
+// If afterID is not nil, only NFTokens after afterID, in the given order, are
+// returned.
 //
 // Pages start at 1.
-func SelectDataAll(conn *sqlite.Conn, order string, page, limit uint) (
+func SelectDataAll(conn *sqlite.Conn, filters []MetadataFilter,
+	afterID *fat1.NFTokenID, order string, page, limit uint) (
 	[]fat1.NFTokenID, []factom.FAAddress, []factom.Bytes32, [][]byte, error) {
 	if page == 0 {
 		return nil, nil, nil, nil, fmt.Errorf("invalid page")
 	}
-	stmt := conn.Prep(`SELECT "id", "owner", "creation_hash", "metadata"
-                        FROM "nftoken_address";`)
+	var sql sqlbuilder.SQLBuilder
+	sql.Append(`SELECT "id", "owner", "creation_hash", "metadata"
+                        FROM "nftoken_address" WHERE true`)
+	appendAfter(&sql, "id", afterID, order)
+	if len(filters) > 0 {
+		// json_extract fails on malformed JSON, so skip over any
+		// invalid metadata.
+		sql.Append(` AND ("metadata" IS NULL OR json_valid("metadata"))`)
+	}
+	for _, f := range filters {
+		if err := f.appendWhere(&sql); err != nil {
+			return nil, nil, nil, nil, err
+		}
+	}
+	sql.OrderByPaginate("id", order, page, limit)
+
+	stmt := sql.Prep(conn)
 	defer stmt.Reset()
 
 	var tkns []fat1.NFTokenID
@@ -208,11 +229,13 @@ func SelectDataAll(conn *sqlite.Conn, order string, page, limit uint) (
 }
 
 // SelectByOwner returns the fat1.NFTokens owned by the given adr for the given
-// pagination range.
+// pagination range. If afterID is not nil, only NFTokens after afterID, in the
+// given order, are returned.
 //
 // Pages start at 1.
 func SelectByOwner(conn *sqlite.Conn, adr *factom.FAAddress,
-	page, limit uint, order string) (fat1.NFTokens, error) {
+	afterID *fat1.NFTokenID, page, limit uint, order string) (
+	fat1.NFTokens, error) {
 	if page == 0 {
 		return nil, fmt.Errorf("invalid page")
 	}
@@ -223,6 +246,7 @@ func SelectByOwner(conn *sqlite.Conn, adr *factom.FAAddress,
 			s.BindBytes(c, adr[:])
 			return 1
 		})
+	appendAfter(&sql, "id", afterID, order)
 	sql.OrderByPaginate("id", order, page, limit)
 
 	stmt := sql.Prep(conn)
@@ -244,3 +268,69 @@ func SelectByOwner(conn *sqlite.Conn, adr *factom.FAAddress,
 	}
 	return nfTkns, nil
 }
+
+// SelectByOwnerAt returns the fat1.NFTokens owned by the given adr as of the
+// entry with row id entryID, for the given pagination range. If afterID is not
+// nil, only NFTokens after afterID, in the given order, are returned.
+//
+// Pages start at 1.
+func SelectByOwnerAt(conn *sqlite.Conn, adr *factom.FAAddress, entryID int64,
+	afterID *fat1.NFTokenID, page, limit uint, order string) (
+	fat1.NFTokens, error) {
+	if page == 0 {
+		return nil, fmt.Errorf("invalid page")
+	}
+	var sql sqlbuilder.SQLBuilder
+	sql.Append(`SELECT "nftoken_id" AS "id" FROM "nftoken_address_tx" AS "tx"
+                WHERE "to" = true AND "entry_id" <= ? AND "address_id" = (
+                        SELECT "id" FROM "address" WHERE "address" = ?)
+                AND "entry_id" = (
+                        SELECT max("entry_id") FROM "nftoken_address_tx"
+                        WHERE "nftoken_id" = "tx"."nftoken_id" AND
+                                "to" = true AND "entry_id" <= ?)`,
+		func(s *sqlite.Stmt, c int) int {
+			s.BindInt64(c, entryID)
+			s.BindBytes(c+1, adr[:])
+			s.BindInt64(c+2, entryID)
+			return 3
+		})
+	appendAfter(&sql, "nftoken_id", afterID, order)
+	sql.OrderByPaginate("id", order, page, limit)
+
+	stmt := sql.Prep(conn)
+	defer stmt.Reset()
+	nfTkns := make(fat1.NFTokens)
+	for {
+		hasRow, err := stmt.Step()
+		if err != nil {
+			return nil, err
+		}
+		if !hasRow {
+			break
+		}
+		colVal := stmt.ColumnInt64(0)
+		if colVal < 0 {
+			panic("negative NFTokenID")
+		}
+		nfTkns[fat1.NFTokenID(colVal)] = struct{}{}
+	}
+	return nfTkns, nil
+}
+
+// appendAfter appends a condition that col is after afterID in the given
+// order, for keyset pagination. Nothing is appended if afterID is nil.
+func appendAfter(sql *sqlbuilder.SQLBuilder,
+	col string, afterID *fat1.NFTokenID, order string) {
+	if afterID == nil {
+		return
+	}
+	op := ">"
+	if strings.ToLower(order) == "desc" {
+		op = "<"
+	}
+	sql.Append(fmt.Sprintf(` AND %q %v ?`, col, op),
+		func(s *sqlite.Stmt, p int) int {
+			s.BindInt64(p, int64(*afterID))
+			return 1
+		})
+}
@@ -1,7 +1,10 @@
 package nftoken
 
 import (
+	"time"
+
 	"crawshaw.io/sqlite"
+	"github.com/Factom-Asset-Tokens/factom"
 	"github.com/Factom-Asset-Tokens/factom/fat1"
 )
 
@@ -41,3 +44,56 @@ func InsertTxRelation(conn *sqlite.Conn,
 	_, err := stmt.Step()
 	return err
 }
+
+// Transfer is a change of ownership of an NFToken by a valid transaction. The
+// creation of an NFToken is a Transfer From the coinbase address, and burning
+// an NFToken is a Transfer To the coinbase address.
+type Transfer struct {
+	Hash      factom.Bytes32
+	Timestamp time.Time
+	From      factom.FAAddress
+	To        factom.FAAddress
+}
+
+// SelectHistory returns every Transfer of nfID in the order they occurred.
+func SelectHistory(conn *sqlite.Conn, nfID fat1.NFTokenID) ([]Transfer, error) {
+	stmt := conn.Prep(`SELECT "entry"."hash", "entry"."timestamp",
+                        "adr_from"."address", "adr_to"."address"
+                FROM "nftoken_address_tx" AS "tx_from"
+                JOIN "nftoken_address_tx" AS "tx_to" ON
+                        "tx_to"."entry_id" = "tx_from"."entry_id" AND
+                        "tx_to"."nftoken_id" = "tx_from"."nftoken_id" AND
+                        "tx_to"."to" = true
+                JOIN "entry" ON "entry"."id" = "tx_from"."entry_id"
+                JOIN "address" AS "adr_from" ON
+                        "adr_from"."id" = "tx_from"."address_id"
+                JOIN "address" AS "adr_to" ON
+                        "adr_to"."id" = "tx_to"."address_id"
+                WHERE "tx_from"."nftoken_id" = ? AND "tx_from"."to" = false
+                ORDER BY "entry"."id";`)
+	stmt.BindInt64(1, int64(nfID))
+	defer stmt.Reset()
+
+	var history []Transfer
+	for {
+		hasRow, err := stmt.Step()
+		if err != nil {
+			return nil, err
+		}
+		if !hasRow {
+			return history, nil
+		}
+		var t Transfer
+		if stmt.ColumnBytes(0, t.Hash[:]) != len(t.Hash) {
+			panic("invalid hash length")
+		}
+		t.Timestamp = time.Unix(stmt.ColumnInt64(1), 0)
+		if stmt.ColumnBytes(2, t.From[:]) != len(t.From) {
+			panic("invalid address length")
+		}
+		if stmt.ColumnBytes(3, t.To[:]) != len(t.To) {
+			panic("invalid address length")
+		}
+		history = append(history, t)
+	}
+}
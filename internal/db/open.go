@@ -91,6 +91,12 @@ func OpenAllFATChains(ctx context.Context, dbPath string) (chains []FATChain, er
 	}
 	return chains, nil
 }
+
+// FileName returns the name of the database file for chainID.
+func FileName(chainID *factom.Bytes32) string {
+	return chainID.String() + dbFileExtension
+}
+
 func fnameToChainID(fname string) (*factom.Bytes32, error) {
 	invalidFName := fmt.Errorf("invalid filename: %v", fname)
 	if len(fname) != dbFileNameLen ||
@@ -23,16 +23,20 @@
 package db
 
 import (
+	"encoding/json"
 	"fmt"
 
 	"crawshaw.io/sqlite"
 	"crawshaw.io/sqlite/sqlitex"
+	"github.com/Factom-Asset-Tokens/factom"
 	"github.com/Factom-Asset-Tokens/factom/fat"
+	"github.com/Factom-Asset-Tokens/factom/fat0"
 	"github.com/Factom-Asset-Tokens/fatd/internal/db/address"
 	"github.com/Factom-Asset-Tokens/fatd/internal/db/eblock"
 	"github.com/Factom-Asset-Tokens/fatd/internal/db/entry"
 	"github.com/Factom-Asset-Tokens/fatd/internal/db/metadata"
 	"github.com/Factom-Asset-Tokens/fatd/internal/db/nftoken"
+	"github.com/Factom-Asset-Tokens/fatd/internal/db/search"
 )
 
 const (
@@ -40,14 +44,17 @@ const (
 	// regardless of whether they actually make use of the NFTokens tables.
 	chainDBSchema = eblock.CreateTable +
 		entry.CreateTable +
+		entry.CreateTableInvalid +
 		address.CreateTable +
 		address.CreateTableTxRelation +
+		address.CreateTableBalanceDelta +
 		nftoken.CreateTable +
 		nftoken.CreateTableTxRelation +
 		metadata.CreateTableFactomChain +
-		metadata.CreateTableFATChain
+		metadata.CreateTableFATChain +
+		search.CreateTable
 
-	currentDBVersion = 1
+	currentDBVersion = 6
 )
 
 var migrations = []func(*sqlite.Conn) error{
@@ -161,6 +168,57 @@ DROP TABLE "metadata";`)
 		return err
 
 	},
+	func(conn *sqlite.Conn) error {
+		err := sqlitex.ExecScript(conn, address.CreateTableBalanceDelta+`
+INSERT INTO "address_balance_delta" ("address_id", "entry_id", "delta")
+        SELECT "address_id", "entry_id",
+                CASE WHEN "to" THEN count(*) ELSE -count(*) END
+        FROM "nftoken_address_tx"
+        WHERE "address_id" != 1 OR "to" = true
+        GROUP BY "address_id", "entry_id", "to"
+        ON CONFLICT("address_id", "entry_id") DO
+        UPDATE SET "delta" = "delta" + "excluded"."delta";`)
+		if err != nil {
+			return err
+		}
+		// FAT-0 amounts are not stored anywhere but the entry itself.
+		return migrateFAT0BalanceDeltas(conn)
+	},
+	func(conn *sqlite.Conn) error {
+		err := sqlitex.ExecScript(conn,
+			`ALTER TABLE "eblock" ADD COLUMN "state_hash" BLOB;`)
+		if err != nil {
+			return err
+		}
+		return migrateStateHashes(conn)
+	},
+	func(conn *sqlite.Conn) error {
+		// The reasons for existing invalid entries are populated the
+		// next time the chain is validated.
+		return sqlitex.ExecScript(conn, entry.CreateTableInvalid)
+	},
+	func(conn *sqlite.Conn) error {
+		return sqlitex.ExecScript(conn, `
+CREATE INDEX "idx_address_balance" ON "address"("balance");`)
+	},
+	func(conn *sqlite.Conn) error {
+		if err := sqlitex.ExecScript(conn, search.CreateTable); err != nil {
+			return err
+		}
+		// Sync indexes the metadata of all existing NFTokens.
+		if err := search.Sync(conn); err != nil {
+			return err
+		}
+		_, tokenID, _, issuance, err := metadata.SelectFATChain(conn)
+		if err != nil {
+			return err
+		}
+		if !issuance.Entry.IsPopulated() {
+			return nil
+		}
+		return search.InsertIssuance(conn,
+			tokenID, issuance.Symbol, issuance.Metadata)
+	},
 }
 var _ = map[bool]int{false: 0,
 	(len(migrations) == currentDBVersion): 1}
@@ -239,3 +297,78 @@ func updateDBVersion(conn *sqlite.Conn) error {
 	return sqlitex.ExecScript(conn, fmt.Sprintf(`PRAGMA user_version = %v;`,
 		currentDBVersion))
 }
+
+// migrateFAT0BalanceDeltas populates the "address_balance_delta" table by
+// re-parsing every valid transaction of a FAT-0 chain. This is a no-op for
+// chains that are not issued or are not FAT-0.
+func migrateFAT0BalanceDeltas(conn *sqlite.Conn) error {
+	initID, err := metadata.SelectInitEntryID(conn)
+	if err != nil {
+		return err
+	}
+	if initID == 0 {
+		return nil
+	}
+
+	e, err := entry.SelectByID(conn, initID)
+	if err != nil {
+		return err
+	}
+	var issuance struct {
+		Type fat.Type `json:"type"`
+	}
+	if err := json.Unmarshal(e.Content, &issuance); err != nil {
+		return fmt.Errorf("json.Unmarshal(): %w", err)
+	}
+	if issuance.Type != fat.TypeFAT0 {
+		return nil
+	}
+
+	stmt := conn.Prep(`SELECT "id", "data" FROM "entry"
+                WHERE "valid" = true AND "id" > ? ORDER BY "id";`)
+	stmt.BindInt64(1, initID)
+	defer stmt.Reset()
+	for {
+		hasRow, err := stmt.Step()
+		if err != nil {
+			return err
+		}
+		if !hasRow {
+			return nil
+		}
+		eID := stmt.ColumnInt64(0)
+		data := make([]byte, stmt.ColumnLen(1))
+		stmt.ColumnBytes(1, data)
+		var e factom.Entry
+		if err := e.UnmarshalBinary(data); err != nil {
+			return fmt.Errorf("factom.Entry.UnmarshalBinary(): %w", err)
+		}
+		var tx fat0.Transaction
+		if err := tx.UnmarshalJSON(e.Content); err != nil {
+			return fmt.Errorf("fat0.Transaction.UnmarshalJSON(): %w", err)
+		}
+		if !tx.IsCoinbase() {
+			for adr, amount := range tx.Inputs {
+				if err := insertBalanceDelta(conn,
+					&adr, eID, -int64(amount)); err != nil {
+					return err
+				}
+			}
+		}
+		for adr, amount := range tx.Outputs {
+			if err := insertBalanceDelta(conn,
+				&adr, eID, int64(amount)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func insertBalanceDelta(conn *sqlite.Conn,
+	adr *factom.FAAddress, eID, delta int64) error {
+	adrID, err := address.SelectID(conn, adr)
+	if err != nil {
+		return fmt.Errorf("address.SelectID(%v): %w", adr, err)
+	}
+	return address.InsertBalanceDelta(conn, adrID, eID, delta)
+}
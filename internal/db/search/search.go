@@ -0,0 +1,294 @@
+// MIT License
+//
+// Copyright 2018 Canonical Ledgers, LLC
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+// Package search provides functions and SQL fragments for working with the
+// "nftoken_search" and "issuance_search" FTS5 tables, which index the string
+// values within NFToken and Issuance metadata for full-text search.
+package search
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"crawshaw.io/sqlite"
+	"crawshaw.io/sqlite/sqlitex"
+	"github.com/AdamSLevy/sqlbuilder"
+	"github.com/Factom-Asset-Tokens/factom"
+	"github.com/Factom-Asset-Tokens/factom/fat1"
+)
+
+// CreateTable is a SQL string that creates the "nftoken_search" and
+// "issuance_search" FTS5 tables.
+//
+// The "nftoken_search" rowid is the NFTokenID. The original "metadata" is
+// stored alongside the indexed "text" so that the index can be checked
+// against the "nftoken" table by Sync. The "issuance_search" table holds at
+// most one row, with rowid 1.
+const CreateTable = `CREATE VIRTUAL TABLE "nftoken_search" USING fts5(
+        "text",
+        "metadata" UNINDEXED
+);
+CREATE VIRTUAL TABLE "issuance_search" USING fts5(
+        "text"
+);
+`
+
+// tables are the FTS5 tables created by CreateTable.
+var tables = []string{"nftoken_search", "issuance_search"}
+
+// IsIndexTable returns true if name is one of the FTS5 tables created by
+// CreateTable or one of their shadow tables.
+//
+// The shadow tables must never be attached to a sqlite.Session. Reverting
+// them by applying an inverted changeset corrupts the FTS5 index. Use Sync
+// to bring the index back in line with the "nftoken" and "fat_chain" tables
+// instead.
+func IsIndexTable(name string) bool {
+	for _, table := range tables {
+		if name == table || strings.HasPrefix(name, table+"_") {
+			return true
+		}
+	}
+	return false
+}
+
+// selectText is a SQL expression that concatenates all string values within
+// the JSON bound to the parameter.
+const selectText = `(SELECT group_concat("value", ' ')
+                FROM json_tree(?) WHERE "type" = 'text')`
+
+// InsertNFToken indexes the string values within the metadata of nfID,
+// replacing any existing entry for nfID. Metadata that is not valid JSON is
+// not indexed.
+func InsertNFToken(conn *sqlite.Conn,
+	nfID fat1.NFTokenID, metadata json.RawMessage) error {
+	stmt := conn.Prep(`INSERT OR REPLACE INTO "nftoken_search"
+                ("rowid", "text", "metadata") SELECT ?, ` + selectText + `, ?
+                WHERE json_valid(?);`)
+	stmt.BindInt64(1, int64(nfID))
+	stmt.BindBytes(2, metadata)
+	stmt.BindBytes(3, metadata)
+	stmt.BindBytes(4, metadata)
+	_, err := stmt.Step()
+	return err
+}
+
+// InsertIssuance indexes the tokenID, symbol, and the string values within
+// the metadata of the Issuance, replacing any existing entry.
+func InsertIssuance(conn *sqlite.Conn,
+	tokenID, symbol string, metadata json.RawMessage) error {
+	if !json.Valid(metadata) {
+		metadata = nil
+	}
+	stmt := conn.Prep(`INSERT OR REPLACE INTO "issuance_search"
+                ("rowid", "text") VALUES (1, ? || ' ' || ? || ' ' ||
+                ifnull(` + selectText + `, ''));`)
+	stmt.BindText(1, tokenID)
+	stmt.BindText(2, symbol)
+	stmt.BindBytes(3, metadata)
+	_, err := stmt.Step()
+	return err
+}
+
+// Sync updates the index to match the current "nftoken" metadata and removes
+// the Issuance from the index if the chain is not issued. This is only needed
+// after changes are made to the "nftoken" or "fat_chain" tables without
+// calling InsertNFToken or InsertIssuance, such as when a sqlite.Session is
+// reverted.
+func Sync(conn *sqlite.Conn) error {
+	if err := SyncIssuance(conn); err != nil {
+		return err
+	}
+	err := sqlitex.ExecScript(conn, `
+DELETE FROM "nftoken_search" WHERE "rowid" NOT IN (
+        SELECT "id" FROM "nftoken" WHERE "metadata" IS NOT NULL);`)
+	if err != nil {
+		return err
+	}
+	stmt := conn.Prep(`SELECT "id", "nftoken"."metadata" FROM "nftoken"
+                LEFT JOIN "nftoken_search" ON "id" = "nftoken_search"."rowid"
+                WHERE "nftoken"."metadata" IS NOT NULL AND
+                "nftoken"."metadata" IS NOT "nftoken_search"."metadata";`)
+	defer stmt.Reset()
+	var nfIDs []fat1.NFTokenID
+	var metadata []json.RawMessage
+	for {
+		hasRow, err := stmt.Step()
+		if err != nil {
+			return err
+		}
+		if !hasRow {
+			break
+		}
+		nfIDs = append(nfIDs, fat1.NFTokenID(stmt.ColumnInt64(0)))
+		m := make(json.RawMessage, stmt.ColumnLen(1))
+		stmt.ColumnBytes(1, m)
+		metadata = append(metadata, m)
+	}
+	for i, nfID := range nfIDs {
+		if err := InsertNFToken(conn, nfID, metadata[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SyncNFTokens is like Sync, but only updates the index for nfIDs, so that
+// its cost is proportional to the number of NFTokens changed.
+func SyncNFTokens(conn *sqlite.Conn, nfIDs []fat1.NFTokenID) error {
+	for _, nfID := range nfIDs {
+		if err := syncNFToken(conn, nfID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func syncNFToken(conn *sqlite.Conn, nfID fat1.NFTokenID) error {
+	stmt := conn.Prep(`SELECT "metadata" FROM "nftoken"
+                WHERE "id" = ? AND "metadata" IS NOT NULL;`)
+	stmt.BindInt64(1, int64(nfID))
+	defer stmt.Reset()
+	hasRow, err := stmt.Step()
+	if err != nil {
+		return err
+	}
+	if !hasRow {
+		stmt := conn.Prep(`DELETE FROM "nftoken_search" WHERE "rowid" = ?;`)
+		stmt.BindInt64(1, int64(nfID))
+		_, err := stmt.Step()
+		return err
+	}
+	metadata := make(json.RawMessage, stmt.ColumnLen(0))
+	stmt.ColumnBytes(0, metadata)
+	return InsertNFToken(conn, nfID, metadata)
+}
+
+// SyncIssuance removes the Issuance from the index if the chain is not
+// issued.
+func SyncIssuance(conn *sqlite.Conn) error {
+	return sqlitex.ExecScript(conn, `
+DELETE FROM "issuance_search" WHERE
+        (SELECT "init_entry_id" FROM "fat_chain") IS NULL;`)
+}
+
+// Clear removes all entries from the index.
+func Clear(conn *sqlite.Conn) error {
+	return sqlitex.ExecScript(conn, `
+DELETE FROM "nftoken_search";
+DELETE FROM "issuance_search";`)
+}
+
+// Query converts the whitespace separated terms in q into an FTS5 query that
+// matches rows containing all of the terms. Each term is quoted so that it is
+// never interpreted as FTS5 query syntax, except that a trailing "*" is
+// preserved as a prefix match. Query returns an empty string if q has no
+// terms.
+func Query(q string) string {
+	var terms []string
+	for _, term := range strings.Fields(q) {
+		var prefix string
+		if strings.HasSuffix(term, "*") {
+			term = strings.TrimRight(term, "*")
+			prefix = "*"
+		}
+		if len(term) == 0 {
+			continue
+		}
+		terms = append(terms,
+			`"`+strings.ReplaceAll(term, `"`, `""`)+`"`+prefix)
+	}
+	return strings.Join(terms, " ")
+}
+
+// SelectNFTokens returns the NFTokens whose metadata matches the FTS5 query,
+// ordered by relevance, along with their owners, creation hashes, and
+// metadata.
+func SelectNFTokens(conn *sqlite.Conn, query string, page, limit uint) (
+	[]fat1.NFTokenID, []factom.FAAddress, []factom.Bytes32, [][]byte, error) {
+	if page == 0 {
+		return nil, nil, nil, nil, fmt.Errorf("invalid page")
+	}
+	var sql sqlbuilder.SQLBuilder
+	// Always join with "nftoken_address" so that only NFTokens that
+	// currently exist are returned.
+	sql.Append(`SELECT "id", "owner", "creation_hash",
+                        "nftoken_address"."metadata"
+                FROM "nftoken_search" JOIN "nftoken_address"
+                        ON "nftoken_search"."rowid" = "id"
+                WHERE "nftoken_search" MATCH ? ORDER BY "rank"`,
+		func(s *sqlite.Stmt, p int) int {
+			s.BindText(p, query)
+			return 1
+		})
+	sql.Paginate(page, limit)
+
+	stmt := sql.Prep(conn)
+	defer stmt.Reset()
+
+	var tkns []fat1.NFTokenID
+	var owners []factom.FAAddress
+	var creationHashes []factom.Bytes32
+	var metadata [][]byte
+	for {
+		hasRow, err := stmt.Step()
+		if err != nil {
+			return nil, nil, nil, nil, err
+		}
+		if !hasRow {
+			break
+		}
+		tkns = append(tkns, fat1.NFTokenID(stmt.ColumnInt64(0)))
+
+		var owner factom.FAAddress
+		if stmt.ColumnBytes(1, owner[:]) != len(owner) {
+			panic("invalid address length")
+		}
+		owners = append(owners, owner)
+
+		var creationHash factom.Bytes32
+		if stmt.ColumnBytes(2, creationHash[:]) != len(creationHash) {
+			panic("invalid hash length")
+		}
+		creationHashes = append(creationHashes, creationHash)
+
+		m := make([]byte, stmt.ColumnLen(3))
+		stmt.ColumnBytes(3, m)
+		metadata = append(metadata, m)
+	}
+	return tkns, owners, creationHashes, metadata, nil
+}
+
+// SelectIssuanceRank returns the FTS5 rank of the Issuance against the query,
+// and false if the Issuance does not match. Lower ranks are better matches.
+func SelectIssuanceRank(conn *sqlite.Conn, query string) (float64, bool, error) {
+	stmt := conn.Prep(`SELECT "rank" FROM "issuance_search"
+                WHERE "issuance_search" MATCH ?;`)
+	stmt.BindText(1, query)
+	defer stmt.Reset()
+	hasRow, err := stmt.Step()
+	if err != nil || !hasRow {
+		return 0, false, err
+	}
+	return stmt.ColumnFloat(0), true, nil
+}
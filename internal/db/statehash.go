@@ -0,0 +1,317 @@
+// MIT License
+//
+// Copyright 2018 Canonical Ledgers, LLC
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package db
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"sort"
+
+	"crawshaw.io/sqlite"
+	"github.com/Factom-Asset-Tokens/factom"
+	"github.com/Factom-Asset-Tokens/fatd/internal/db/eblock"
+)
+
+// State hash leaves are prefixed so that address and NFToken leaves can never
+// collide.
+const (
+	stateHashAddressPrefix byte = 0x00
+	stateHashNFTokenPrefix byte = 0x01
+)
+
+// stateChanges holds the balances and NFToken ownership changed by the
+// Entries of a single EBlock.
+type stateChanges struct {
+	Balances map[factom.FAAddress]uint64
+	NFTokens map[uint64]nfTokenState
+}
+
+type nfTokenState struct {
+	Owner        factom.FAAddress
+	MetadataHash factom.Bytes32
+}
+
+func newStateChanges() stateChanges {
+	return stateChanges{
+		Balances: make(map[factom.FAAddress]uint64),
+		NFTokens: make(map[uint64]nfTokenState),
+	}
+}
+
+// Hash returns the state hash after applying the changes to the state with
+// the given prev state hash.
+//
+// If there are no changes, the state hash is prev. Otherwise it is
+// sha256(prev | root), where root is the Merkle root of the following leaves,
+// which are each hashed with sha256:
+//
+//	0x00 | address (32 bytes) | balance (8 bytes, big endian)
+//
+// for each address whose balance changed, including to zero, sorted by
+// address, followed by
+//
+//	0x01 | NFTokenID (8 bytes, big endian) | owner (32 bytes) |
+//	        sha256(metadata) (32 bytes)
+//
+// for each NFToken that was created or transferred, sorted by NFTokenID. The
+// Merkle tree is computed the same way as a DBlock body Merkle root. The state
+// hash prior to the first EBlock is all zeros.
+//
+// So the state hash is a hash chain over the history of changes, not a Merkle
+// root over the full state, and cannot prove the inclusion of a balance or
+// NFToken owner.
+func (s stateChanges) Hash(prev factom.Bytes32) (factom.Bytes32, error) {
+	adrs := make([]factom.FAAddress, 0, len(s.Balances))
+	for adr := range s.Balances {
+		adrs = append(adrs, adr)
+	}
+	sort.Slice(adrs, func(i, j int) bool {
+		return bytes.Compare(adrs[i][:], adrs[j][:]) < 0
+	})
+
+	nfIDs := make([]uint64, 0, len(s.NFTokens))
+	for nfID := range s.NFTokens {
+		nfIDs = append(nfIDs, nfID)
+	}
+	sort.Slice(nfIDs, func(i, j int) bool { return nfIDs[i] < nfIDs[j] })
+
+	leaves := make([][]byte, 0, len(adrs)+len(nfIDs))
+	for _, adr := range adrs {
+		leaf := make([]byte, 1+32+8)
+		leaf[0] = stateHashAddressPrefix
+		copy(leaf[1:], adr[:])
+		binary.BigEndian.PutUint64(leaf[1+32:], s.Balances[adr])
+		leaves = append(leaves, leaf)
+	}
+	for _, nfID := range nfIDs {
+		nfTkn := s.NFTokens[nfID]
+		leaf := make([]byte, 1+8+32+32)
+		leaf[0] = stateHashNFTokenPrefix
+		binary.BigEndian.PutUint64(leaf[1:], nfID)
+		copy(leaf[1+8:], nfTkn.Owner[:])
+		copy(leaf[1+8+32:], nfTkn.MetadataHash[:])
+		leaves = append(leaves, leaf)
+	}
+
+	if len(leaves) == 0 {
+		return prev, nil
+	}
+	root, err := factom.ComputeDBlockBodyMR(leaves)
+	if err != nil {
+		return factom.Bytes32{}, err
+	}
+	return sha256.Sum256(append(prev[:], root[:]...)), nil
+}
+
+// ComputeStateHash returns the state hash of the EBlock with sequence seq,
+// which must be the latest applied EBlock. Only the balances and NFTokens
+// changed by the Entries in the EBlock are read, and folded into the state
+// hash of the previous EBlock. See stateChanges.Hash for how it is computed.
+func (chain *FATChain) ComputeStateHash(seq uint32) (factom.Bytes32, error) {
+	var prev factom.Bytes32
+	if seq > 0 {
+		hash, err := eblock.SelectStateHash(chain.Conn, seq-1)
+		if err != nil {
+			return factom.Bytes32{}, err
+		}
+		if hash == nil {
+			return factom.Bytes32{}, fmt.Errorf(
+				"EBlock %v has no state hash", seq-1)
+		}
+		prev = *hash
+	}
+
+	changes := newStateChanges()
+	if err := selectBalanceChanges(chain.Conn, seq, changes); err != nil {
+		return factom.Bytes32{}, err
+	}
+	if err := selectNFTokenChanges(chain.Conn, seq, changes); err != nil {
+		return factom.Bytes32{}, err
+	}
+	return changes.Hash(prev)
+}
+
+// selectBalanceChanges adds the current balance of each address with a
+// balance delta in the EBlock with sequence seq to changes.
+func selectBalanceChanges(conn *sqlite.Conn,
+	seq uint32, changes stateChanges) error {
+	stmt := conn.Prep(`SELECT "address", "balance" FROM "address"
+                WHERE "id" IN (SELECT "address_id"
+                        FROM "address_balance_delta", "entry"
+                                ON "entry_id" = "entry"."id"
+                        WHERE "eb_seq" = ?);`)
+	defer stmt.Reset()
+	stmt.BindInt64(1, int64(seq))
+	for {
+		hasRow, err := stmt.Step()
+		if err != nil {
+			return err
+		}
+		if !hasRow {
+			return nil
+		}
+		var adr factom.FAAddress
+		if stmt.ColumnBytes(0, adr[:]) != len(adr) {
+			panic("invalid address length")
+		}
+		changes.Balances[adr] = uint64(stmt.ColumnInt64(1))
+	}
+}
+
+// selectNFTokenChanges adds the current owner and metadata of each NFToken
+// received by an address in the EBlock with sequence seq to changes.
+func selectNFTokenChanges(conn *sqlite.Conn,
+	seq uint32, changes stateChanges) error {
+	stmt := conn.Prep(`SELECT "nftoken"."id", "address", "metadata"
+                FROM "nftoken", "address" ON "owner_id" = "address"."id"
+                WHERE "nftoken"."id" IN (SELECT "nftoken_id"
+                        FROM "nftoken_address_tx" AS "tkn_tx", "entry"
+                                ON "tkn_tx"."entry_id" = "entry"."id"
+                        WHERE "to" = true AND "eb_seq" = ?);`)
+	defer stmt.Reset()
+	stmt.BindInt64(1, int64(seq))
+	for {
+		hasRow, err := stmt.Step()
+		if err != nil {
+			return err
+		}
+		if !hasRow {
+			return nil
+		}
+		var nfTkn nfTokenState
+		if stmt.ColumnBytes(1, nfTkn.Owner[:]) != len(nfTkn.Owner) {
+			panic("invalid address length")
+		}
+		nfTkn.MetadataHash = columnSHA256(stmt, 2)
+		changes.NFTokens[uint64(stmt.ColumnInt64(0))] = nfTkn
+	}
+}
+
+func columnSHA256(stmt *sqlite.Stmt, col int) factom.Bytes32 {
+	data := make([]byte, stmt.ColumnLen(col))
+	stmt.ColumnBytes(col, data)
+	return sha256.Sum256(data)
+}
+
+// migrateStateHashes computes the "state_hash" of all existing EBlocks from
+// the "address_balance_delta" and "nftoken_address_tx" tables. The balances
+// are summed up in memory since the "address" table only holds the latest
+// balances.
+func migrateStateHashes(conn *sqlite.Conn) error {
+	balances := make(map[factom.FAAddress]uint64)
+
+	metadata := make(map[uint64]factom.Bytes32)
+	stmt := conn.Prep(`SELECT "id", "metadata" FROM "nftoken";`)
+	for {
+		hasRow, err := stmt.Step()
+		if err != nil {
+			stmt.Reset()
+			return err
+		}
+		if !hasRow {
+			break
+		}
+		metadata[uint64(stmt.ColumnInt64(0))] = columnSHA256(stmt, 1)
+	}
+
+	deltaStmt := conn.Prep(`SELECT "address", "delta"
+                FROM "address_balance_delta" AS "adr_delta", "address", "entry"
+                        ON "adr_delta"."address_id" = "address"."id" AND
+                                "adr_delta"."entry_id" = "entry"."id"
+                WHERE "eb_seq" = ?;`)
+	defer deltaStmt.Reset()
+	ownerStmt := conn.Prep(`SELECT "nftoken_id", "address"
+                FROM "nftoken_address_tx" AS "tkn_tx", "address", "entry"
+                        ON "tkn_tx"."address_id" = "address"."id" AND
+                                "tkn_tx"."entry_id" = "entry"."id"
+                WHERE "to" = true AND "eb_seq" = ?
+                ORDER BY "tkn_tx"."entry_id";`)
+	defer ownerStmt.Reset()
+
+	seqStmt := conn.Prep(`SELECT "seq" FROM "eblock" ORDER BY "seq";`)
+	var seqs []uint32
+	for {
+		hasRow, err := seqStmt.Step()
+		if err != nil {
+			seqStmt.Reset()
+			return err
+		}
+		if !hasRow {
+			break
+		}
+		seqs = append(seqs, uint32(seqStmt.ColumnInt64(0)))
+	}
+
+	var hash factom.Bytes32
+	for _, seq := range seqs {
+		changes := newStateChanges()
+
+		deltaStmt.Reset()
+		deltaStmt.BindInt64(1, int64(seq))
+		for {
+			hasRow, err := deltaStmt.Step()
+			if err != nil {
+				return err
+			}
+			if !hasRow {
+				break
+			}
+			var adr factom.FAAddress
+			if deltaStmt.ColumnBytes(0, adr[:]) != len(adr) {
+				panic("invalid address length")
+			}
+			balances[adr] += uint64(deltaStmt.ColumnInt64(1))
+			changes.Balances[adr] = balances[adr]
+		}
+
+		ownerStmt.Reset()
+		ownerStmt.BindInt64(1, int64(seq))
+		for {
+			hasRow, err := ownerStmt.Step()
+			if err != nil {
+				return err
+			}
+			if !hasRow {
+				break
+			}
+			nfID := uint64(ownerStmt.ColumnInt64(0))
+			nfTkn := nfTokenState{MetadataHash: metadata[nfID]}
+			if ownerStmt.ColumnBytes(1, nfTkn.Owner[:]) !=
+				len(nfTkn.Owner) {
+				panic("invalid address length")
+			}
+			changes.NFTokens[nfID] = nfTkn
+		}
+
+		var err error
+		if hash, err = changes.Hash(hash); err != nil {
+			return fmt.Errorf("db.stateChanges.Hash(): %w", err)
+		}
+		if err := eblock.SetStateHash(conn, seq, &hash); err != nil {
+			return fmt.Errorf("eblock.SetStateHash(): %w", err)
+		}
+	}
+	return nil
+}
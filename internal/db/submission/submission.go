@@ -0,0 +1,197 @@
+// MIT License
+//
+// Copyright 2018 Canonical Ledgers, LLC
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+// Package submission provides functions and SQL fragments for working with the
+// "submission" table, which stores the local outbox of transactions sent with
+// send-transaction.
+package submission
+
+import (
+	"time"
+
+	"crawshaw.io/sqlite"
+	"github.com/Factom-Asset-Tokens/factom"
+)
+
+// CreateTable is a SQL string that creates the "submission" table if it does
+// not already exist.
+//
+// Unlike the other tables, the "submission" table is not stored in a chain
+// database, so it is never rewound or reverted along with chain state.
+const CreateTable = `CREATE TABLE IF NOT EXISTS "submission" (
+        "entry_hash"    BLOB PRIMARY KEY,
+        "chain_id"      BLOB NOT NULL,
+        "tx_id"         BLOB NOT NULL UNIQUE,
+        "data"          BLOB NOT NULL,
+        "status"        TEXT NOT NULL,
+        "valid"         BOOL NOT NULL DEFAULT FALSE,
+        "reason"        TEXT NOT NULL DEFAULT '',
+        "reveals"       INTEGER NOT NULL DEFAULT 0,
+        "submitted"     INTEGER NOT NULL,
+        "updated"       INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS "idx_submission_status" ON "submission"("status");
+`
+
+// Submission is a transaction entry that was committed to factomd by this
+// fatd node.
+type Submission struct {
+	Hash    *factom.Bytes32
+	ChainID *factom.Bytes32
+	TxID    *factom.Bytes32
+
+	// Data is the raw entry, which is required to reveal it again.
+	Data []byte
+
+	Status string
+	Valid  bool
+	Reason string
+
+	// Reveals is the number of times the entry has been revealed.
+	Reveals int64
+
+	Submitted time.Time
+	Updated   time.Time
+}
+
+// Insert s into the "submission" table. If the entry was submitted before,
+// the existing row is replaced.
+func Insert(conn *sqlite.Conn, s Submission) error {
+	stmt := conn.Prep(`INSERT OR REPLACE INTO "submission"
+                ("entry_hash", "chain_id", "tx_id", "data", "status",
+                        "submitted", "updated")
+                VALUES (?, ?, ?, ?, ?, ?, ?);`)
+	stmt.BindBytes(1, s.Hash[:])
+	stmt.BindBytes(2, s.ChainID[:])
+	stmt.BindBytes(3, s.TxID[:])
+	stmt.BindBytes(4, s.Data)
+	stmt.BindText(5, s.Status)
+	stmt.BindInt64(6, s.Submitted.Unix())
+	stmt.BindInt64(7, s.Submitted.Unix())
+	_, err := stmt.Step()
+	return err
+}
+
+// UpdateStatus sets the status, valid flag and invalid reason of the
+// submission with hash.
+func UpdateStatus(conn *sqlite.Conn, hash *factom.Bytes32,
+	status string, valid bool, reason string) error {
+	stmt := conn.Prep(`UPDATE "submission" SET
+                "status" = ?, "valid" = ?, "reason" = ?, "updated" = ?
+                WHERE "entry_hash" = ?;`)
+	stmt.BindText(1, status)
+	stmt.BindBool(2, valid)
+	stmt.BindText(3, reason)
+	stmt.BindInt64(4, time.Now().Unix())
+	stmt.BindBytes(5, hash[:])
+	_, err := stmt.Step()
+	return err
+}
+
+// UpdateRevealed sets the status of the submission with hash and increments
+// its number of reveals.
+func UpdateRevealed(conn *sqlite.Conn, hash *factom.Bytes32, status string) error {
+	stmt := conn.Prep(`UPDATE "submission" SET
+                "status" = ?, "reveals" = "reveals" + 1, "updated" = ?
+                WHERE "entry_hash" = ?;`)
+	stmt.BindText(1, status)
+	stmt.BindInt64(2, time.Now().Unix())
+	stmt.BindBytes(3, hash[:])
+	_, err := stmt.Step()
+	return err
+}
+
+// SelectWhere is a SQL fragment for retrieving rows from the "submission"
+// table with Select().
+const SelectWhere = `SELECT "entry_hash", "chain_id", "tx_id", "data",
+        "status", "valid", "reason", "reveals", "submitted", "updated"
+        FROM "submission" WHERE `
+
+// Select the next Submission from the given prepared Stmt. If there are no
+// more rows, a Submission with a nil Hash is returned.
+//
+// The Stmt must be created with a SQL string starting with SelectWhere.
+func Select(stmt *sqlite.Stmt) (Submission, error) {
+	var s Submission
+	hasRow, err := stmt.Step()
+	if err != nil || !hasRow {
+		return s, err
+	}
+
+	s.Hash = new(factom.Bytes32)
+	if stmt.ColumnBytes(0, s.Hash[:]) != len(s.Hash) {
+		panic("invalid hash length")
+	}
+	s.ChainID = new(factom.Bytes32)
+	if stmt.ColumnBytes(1, s.ChainID[:]) != len(s.ChainID) {
+		panic("invalid chain id length")
+	}
+	s.TxID = new(factom.Bytes32)
+	if stmt.ColumnBytes(2, s.TxID[:]) != len(s.TxID) {
+		panic("invalid tx id length")
+	}
+	s.Data = make([]byte, stmt.ColumnLen(3))
+	stmt.ColumnBytes(3, s.Data)
+
+	s.Status = stmt.ColumnText(4)
+	s.Valid = stmt.ColumnInt(5) != 0
+	s.Reason = stmt.ColumnText(6)
+	s.Reveals = stmt.ColumnInt64(7)
+	s.Submitted = time.Unix(stmt.ColumnInt64(8), 0)
+	s.Updated = time.Unix(stmt.ColumnInt64(9), 0)
+
+	return s, nil
+}
+
+// SelectByHash returns the Submission of the entry with hash.
+func SelectByHash(conn *sqlite.Conn, hash *factom.Bytes32) (Submission, error) {
+	stmt := conn.Prep(SelectWhere + `"entry_hash" = ?;`)
+	stmt.BindBytes(1, hash[:])
+	defer stmt.Reset()
+	return Select(stmt)
+}
+
+// SelectByTxID returns the Submission with the commit txID.
+func SelectByTxID(conn *sqlite.Conn, txID *factom.Bytes32) (Submission, error) {
+	stmt := conn.Prep(SelectWhere + `"tx_id" = ?;`)
+	stmt.BindBytes(1, txID[:])
+	defer stmt.Reset()
+	return Select(stmt)
+}
+
+// SelectByStatus returns all Submissions with status, oldest first.
+func SelectByStatus(conn *sqlite.Conn, status string) ([]Submission, error) {
+	stmt := conn.Prep(SelectWhere + `"status" = ? ORDER BY "submitted";`)
+	stmt.BindText(1, status)
+	defer stmt.Reset()
+	var subs []Submission
+	for {
+		s, err := Select(stmt)
+		if err != nil {
+			return nil, err
+		}
+		if s.Hash == nil {
+			return subs, nil
+		}
+		subs = append(subs, s)
+	}
+}
@@ -32,8 +32,12 @@ import (
 	"time"
 
 	"github.com/Factom-Asset-Tokens/factom"
+	"github.com/Factom-Asset-Tokens/fatd/api"
+	"github.com/Factom-Asset-Tokens/fatd/internal/events"
 	"github.com/Factom-Asset-Tokens/fatd/internal/flag"
 	_log "github.com/Factom-Asset-Tokens/fatd/internal/log"
+	"github.com/Factom-Asset-Tokens/fatd/internal/metrics"
+	"github.com/Factom-Asset-Tokens/fatd/internal/state"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -70,9 +74,7 @@ func Start(ctx context.Context, c *factom.Client) (done <-chan struct{}) {
 		return
 	}
 
-	// Add NetworkID subdirectory.
-	flag.DBPath += fmt.Sprintf("%s%c",
-		strings.ReplaceAll(flag.NetworkID.String(), " ", ""), os.PathSeparator)
+	flag.DBPath = networkDBPath()
 
 	log.Debug("Loading state...")
 	state, ctx, err := openState(ctx, c,
@@ -131,12 +133,41 @@ func Start(ctx context.Context, c *factom.Client) (done <-chan struct{}) {
 		}
 	}
 
+	metrics.SyncHeight.Set(float64(syncHeight))
+
 	_done := make(chan struct{})
 	_state = state // Set global _state
 	go engine(ctx, c, _done, state)
 	return _done
 }
 
+// networkDBPath returns flag.DBPath with the NetworkID subdirectory added.
+func networkDBPath() string {
+	return flag.DBPath + fmt.Sprintf("%s%c",
+		strings.ReplaceAll(flag.NetworkID.String(), " ", ""), os.PathSeparator)
+}
+
+// RewindDB rewinds the database for chainID to height without starting the
+// engine. The chain will be resynced the next time the engine is started.
+func RewindDB(ctx context.Context, chainID *factom.Bytes32, height uint32) error {
+	return state.RewindDB(ctx, networkDBPath(), chainID, height)
+}
+
+// ExportSnapshot writes a snapshot of all chain databases to out, signed by
+// sk1, without starting the engine.
+func ExportSnapshot(ctx context.Context, out string, sk1 factom.SK1Key) error {
+	return state.ExportSnapshot(ctx, networkDBPath(), out, sk1)
+}
+
+// ImportSnapshot imports the snapshot in, which must be signed by id1, without
+// starting the engine. Syncing resumes from the snapshot height the next time
+// the engine is started.
+func ImportSnapshot(ctx context.Context,
+	in string, id1 factom.ID1Key, validate bool) error {
+	return state.ImportSnapshot(ctx, networkDBPath(), in, id1,
+		flag.NetworkID, validate)
+}
+
 func engine(ctx context.Context, c *factom.Client,
 	done chan struct{}, state State) {
 
@@ -206,6 +237,7 @@ func engine(ctx context.Context, c *factom.Client,
 				return
 			}
 			retries++
+			metrics.FactomdRetries.Inc()
 			log.Infof("Retrying in %v... (%v)",
 				flag.FactomScanInterval, retries)
 		} else {
@@ -217,6 +249,10 @@ func engine(ctx context.Context, c *factom.Client,
 var (
 	syncHeight, factomHeight uint32
 	heightMtx                = &sync.RWMutex{}
+
+	// factomdDownSince is the time of the first failed height query since
+	// the last successful one, or zero if the last one succeeded.
+	factomdDownSince time.Time
 )
 
 // GetSyncStatus is a threadsafe way to get the sync height and current Factom
@@ -227,22 +263,71 @@ func GetSyncStatus() (sync, current uint32) {
 	return syncHeight, factomHeight
 }
 
+// Ready returns an error describing why the engine is not ready to serve up
+// to date API requests, or nil if it is.
+//
+// The engine is not ready if the sync height lags the Factom Blockchain
+// height by more than -apireadymaxlag blocks, if any chain is still
+// completing its initial sync, or if factomd has been unreachable for longer
+// than -factomscaninterval * -factomscanretries.
+func Ready() error {
+	if _state == nil {
+		return fmt.Errorf("engine not started")
+	}
+
+	heightMtx.RLock()
+	sync, current := syncHeight, factomHeight
+	downSince := factomdDownSince
+	heightMtx.RUnlock()
+
+	// Unlimited or zero retries still allow for one failed scan.
+	retries := flag.FactomScanRetries
+	if retries < 1 {
+		retries = 1
+	}
+	if !downSince.IsZero() {
+		if down := time.Since(downSince); down >
+			flag.FactomScanInterval*time.Duration(retries) {
+			return fmt.Errorf("factomd unreachable for %v",
+				down.Round(time.Second))
+		}
+	}
+
+	if lag := current - sync; uint64(lag) > flag.APIReadyMaxLag {
+		return fmt.Errorf("sync height %v lags factom height %v",
+			sync, current)
+	}
+
+	if n := _state.Syncing(); n > 0 {
+		return fmt.Errorf("%v chains still syncing", n)
+	}
+
+	return nil
+}
+
 func setSyncHeight(sync uint32) {
 	heightMtx.Lock()
 	defer heightMtx.Unlock()
 	syncHeight = sync
+	metrics.SyncHeight.Set(float64(sync))
 }
 
 func updateFactomHeight(ctx context.Context, c *factom.Client) error {
 	// Get the current Factom Blockchain height.
 	var heights factom.Heights
 	err := heights.Get(ctx, c)
+	heightMtx.Lock()
+	defer heightMtx.Unlock()
 	if err != nil {
+		metrics.FactomdFailures.WithLabelValues("heights").Inc()
+		if factomdDownSince.IsZero() {
+			factomdDownSince = time.Now()
+		}
 		return fmt.Errorf("factom.Heights.Get(): %v", err)
 	}
-	heightMtx.Lock()
-	defer heightMtx.Unlock()
+	factomdDownSince = time.Time{}
 	factomHeight = heights.Entry
+	metrics.FactomHeight.Set(float64(factomHeight))
 	return nil
 }
 
@@ -261,6 +346,7 @@ func ApplyDBlock(ctx context.Context, c *factom.Client, h uint32, state State) e
 	// Load next DBlock.
 	dblock := factom.DBlock{Height: h}
 	if err := dblock.Get(ctx, c); err != nil {
+		metrics.FactomdFailures.WithLabelValues("dblock").Inc()
 		return fmt.Errorf("%#v.Get(): %w", dblock, err)
 	}
 
@@ -307,6 +393,8 @@ func ApplyDBlock(ctx context.Context, c *factom.Client, h uint32, state State) e
 	if err := state.SetSync(ctx, h, dblock.KeyMR); err != nil {
 		return fmt.Errorf("state.SetSync(): %w", err)
 	}
+	sync, current := GetSyncStatus()
+	events.Publish(api.EventSync{Sync: sync, Current: current})
 
 	// Check that we haven't been told to stop.
 	return ctx.Err()
@@ -316,6 +404,7 @@ func ApplyPendingEntries(ctx context.Context, c *factom.Client, state State) err
 	var pe factom.PendingEntries
 	// Get and apply any pending entries.
 	if err := pe.Get(ctx, c); err != nil {
+		metrics.FactomdFailures.WithLabelValues("pending-entries").Inc()
 		return fmt.Errorf("factom.PendingEntries.Get(): %w", err)
 	}
 
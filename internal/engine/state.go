@@ -12,9 +12,11 @@ type State interface {
 	ApplyPendingEntries(context.Context, []factom.Entry) error
 	SetSync(context.Context, uint32, *factom.Bytes32) error
 	GetSync() uint32
+	Syncing() int
 	TrackedIDs() []*factom.Bytes32
 	IssuedIDs() []*factom.Bytes32
 	Get(context.Context, *factom.Bytes32, bool) (state.Chain, func(), error)
+	Rewind(context.Context, *factom.Bytes32, uint32) error
 	Close()
 }
 
@@ -33,6 +35,13 @@ var openState = func(ctx context.Context, c *factom.Client,
 
 var _state State
 
+// SetState sets the State queried by Get, TrackedIDs, IssuedIDs and Rewind.
+// This allows the API to be served from a State without running the engine,
+// such as in tests.
+func SetState(state State) {
+	_state = state
+}
+
 func Get(ctx context.Context, chainID *factom.Bytes32, includePending bool) (
 	state.Chain, func(), error) {
 	return _state.Get(ctx, chainID, includePending)
@@ -45,3 +54,8 @@ func TrackedIDs() []*factom.Bytes32 {
 func IssuedIDs() []*factom.Bytes32 {
 	return _state.IssuedIDs()
 }
+
+// Rewind the tracked chain with chainID to height and resync it.
+func Rewind(ctx context.Context, chainID *factom.Bytes32, height uint32) error {
+	return _state.Rewind(ctx, chainID, height)
+}
@@ -0,0 +1,123 @@
+// MIT License
+//
+// Copyright 2018 Canonical Ledgers, LLC
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+// Package events provides a simple publish/subscribe hub for notifying API
+// subscribers about transactions and sync progress as the engine applies
+// them.
+package events
+
+import (
+	"sync"
+
+	"github.com/Factom-Asset-Tokens/factom"
+	"github.com/Factom-Asset-Tokens/fatd/api"
+)
+
+// BufferSize is the number of events that may be queued for a Subscription
+// before it is considered too slow and is closed.
+const BufferSize = 1000
+
+// Transaction is published for each pending, confirmed, or dropped FAT
+// transaction. Addresses holds all addresses involved in the transaction so
+// that subscribers may filter on them.
+type Transaction struct {
+	api.EventTransaction
+	Addresses []factom.FAAddress
+}
+
+// Involves returns true if adr is one of the Addresses of tx.
+func (tx Transaction) Involves(adr factom.FAAddress) bool {
+	for _, a := range tx.Addresses {
+		if a == adr {
+			return true
+		}
+	}
+	return false
+}
+
+// Subscription receives all published events for which its filter returns
+// true on C. C is closed when the Subscription is closed, either by calling
+// Close, or because the subscriber failed to keep up with the published
+// events.
+type Subscription struct {
+	C      <-chan interface{}
+	c      chan interface{}
+	filter func(interface{}) bool
+}
+
+var (
+	mtx  sync.Mutex
+	subs = make(map[*Subscription]struct{})
+)
+
+// Subscribe returns a new Subscription for all events for which filter
+// returns true. The filter must not block.
+func Subscribe(filter func(interface{}) bool) *Subscription {
+	c := make(chan interface{}, BufferSize)
+	sub := &Subscription{C: c, c: c, filter: filter}
+	mtx.Lock()
+	defer mtx.Unlock()
+	subs[sub] = struct{}{}
+	return sub
+}
+
+// Close removes the Subscription from the hub and closes C. It is safe to
+// call Close more than once.
+func (sub *Subscription) Close() {
+	mtx.Lock()
+	defer mtx.Unlock()
+	sub.close()
+}
+
+func (sub *Subscription) close() {
+	if _, ok := subs[sub]; !ok {
+		return
+	}
+	delete(subs, sub)
+	close(sub.c)
+}
+
+// HasSubscribers returns true if there are any open Subscriptions. Publishers
+// may use this to avoid the work of constructing events that no one will
+// receive.
+func HasSubscribers() bool {
+	mtx.Lock()
+	defer mtx.Unlock()
+	return len(subs) > 0
+}
+
+// Publish ev to all Subscriptions that accept it. Publish never blocks. Any
+// Subscription with a full buffer is closed.
+func Publish(ev interface{}) {
+	mtx.Lock()
+	defer mtx.Unlock()
+	for sub := range subs {
+		if !sub.filter(ev) {
+			continue
+		}
+		select {
+		case sub.c <- ev:
+		default:
+			sub.close()
+		}
+	}
+}
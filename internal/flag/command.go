@@ -0,0 +1,207 @@
+// MIT License
+//
+// Copyright 2018 Canonical Ledgers, LLC
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package flag
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/Factom-Asset-Tokens/factom"
+)
+
+// Command is the optional command given after all other flags. If empty, the
+// daemon is run normally.
+var Command string
+
+// Flags for the "rewind" command.
+var (
+	RewindChainID factom.Bytes32
+	RewindHeight  uint64
+)
+
+// Flags for the "snapshot" command.
+var (
+	// SnapshotCommand is either "export" or "import".
+	SnapshotCommand string
+
+	SnapshotOut      string
+	SnapshotSK1File  string
+	SnapshotSK1      factom.SK1Key
+	SnapshotIn       string
+	SnapshotID1      factom.ID1Key
+	SnapshotValidate bool
+)
+
+// snapshotSK1Env is the environment variable that the SK1 key for "snapshot
+// export" is read from if -sk1file is not used.
+const snapshotSK1Env = envNamePrefix + "SNAPSHOT_SK1"
+
+var commands = map[string]*flag.FlagSet{
+	"rewind": func() *flag.FlagSet {
+		fs := flag.NewFlagSet("rewind", flag.ExitOnError)
+		fs.Var(&RewindChainID, "chainid",
+			"Chain ID of the tracked chain to rewind")
+		fs.Uint64Var(&RewindHeight, "height", 0,
+			"DBlock height to rewind the chain to")
+		fs.Usage = func() {
+			fmt.Fprintf(fs.Output(),
+				"Usage: %v [flags] rewind -chainid <chain-id> -height <height>\n\n"+
+					"Delete all EBlocks above height and recompute the state of the chain.\n"+
+					"The chain is resynced from factomd when fatd is next started.\n\n",
+				os.Args[0])
+			fs.PrintDefaults()
+		}
+		return fs
+	}(),
+	"snapshot": func() *flag.FlagSet {
+		fs := flag.NewFlagSet("snapshot", flag.ExitOnError)
+		fs.Usage = func() {
+			fmt.Fprintf(fs.Output(),
+				"Usage: %v [flags] snapshot export|import [flags]\n\n"+
+					"Export or import a signed archive of all chain databases.\n"+
+					"Run \"snapshot export -h\" or \"snapshot import -h\" for details.\n",
+				os.Args[0])
+		}
+		return fs
+	}(),
+}
+
+var snapshotCommands = map[string]*flag.FlagSet{
+	"export": func() *flag.FlagSet {
+		fs := flag.NewFlagSet("snapshot export", flag.ExitOnError)
+		fs.StringVar(&SnapshotOut, "out", "",
+			"File to write the snapshot archive to")
+		fs.StringVar(&SnapshotSK1File, "sk1file", "",
+			"File containing the SK1 key to sign the snapshot with\n"+
+				"Environment variable: "+snapshotSK1Env)
+		fs.Usage = func() {
+			fmt.Fprintf(fs.Output(),
+				"Usage: %v [flags] snapshot export -out <file> -sk1file <file>\n\n"+
+					"Write all chain databases, and the height they are synced to, to a\n"+
+					"single signed and checksummed archive.\n\n"+
+					"The SK1 key is read from -sk1file, or otherwise from the %v\n"+
+					"environment variable, so that it is not exposed in the process list.\n\n",
+				os.Args[0], snapshotSK1Env)
+			fs.PrintDefaults()
+		}
+		return fs
+	}(),
+	"import": func() *flag.FlagSet {
+		fs := flag.NewFlagSet("snapshot import", flag.ExitOnError)
+		fs.StringVar(&SnapshotIn, "in", "",
+			"File to read the snapshot archive from")
+		fs.Var(&SnapshotID1, "id1key",
+			"ID1 key that the snapshot must be signed by")
+		fs.BoolVar(&SnapshotValidate, "validate", false,
+			"Fully validate each chain before importing it")
+		fs.Usage = func() {
+			fmt.Fprintf(fs.Output(),
+				"Usage: %v [flags] snapshot import -in <file> -id1key <id1-key> [-validate]\n\n"+
+					"Verify and extract a snapshot archive into an empty database directory.\n"+
+					"Syncing resumes from the snapshot height when fatd is next started.\n\n",
+				os.Args[0])
+			fs.PrintDefaults()
+		}
+		return fs
+	}(),
+}
+
+// parseCommand parses the Command and its flags from the remaining arguments
+// after all other flags have been parsed.
+func parseCommand() {
+	args := flag.Args()
+	if len(args) == 0 {
+		return
+	}
+	Command = args[0]
+	fs, ok := commands[Command]
+	if !ok {
+		log.Fatalf("unknown command: %q", Command)
+	}
+	fs.Parse(args[1:])
+
+	switch Command {
+	case "rewind":
+		if RewindChainID.IsZero() {
+			log.Fatal("rewind: -chainid is required")
+		}
+		set := false
+		fs.Visit(func(f *flag.Flag) { set = set || f.Name == "height" })
+		if !set {
+			log.Fatal("rewind: -height is required")
+		}
+		if RewindHeight > math.MaxUint32 {
+			log.Fatal("rewind: -height is too large")
+		}
+	case "snapshot":
+		SnapshotCommand = fs.Arg(0)
+		sfs, ok := snapshotCommands[SnapshotCommand]
+		if !ok {
+			fs.Usage()
+			log.Fatalf("unknown snapshot command: %q", SnapshotCommand)
+		}
+		sfs.Parse(fs.Args()[1:])
+		switch SnapshotCommand {
+		case "export":
+			if SnapshotOut == "" {
+				log.Fatal("snapshot export: -out is required")
+			}
+			if err := loadSnapshotSK1(); err != nil {
+				log.Fatalf("snapshot export: %v", err)
+			}
+		case "import":
+			if SnapshotIn == "" {
+				log.Fatal("snapshot import: -in is required")
+			}
+			if SnapshotID1 == (factom.ID1Key{}) {
+				log.Fatal("snapshot import: -id1key is required")
+			}
+		}
+	}
+}
+
+// loadSnapshotSK1 sets SnapshotSK1 from the contents of SnapshotSK1File, or
+// otherwise from snapshotSK1Env. The key is never accepted as an argument
+// since it would be visible to other users in the process list.
+func loadSnapshotSK1() error {
+	sk1 := os.Getenv(snapshotSK1Env)
+	if len(SnapshotSK1File) > 0 {
+		data, err := ioutil.ReadFile(SnapshotSK1File)
+		if err != nil {
+			return fmt.Errorf("-sk1file: %w", err)
+		}
+		sk1 = strings.TrimSpace(string(data))
+	}
+	if len(sk1) == 0 {
+		return fmt.Errorf("-sk1file or %v is required", snapshotSK1Env)
+	}
+	// Do not include the error, which may contain the key.
+	if err := SnapshotSK1.Set(sk1); err != nil {
+		return fmt.Errorf("invalid SK1 key")
+	}
+	return nil
+}
@@ -53,13 +53,30 @@ var (
 
 		"dbpath": "DB_PATH",
 
-		"apiaddress":  "API_ADDRESS",
-		"apiusername": "API_USERNAME",
-		"apipassword": "API_PASSWORD",
-		"apitlscert":  "API_TLS_CERT",
-		"apitlskey":   "API_TLS_KEY",
-		"apimaxlimit": "API_MAX_LIMIT",
-		"apitimeout":  "API_TIMEOUT",
+		"apiaddress":     "API_ADDRESS",
+		"apiusername":    "API_USERNAME",
+		"apipassword":    "API_PASSWORD",
+		"apikeys":        "API_KEYS",
+		"apilisteners":   "API_LISTENERS",
+		"apitlscert":     "API_TLS_CERT",
+		"apitlskey":      "API_TLS_KEY",
+		"apitlsclientca": "API_TLS_CLIENT_CA",
+		"apimaxlimit":    "API_MAX_LIMIT",
+		"apitimeout":     "API_TIMEOUT",
+		"apimetrics":     "API_METRICS",
+
+		"apireadymaxlag": "API_READY_MAX_LAG",
+
+		"apiratelimit":        "API_RATE_LIMIT",
+		"apirateburst":        "API_RATE_BURST",
+		"apimethodratelimits": "API_METHOD_RATE_LIMITS",
+		"apimaxexpensive":     "API_MAX_EXPENSIVE",
+
+		"apicorsorigins":     "API_CORS_ORIGINS",
+		"apicorssendorigins": "API_CORS_SEND_ORIGINS",
+		"apicorsmethods":     "API_CORS_METHODS",
+		"apicorsheaders":     "API_CORS_HEADERS",
+		"apicorscredentials": "API_CORS_CREDENTIALS",
 
 		"s":               "FACTOMD_SERVER",
 		"factomdtimeout":  "FACTOMD_TIMEOUT",
@@ -100,13 +117,25 @@ var (
 			return "./fatd.db"
 		}(),
 
-		"apiaddress":  ":8078",
-		"apiusername": "",
-		"apipassword": "",
-		"apitlscert":  "",
-		"apitlskey":   "",
-		"apimaxlimit": uint64(math.MaxUint32),
-		"apitimeout":  5 * time.Second,
+		"apiaddress":     ":8078",
+		"apiusername":    "",
+		"apipassword":    "",
+		"apikeys":        "",
+		"apilisteners":   "",
+		"apitlscert":     "",
+		"apitlskey":      "",
+		"apitlsclientca": "",
+		"apimaxlimit":    uint64(math.MaxUint32),
+		"apitimeout":     5 * time.Second,
+		"apimetrics":     false,
+
+		"apireadymaxlag": uint64(1),
+
+		"apiratelimit":    uint64(0),
+		"apirateburst":    uint64(10),
+		"apimaxexpensive": uint64(0),
+
+		"apicorscredentials": false,
 
 		"s":               "http://localhost:8088/v2",
 		"factomdtimeout":  20 * time.Second,
@@ -138,13 +167,30 @@ var (
 
 		"dbpath": "Path to the folder containing all database files",
 
-		"apiaddress":  "IPAddr:port# to bind to for serving the fatd API",
-		"apiusername": "Username required for connections to fatd API",
-		"apipassword": "Password required for connections to fatd API",
-		"apitlscert":  "Path to TLS certificate for the fatd API",
-		"apitlskey":   "Path to TLS Key for the fatd API",
-		"apimaxlimit": "Maximum pagination limit",
-		"apitimeout":  "Maximum amount of time to allow API queries to complete",
+		"apiaddress":     "IPAddr:port# to bind to for serving the fatd API",
+		"apiusername":    "Username required for connections to fatd API",
+		"apipassword":    "Password required for connections to fatd API",
+		"apikeys":        "Path to a JSON file of API keys with read, send or admin scope",
+		"apilisteners":   "Path to a JSON file of API listeners, overriding -apiaddress, -apitlscert and -apitlskey",
+		"apitlscert":     "Path to TLS certificate for the fatd API",
+		"apitlskey":      "Path to TLS Key for the fatd API",
+		"apitlsclientca": "Path to a CA bundle to verify fatd API client certificates",
+		"apimaxlimit":    "Maximum pagination limit",
+		"apitimeout":     "Maximum amount of time to allow API queries to complete",
+		"apimetrics":     "Serve Prometheus metrics at /metrics on the fatd API",
+
+		"apireadymaxlag": "Maximum blocks the sync height may lag the Factom height for /readyz to succeed",
+
+		"apiratelimit":        "Maximum API requests per second for each IP or API key, 0 means unlimited",
+		"apirateburst":        "Maximum burst of API requests for each IP or API key above the rate limits",
+		"apimethodratelimits": `Comma separated "method:rate" API requests per second for each IP or API key`,
+		"apimaxexpensive":     "Maximum concurrent expensive API queries, such as get-transactions, 0 means unlimited",
+
+		"apicorsorigins":     `Comma separated CORS allowed origins for the fatd API (default "*")`,
+		"apicorssendorigins": "Comma separated CORS allowed origins for send-transaction (default -apicorsorigins)",
+		"apicorsmethods":     "Comma separated CORS allowed HTTP methods (default GET,POST,HEAD)",
+		"apicorsheaders":     "Comma separated CORS allowed HTTP headers (default Origin,Accept,Content-Type,X-Requested-With)",
+		"apicorscredentials": "Allow CORS requests with credentials, such as the API key",
 
 		"s":               "IPAddr:port# of factomd API to use to access blockchain",
 		"factomdtimeout":  "Timeout for factomd API requests, 0 means never timeout",
@@ -179,13 +225,30 @@ var (
 
 		"-dbpath": complete.PredictFiles("*"),
 
-		"-apiaddress":  complete.PredictAnything,
-		"-apiusername": complete.PredictAnything,
-		"-apipassword": complete.PredictAnything,
-		"-apitlscert":  complete.PredictFiles("*.cert"),
-		"-apitlskey":   complete.PredictFiles("*.key"),
-		"-apimaxlimit": complete.PredictAnything,
-		"-apitimeout":  complete.PredictAnything,
+		"-apiaddress":     complete.PredictAnything,
+		"-apiusername":    complete.PredictAnything,
+		"-apipassword":    complete.PredictAnything,
+		"-apikeys":        complete.PredictFiles("*.json"),
+		"-apilisteners":   complete.PredictFiles("*.json"),
+		"-apitlscert":     complete.PredictFiles("*.cert"),
+		"-apitlskey":      complete.PredictFiles("*.key"),
+		"-apitlsclientca": complete.PredictFiles("*.pem"),
+		"-apimaxlimit":    complete.PredictAnything,
+		"-apitimeout":     complete.PredictAnything,
+		"-apimetrics":     complete.PredictNothing,
+
+		"-apireadymaxlag": complete.PredictAnything,
+
+		"-apiratelimit":        complete.PredictAnything,
+		"-apirateburst":        complete.PredictAnything,
+		"-apimethodratelimits": complete.PredictAnything,
+		"-apimaxexpensive":     complete.PredictAnything,
+
+		"-apicorsorigins":     complete.PredictAnything,
+		"-apicorssendorigins": complete.PredictAnything,
+		"-apicorsmethods":     complete.PredictSet("GET", "POST", "HEAD", "OPTIONS"),
+		"-apicorsheaders":     complete.PredictAnything,
+		"-apicorscredentials": complete.PredictNothing,
 
 		"-s":               complete.PredictAnything,
 		"-factomdtimeout":  complete.PredictAnything,
@@ -235,9 +298,24 @@ var (
 
 	DBPath string
 
-	APIAddress  string
-	APIMaxLimit uint64
-	APITimeout  time.Duration
+	APIAddress       string
+	APIListenersFile string
+	APIMaxLimit      uint64
+	APITimeout       time.Duration
+	APIMetrics       bool
+
+	APIReadyMaxLag uint64
+
+	APIRateLimit        uint64
+	APIRateBurst        uint64
+	APIMethodRateLimits MethodRates
+	APIMaxExpensive     uint64
+
+	APICORSOrigins     StringList
+	APICORSSendOrigins StringList
+	APICORSMethods     StringList
+	APICORSHeaders     StringList
+	APICORSCredentials bool
 
 	FactomClient = factom.NewClient()
 	NetworkID    factom.NetworkID
@@ -250,13 +328,16 @@ var (
 	ignoreNewChains      bool
 	SkipDBValidation     bool
 
-	HasAuth  bool
-	Username string
-	Password string
+	HasAuth     bool
+	Username    string
+	Password    string
+	APIKeysFile string
 
 	HasTLS      bool
 	TLSCertFile string
 	TLSKeyFile  string
+
+	TLSClientCAFile string
 )
 
 func init() {
@@ -272,11 +353,25 @@ func init() {
 	flagVar(&APIAddress, "apiaddress")
 	flagVar(&APIMaxLimit, "apimaxlimit")
 	flagVar(&APITimeout, "apitimeout")
+	flagVar(&APIMetrics, "apimetrics")
+	flagVar(&APIReadyMaxLag, "apireadymaxlag")
+	flagVar(&APIRateLimit, "apiratelimit")
+	flagVar(&APIRateBurst, "apirateburst")
+	flagVar(&APIMethodRateLimits, "apimethodratelimits")
+	flagVar(&APIMaxExpensive, "apimaxexpensive")
+	flagVar(&APICORSOrigins, "apicorsorigins")
+	flagVar(&APICORSSendOrigins, "apicorssendorigins")
+	flagVar(&APICORSMethods, "apicorsmethods")
+	flagVar(&APICORSHeaders, "apicorsheaders")
+	flagVar(&APICORSCredentials, "apicorscredentials")
 	// Added in FatD authentication info.
 	flagVar(&Username, "apiusername")
 	flagVar(&Password, "apipassword")
+	flagVar(&APIKeysFile, "apikeys")
+	flagVar(&APIListenersFile, "apilisteners")
 	flagVar(&TLSCertFile, "apitlscert")
 	flagVar(&TLSKeyFile, "apitlskey")
+	flagVar(&TLSClientCAFile, "apitlsclientca")
 
 	flagVar(&ECAdr, "ecadr")
 	flagVar(&EsAdr, "esadr")
@@ -314,6 +409,7 @@ func Parse() {
 	flag.Visit(func(f *flag.Flag) { flagset[f.Name] = true })
 
 	setupLogger()
+	parseCommand()
 
 	// Load options from environment variables if they haven't been
 	// specified on the command line.
@@ -327,6 +423,20 @@ func Parse() {
 	loadFromEnv(&DBPath, "dbpath")
 
 	loadFromEnv(&APIAddress, "apiaddress")
+	loadFromEnv(&APIKeysFile, "apikeys")
+	loadFromEnv(&APIListenersFile, "apilisteners")
+	loadFromEnv(&TLSClientCAFile, "apitlsclientca")
+	loadFromEnv(&APIMetrics, "apimetrics")
+	loadFromEnv(&APIReadyMaxLag, "apireadymaxlag")
+	loadFromEnv(&APIRateLimit, "apiratelimit")
+	loadFromEnv(&APIRateBurst, "apirateburst")
+	loadFromEnv(&APIMethodRateLimits, "apimethodratelimits")
+	loadFromEnv(&APIMaxExpensive, "apimaxexpensive")
+	loadFromEnv(&APICORSOrigins, "apicorsorigins")
+	loadFromEnv(&APICORSSendOrigins, "apicorssendorigins")
+	loadFromEnv(&APICORSMethods, "apicorsmethods")
+	loadFromEnv(&APICORSHeaders, "apicorsheaders")
+	loadFromEnv(&APICORSCredentials, "apicorscredentials")
 
 	loadFromEnv(&FactomClient.FactomdServer, "s")
 	loadFromEnv(&FactomClient.Factomd.Timeout, "factomdtimeout")
@@ -351,6 +461,12 @@ func Parse() {
 	if !flagset["networkid"] {
 		NetworkID = factom.MainnetID()
 	}
+	if len(APICORSOrigins) == 0 {
+		APICORSOrigins = StringList{"*"}
+	}
+	if len(APICORSSendOrigins) == 0 {
+		APICORSSendOrigins = APICORSOrigins
+	}
 }
 
 func Validate() {
@@ -370,6 +486,18 @@ func Validate() {
 
 	log.Debugf("-dbpath            %#v", DBPath)
 	log.Debugf("-apiaddress        %#v", APIAddress)
+	log.Debugf("-apilisteners      %#v", APIListenersFile)
+	log.Debugf("-apimetrics        %v", APIMetrics)
+	log.Debugf("-apireadymaxlag    %v", APIReadyMaxLag)
+	log.Debugf("-apiratelimit      %v", APIRateLimit)
+	log.Debugf("-apirateburst      %v", APIRateBurst)
+	log.Debugf("-apimethodratelimits %q", APIMethodRateLimits)
+	log.Debugf("-apimaxexpensive   %v", APIMaxExpensive)
+	log.Debugf("-apicorsorigins     %q", APICORSOrigins)
+	log.Debugf("-apicorssendorigins %q", APICORSSendOrigins)
+	log.Debugf("-apicorsmethods     %q", APICORSMethods)
+	log.Debugf("-apicorsheaders     %q", APICORSHeaders)
+	log.Debugf("-apicorscredentials %v", APICORSCredentials)
 	debugPrintln()
 
 	log.Debugf("-startscanheight   %v ", StartScanHeight)
@@ -392,8 +520,10 @@ func Validate() {
 
 	log.Debugf("-apiusername    %#v", Username)
 	log.Debugf("-apipassword    %v ", apiPassword)
+	log.Debugf("-apikeys        %#v", APIKeysFile)
 	log.Debugf("-apitlscert     %#v", TLSCertFile)
 	log.Debugf("-apitlskey      %#v", TLSKeyFile)
+	log.Debugf("-apitlsclientca %#v", TLSClientCAFile)
 	debugPrintln()
 
 	var err error
@@ -420,12 +550,36 @@ func Validate() {
 		}
 		HasAuth = true
 	}
+	if len(APIKeysFile) > 0 {
+		HasAuth = true
+	}
+	if APICORSCredentials && (originsAny(APICORSOrigins) ||
+		originsAny(APICORSSendOrigins)) {
+		log.Fatal(`-apicorscredentials may not be used with "*" origins`)
+	}
 	if len(TLSCertFile) > 0 || len(TLSKeyFile) > 0 {
 		if len(TLSCertFile) == 0 || len(TLSKeyFile) == 0 {
 			log.Fatal("-apitlscert and -apitlskey must be used together")
 		}
 		HasTLS = true
 	}
+	if len(TLSClientCAFile) > 0 {
+		if !HasTLS && len(APIListenersFile) == 0 {
+			log.Fatal("-apitlsclientca requires -apitlscert and -apitlskey")
+		}
+		if len(APIKeysFile) == 0 {
+			log.Fatal("-apitlsclientca requires -apikeys")
+		}
+	}
+}
+
+func originsAny(origins StringList) bool {
+	for _, origin := range origins {
+		if origin == "*" {
+			return true
+		}
+	}
+	return false
 }
 
 func flagVar(v interface{}, name string) {
@@ -23,6 +23,9 @@
 package flag
 
 import (
+	"fmt"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/Factom-Asset-Tokens/factom"
@@ -79,3 +82,53 @@ func (b32s *Bytes32List) Set(s string) error {
 	*b32s = append(*b32s, newB32s...)
 	return nil
 }
+
+// MethodRates maps API method names to a rate limit in requests per second.
+type MethodRates map[string]uint64
+
+func (rates MethodRates) String() string {
+	if len(rates) == 0 {
+		return ""
+	}
+	methods := make([]string, 0, len(rates))
+	for method := range rates {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	var s string
+	for _, method := range methods {
+		s += fmt.Sprintf("%v:%v,", method, rates[method])
+	}
+	return s[:len(s)-1]
+}
+
+// Set adds a comma seperated list of method:rate pairs.
+func (rates *MethodRates) Set(s string) error {
+	if *rates == nil {
+		*rates = make(MethodRates)
+	}
+	for _, pair := range strings.Split(s, ",") {
+		i := strings.LastIndex(pair, ":")
+		if i < 1 {
+			return fmt.Errorf("invalid method:rate %q", pair)
+		}
+		rate, err := strconv.ParseUint(pair[i+1:], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid method:rate %q: %w", pair, err)
+		}
+		(*rates)[pair[:i]] = rate
+	}
+	return nil
+}
+
+type StringList []string
+
+func (strs StringList) String() string {
+	return strings.Join(strs, ",")
+}
+
+// Set appends a comma seperated list of strings.
+func (strs *StringList) Set(s string) error {
+	*strs = append(*strs, strings.Split(s, ",")...)
+	return nil
+}
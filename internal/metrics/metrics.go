@@ -0,0 +1,129 @@
+// MIT License
+//
+// Copyright 2018 Canonical Ledgers, LLC
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+// Package metrics defines the Prometheus metrics reported by fatd. The
+// metrics are always collected, but are only served by the API at /metrics if
+// enabled with -apimetrics.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "fatd"
+
+var (
+	// SyncHeight is the last DBlock height fully applied by the engine.
+	SyncHeight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "sync_height",
+		Help:      "Last DBlock height fully applied by the engine.",
+	})
+	// FactomHeight is the current height of the Factom Blockchain
+	// reported by factomd.
+	FactomHeight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "factom_height",
+		Help:      "Current Factom Blockchain height reported by factomd.",
+	})
+
+	// EntriesApplied counts the confirmed entries applied to each chain.
+	EntriesApplied = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "entries_applied_total",
+		Help:      "Confirmed entries applied to each chain.",
+	}, []string{"chain"})
+	// Transactions counts the confirmed valid and invalid transactions
+	// applied to each chain.
+	Transactions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "transactions_total",
+		Help:      "Confirmed transactions applied to each chain.",
+	}, []string{"chain", "valid"})
+	// PendingEntries is the number of pending entries currently applied
+	// to each chain.
+	PendingEntries = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "pending_entries",
+		Help:      "Pending entries currently applied to each chain.",
+	}, []string{"chain"})
+
+	// RPCDuration observes the latency of each JSON RPC method.
+	RPCDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "rpc_duration_seconds",
+		Help:      "Latency of JSON RPC requests by method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method"})
+	// RPCErrors counts the JSON RPC requests that returned an error.
+	RPCErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rpc_errors_total",
+		Help:      "JSON RPC requests that returned an error by method.",
+	}, []string{"method"})
+
+	// DBPoolWait observes the time spent waiting for a read connection
+	// from a chain database pool.
+	DBPoolWait = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_pool_wait_seconds",
+		Help:      "Time spent waiting for a chain database read connection.",
+		Buckets:   prometheus.ExponentialBuckets(0.0001, 4, 10),
+	})
+
+	// FactomdFailures counts the failed requests to factomd by request.
+	FactomdFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "factomd_failures_total",
+		Help:      "Failed requests to factomd by request.",
+	}, []string{"request"})
+	// FactomdRetries counts the times that the engine has retried
+	// querying factomd for the latest height.
+	FactomdRetries = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "factomd_retries_total",
+		Help:      "Retries of the factomd height query by the engine.",
+	})
+
+	registry = prometheus.NewRegistry()
+)
+
+func init() {
+	registry.MustRegister(
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+		SyncHeight, FactomHeight,
+		EntriesApplied, Transactions, PendingEntries,
+		RPCDuration, RPCErrors,
+		DBPoolWait,
+		FactomdFailures, FactomdRetries,
+	)
+}
+
+// Handler returns an http.Handler that serves all metrics in the Prometheus
+// text format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,337 @@
+// MIT License
+//
+// Copyright 2018 Canonical Ledgers, LLC
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+// Package outbox keeps a local record of the transactions submitted with
+// send-transaction and tracks each one through the states committed,
+// revealed, pending and confirmed. Entries that factomd drops before they are
+// confirmed are revealed again.
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"crawshaw.io/sqlite"
+	"crawshaw.io/sqlite/sqlitex"
+	"github.com/Factom-Asset-Tokens/factom"
+	"github.com/Factom-Asset-Tokens/fatd/api"
+	"github.com/Factom-Asset-Tokens/fatd/internal/db/entry"
+	"github.com/Factom-Asset-Tokens/fatd/internal/db/submission"
+	"github.com/Factom-Asset-Tokens/fatd/internal/engine"
+	"github.com/Factom-Asset-Tokens/fatd/internal/flag"
+	_log "github.com/Factom-Asset-Tokens/fatd/internal/log"
+	"github.com/Factom-Asset-Tokens/fatd/internal/state"
+)
+
+const (
+	// dbFileName is the name of the outbox database within the network
+	// database directory. It does not look like a chain database so it is
+	// ignored when the chain databases are loaded.
+	dbFileName = "outbox.sqlite3"
+
+	// revealTimeout is how long a revealed entry may go unseen before it
+	// is revealed again.
+	revealTimeout = 2 * time.Minute
+
+	// commitExpiry is how long factomd holds a commit. After this, the
+	// entry can no longer be revealed and the submission is dropped.
+	commitExpiry = time.Hour
+)
+
+var (
+	log _log.Log
+
+	conn *sqlite.Conn
+	mtx  sync.Mutex
+)
+
+// Start opens the outbox database and launches the goroutine that tracks all
+// in flight submissions. The engine must already be started. The done channel
+// is closed when the goroutine exits after ctx is done. Errors are logged, and
+// failed updates are retried on the next tick, so that a factomd or database
+// error never stops the daemon.
+func Start(ctx context.Context, c *factom.Client) (done <-chan struct{}) {
+	log = _log.New("pkg", "outbox")
+
+	fname := flag.DBPath + dbFileName
+	const flags = sqlite.SQLITE_OPEN_READWRITE |
+		sqlite.SQLITE_OPEN_CREATE |
+		sqlite.SQLITE_OPEN_WAL |
+		sqlite.SQLITE_OPEN_NOMUTEX
+	var err error
+	if conn, err = sqlite.OpenConn(fname, flags); err != nil {
+		log.Errorf("sqlite.OpenConn(%q, %x): %v", fname, flags, err)
+		return
+	}
+	if err := sqlitex.ExecScript(conn, submission.CreateTable); err != nil {
+		log.Errorf("sqlitex.ExecScript(submission.CreateTable): %v", err)
+		conn.Close()
+		conn = nil
+		return
+	}
+
+	_done := make(chan struct{})
+	go track(ctx, c, _done)
+	return _done
+}
+
+func track(ctx context.Context, c *factom.Client, done chan struct{}) {
+	// Always close the database and done on exit.
+	defer func() {
+		mtx.Lock()
+		defer mtx.Unlock()
+		if err := conn.Close(); err != nil {
+			log.Errorf("sqlite.Conn.Close(): %v", err)
+		}
+		conn = nil
+		close(done)
+	}()
+
+	ticker := time.NewTicker(flag.FactomScanInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+		if err := update(ctx, c); err != nil && ctx.Err() == nil {
+			log.Errorf("update(): %v", err)
+		}
+	}
+}
+
+// update the status of all in flight submissions. Dropped submissions are
+// also rechecked, since their chain may have been behind factomd when they
+// were dropped. A submission that fails to update is logged and skipped, so
+// that it does not hold up the others.
+func update(ctx context.Context, c *factom.Client) error {
+	for _, status := range []string{
+		api.SubmissionStatusCommitted,
+		api.SubmissionStatusRevealed,
+		api.SubmissionStatusPending,
+		api.SubmissionStatusDropped,
+	} {
+		subs, err := selectByStatus(status)
+		if err != nil {
+			return fmt.Errorf("submission.SelectByStatus(%q): %w",
+				status, err)
+		}
+		for _, s := range subs {
+			if err := updateSubmission(ctx, c, s); err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				log.Errorf("updateSubmission(%v): %v", s.Hash, err)
+			}
+		}
+	}
+	return nil
+}
+
+func updateSubmission(ctx context.Context, c *factom.Client,
+	s submission.Submission) error {
+
+	e, reason, pending, err := lookup(ctx, s)
+	if err != nil {
+		return err
+	}
+
+	if e.IsPopulated() {
+		if pending {
+			if s.Status == api.SubmissionStatusPending {
+				return nil
+			}
+			return setStatus(s.Hash, api.SubmissionStatusPending,
+				false, "")
+		}
+		return setStatus(s.Hash, api.SubmissionStatusConfirmed,
+			len(reason) == 0, reason)
+	}
+
+	if s.Status == api.SubmissionStatusDropped {
+		// The commit has expired so the entry cannot be revealed
+		// again, but it may still turn up in its chain.
+		return nil
+	}
+
+	if time.Since(s.Submitted) > commitExpiry {
+		// The chain may be behind factomd, or no longer tracked, so
+		// only drop the entry if factomd does not have it either.
+		known, err := acknowledged(ctx, c, s)
+		if err != nil {
+			return fmt.Errorf("acknowledged(): %w", err)
+		}
+		if known {
+			return nil
+		}
+		log.Warnf("Dropping submission %v: not confirmed after %v",
+			s.Hash, commitExpiry)
+		return setStatus(s.Hash, api.SubmissionStatusDropped, false, "")
+	}
+
+	// The entry is not known. Reveal it if it was never revealed, if
+	// factomd dropped it after it was seen pending, or if it has gone
+	// unseen for too long.
+	if s.Status == api.SubmissionStatusRevealed &&
+		time.Since(s.Updated) < revealTimeout {
+		return nil
+	}
+	if err := c.Reveal(ctx, s.Data); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		// factomd rejects reveals of entries that it is still
+		// holding, so just try again later.
+		log.Debugf("factom.Client.Reveal(%v): %v", s.Hash, err)
+		return setStatus(s.Hash, s.Status, false, "")
+	}
+	log.Infof("Revealed submission %v (status: %v, reveals: %v)",
+		s.Hash, s.Status, s.Reveals+1)
+	return setRevealed(s.Hash)
+}
+
+// lookup the entry of s in its chain. If the entry is not found, e is not
+// populated. If the entry is invalid, reason is not empty.
+func lookup(ctx context.Context, s submission.Submission) (
+	e factom.Entry, reason string, pending bool, err error) {
+
+	chain, put, err := engine.Get(ctx, s.ChainID, !flag.DisablePending)
+	if err != nil {
+		return
+	}
+	if put == nil {
+		// The chain is no longer tracked.
+		return
+	}
+	defer put()
+	fatChain, ok := state.ToFATChain(chain)
+	if !ok {
+		return
+	}
+
+	if e, err = entry.SelectValidByHash(fatChain.Conn, s.Hash); err != nil {
+		err = fmt.Errorf("entry.SelectValidByHash(): %w", err)
+		return
+	}
+	if !e.IsPopulated() {
+		e, reason, err = entry.SelectInvalidByHash(fatChain.Conn, s.Hash)
+		if err != nil {
+			err = fmt.Errorf("entry.SelectInvalidByHash(): %w", err)
+			return
+		}
+	}
+	if e.IsPopulated() {
+		pending = fatChain.LatestEntryTimestamp().Before(e.Timestamp)
+	}
+	return
+}
+
+// acknowledged returns true if factomd has acknowledged or confirmed the
+// entry of s.
+func acknowledged(ctx context.Context, c *factom.Client,
+	s submission.Submission) (bool, error) {
+	params := struct {
+		Hash    *factom.Bytes32 `json:"hash"`
+		ChainID *factom.Bytes32 `json:"chainid"`
+	}{Hash: s.Hash, ChainID: s.ChainID}
+	var result struct {
+		EntryData struct {
+			Status string `json:"status"`
+		} `json:"entrydata"`
+	}
+	if err := c.FactomdRequest(ctx, "ack", params, &result); err != nil {
+		return false, err
+	}
+	switch result.EntryData.Status {
+	case "TransactionACK", "DBlockConfirmed":
+		return true, nil
+	}
+	return false, nil
+}
+
+// Add a newly committed entry to the outbox.
+func Add(chainID, hash, txID *factom.Bytes32, data []byte) error {
+	mtx.Lock()
+	defer mtx.Unlock()
+	if conn == nil {
+		return fmt.Errorf("outbox is closed")
+	}
+	return submission.Insert(conn, submission.Submission{
+		Hash:      hash,
+		ChainID:   chainID,
+		TxID:      txID,
+		Data:      data,
+		Status:    api.SubmissionStatusCommitted,
+		Submitted: time.Now(),
+	})
+}
+
+// Revealed records that the entry with hash was revealed.
+func Revealed(hash *factom.Bytes32) error {
+	return setRevealed(hash)
+}
+
+// Get the Submission with either the entry hash or the commit txID. If no
+// such Submission exists, the returned Submission has a nil Hash.
+func Get(hash, txID *factom.Bytes32) (submission.Submission, error) {
+	mtx.Lock()
+	defer mtx.Unlock()
+	if conn == nil {
+		return submission.Submission{}, fmt.Errorf("outbox is closed")
+	}
+	if hash != nil {
+		return submission.SelectByHash(conn, hash)
+	}
+	return submission.SelectByTxID(conn, txID)
+}
+
+func selectByStatus(status string) ([]submission.Submission, error) {
+	mtx.Lock()
+	defer mtx.Unlock()
+	return submission.SelectByStatus(conn, status)
+}
+
+func setStatus(hash *factom.Bytes32, status string, valid bool, reason string) error {
+	mtx.Lock()
+	defer mtx.Unlock()
+	if err := submission.UpdateStatus(conn,
+		hash, status, valid, reason); err != nil {
+		return fmt.Errorf("submission.UpdateStatus(): %w", err)
+	}
+	return nil
+}
+
+func setRevealed(hash *factom.Bytes32) error {
+	mtx.Lock()
+	defer mtx.Unlock()
+	if conn == nil {
+		return fmt.Errorf("outbox is closed")
+	}
+	if err := submission.UpdateRevealed(conn,
+		hash, api.SubmissionStatusRevealed); err != nil {
+		return fmt.Errorf("submission.UpdateRevealed(): %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+// MIT License
+//
+// Copyright 2018 Canonical Ledgers, LLC
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Factom-Asset-Tokens/factom"
+	"github.com/Factom-Asset-Tokens/fatd/internal/db/submission"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAcknowledged(t *testing.T) {
+	var status string
+	var req struct {
+		Method string `json:"method"`
+		Params struct {
+			Hash    *factom.Bytes32 `json:"hash"`
+			ChainID *factom.Bytes32 `json:"chainid"`
+		} `json:"params"`
+		ID json.RawMessage `json:"id"`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":{`+
+				`"entrydata":{"status":%q}}}`, req.ID, status)
+		}))
+	defer srv.Close()
+	c := factom.NewClient()
+	c.FactomdServer = srv.URL
+
+	s := submission.Submission{
+		Hash:    &factom.Bytes32{1},
+		ChainID: &factom.Bytes32{2},
+	}
+	for _, test := range []struct {
+		Status string
+		Exp    bool
+	}{
+		{"Unknown", false},
+		{"NotConfirmed", false},
+		{"TransactionACK", true},
+		{"DBlockConfirmed", true},
+	} {
+		status = test.Status
+		known, err := acknowledged(context.Background(), c, s)
+		require.NoError(t, err)
+		assert.Equal(t, test.Exp, known, test.Status)
+		assert.Equal(t, "ack", req.Method)
+		assert.Equal(t, *s.Hash, *req.Params.Hash)
+		assert.Equal(t, *s.ChainID, *req.Params.ChainID)
+	}
+}
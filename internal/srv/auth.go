@@ -0,0 +1,268 @@
+// MIT License
+//
+// Copyright 2018 Canonical Ledgers, LLC
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package srv
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+
+	jsonrpc2 "github.com/AdamSLevy/jsonrpc2/v14"
+	"github.com/Factom-Asset-Tokens/fatd/api"
+	"github.com/Factom-Asset-Tokens/fatd/internal/flag"
+	"github.com/goji/httpauth"
+)
+
+// scope is the access level of an API key. Each scope includes all lower
+// scopes.
+type scope int
+
+const (
+	scopeRead scope = iota
+	scopeSend
+	scopeAdmin
+)
+
+var scopeNames = map[scope]string{
+	scopeRead:  "read",
+	scopeSend:  "send",
+	scopeAdmin: "admin",
+}
+
+func (s scope) String() string {
+	return scopeNames[s]
+}
+
+func (s *scope) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return err
+	}
+	for sc, n := range scopeNames {
+		if n == name {
+			*s = sc
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid scope: %q", name)
+}
+
+// apiKey is a credential for the API. Clients authenticate using HTTP Basic
+// Auth with the Name as the username and the Key as the password, or with a
+// TLS client certificate verified by the -apitlsclientca with the Subject.
+type apiKey struct {
+	Name    string `json:"name"`
+	Key     string `json:"key,omitempty"`
+	Subject string `json:"subject,omitempty"`
+	Scope   scope  `json:"scope"`
+}
+
+// apiKeys are all credentials accepted by the API. It is only populated if
+// flag.HasAuth is true.
+var apiKeys []apiKey
+
+// loadAPIKeys returns the -apikeys file entries, along with the -apiusername
+// and -apipassword credentials, which have the admin scope.
+func loadAPIKeys() ([]apiKey, error) {
+	var keys []apiKey
+	if len(flag.APIKeysFile) > 0 {
+		data, err := ioutil.ReadFile(flag.APIKeysFile)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, &keys); err != nil {
+			return nil, fmt.Errorf("%v: %w", flag.APIKeysFile, err)
+		}
+		names := make(map[string]struct{}, len(keys))
+		for _, key := range keys {
+			if len(key.Name) == 0 ||
+				(len(key.Key) == 0 && len(key.Subject) == 0) {
+				return nil, fmt.Errorf(
+					"%v: name and either key or subject are required",
+					flag.APIKeysFile)
+			}
+			if _, ok := names[key.Name]; ok {
+				return nil, fmt.Errorf("%v: duplicate name: %q",
+					flag.APIKeysFile, key.Name)
+			}
+			names[key.Name] = struct{}{}
+		}
+	}
+	if len(flag.Username) > 0 {
+		keys = append(keys, apiKey{
+			Name:  flag.Username,
+			Key:   flag.Password,
+			Scope: scopeAdmin,
+		})
+	}
+	return keys, nil
+}
+
+// lookupAPIKey returns the apiKey with the given name and key, if any.
+func lookupAPIKey(name, key string) (apiKey, bool) {
+	for _, k := range apiKeys {
+		if len(k.Key) > 0 &&
+			subtle.ConstantTimeCompare([]byte(name), []byte(k.Name)) == 1 &&
+			subtle.ConstantTimeCompare([]byte(key), []byte(k.Key)) == 1 {
+			return k, true
+		}
+	}
+	return apiKey{}, false
+}
+
+// lookupClientCert returns the apiKey for the verified TLS client certificate
+// of r, if any. The Subject of the apiKey may be either the full subject of
+// the certificate, as in "CN=payments,O=Example", or just its common name.
+func lookupClientCert(r *http.Request) (apiKey, bool) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+		return apiKey{}, false
+	}
+	subject := r.TLS.VerifiedChains[0][0].Subject
+	for _, k := range apiKeys {
+		if len(k.Subject) > 0 && (k.Subject == subject.String() ||
+			k.Subject == subject.CommonName) {
+			return k, true
+		}
+	}
+	return apiKey{}, false
+}
+
+// requireAuth wraps h so that it is only called for requests with a valid
+// API key or a client certificate mapped to one.
+func requireAuth(h http.Handler) http.Handler {
+	basicAuth := httpauth.BasicAuth(httpauth.AuthOptions{
+		AuthFunc: func(name, key string, _ *http.Request) bool {
+			_, ok := lookupAPIKey(name, key)
+			return ok
+		},
+		UnauthorizedHandler: http.HandlerFunc(
+			func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write([]byte(`{}`))
+			}),
+	})(h)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := lookupClientCert(r); ok {
+			h.ServeHTTP(w, r)
+			return
+		}
+		basicAuth.ServeHTTP(w, r)
+	})
+}
+
+type callerCtxKey struct{}
+
+// caller identifies the client of a request for authorization and auditing.
+type caller struct {
+	apiKey
+	RemoteAddr string
+	Origin     string
+}
+
+func (c caller) String() string {
+	if len(c.Name) == 0 {
+		return c.RemoteAddr
+	}
+	return fmt.Sprintf("%v@%v", c.Name, c.RemoteAddr)
+}
+
+// withCaller adds the caller of each request to its context. The request must
+// already be authenticated if the listener requires auth.
+func withCaller(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c := caller{RemoteAddr: r.RemoteAddr,
+			Origin: r.Header.Get("Origin")}
+		if key, ok := lookupClientCert(r); ok {
+			c.apiKey = key
+		} else if name, key, ok := r.BasicAuth(); ok {
+			c.apiKey, _ = lookupAPIKey(name, key)
+		}
+		ctx := context.WithValue(r.Context(), callerCtxKey{}, c)
+		h.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// id returns the name of the API key of c, or if there is none, the IP
+// address of c.
+func (c caller) id() string {
+	if len(c.Name) > 0 {
+		return "key:" + c.Name
+	}
+	host, _, err := net.SplitHostPort(c.RemoteAddr)
+	if err != nil {
+		return c.RemoteAddr
+	}
+	return host
+}
+
+func getCaller(ctx context.Context) caller {
+	c, _ := ctx.Value(callerCtxKey{}).(caller)
+	return c
+}
+
+// authorize wraps method so that, if auth is true, it is only called if the
+// API key of the caller has at least scope sc. Browsers may only call send and
+// admin methods from the -apicorssendorigins. All calls to send and admin
+// methods are audit logged with a sha256 digest of their params, since the
+// params may hold secrets or large transactions.
+func authorize(name string, sc scope, auth bool,
+	method jsonrpc2.MethodFunc) jsonrpc2.MethodFunc {
+	return func(ctx context.Context, params json.RawMessage) interface{} {
+		c := getCaller(ctx)
+		if auth && c.Scope < sc {
+			if sc > scopeRead {
+				log.Warnf("audit: %v: %v denied: requires %v scope",
+					c, name, sc)
+			}
+			err := api.ErrorUnauthorized
+			err.Data = fmt.Sprintf("%v requires %v scope", name, sc)
+			return err
+		}
+		if sc > scopeRead && !sendOriginAllowed(c.Origin) {
+			log.Warnf("audit: %v: %v denied: origin %q not allowed",
+				c, name, c.Origin)
+			err := api.ErrorUnauthorized
+			err.Data = fmt.Sprintf("origin %q may not call %v",
+				c.Origin, name)
+			return err
+		}
+		res := method(ctx, params)
+		if sc == scopeRead {
+			return res
+		}
+		digest := sha256.Sum256(params)
+		if err, ok := res.(error); ok {
+			log.Infof("audit: %v: %v params sha256:%x: %v",
+				c, name, digest, err)
+		} else {
+			log.Infof("audit: %v: %v params sha256:%x: ok",
+				c, name, digest)
+		}
+		return res
+	}
+}
@@ -0,0 +1,59 @@
+// MIT License
+//
+// Copyright 2018 Canonical Ledgers, LLC
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package srv
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	_log "github.com/Factom-Asset-Tokens/fatd/internal/log"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthorizeAuditLog(t *testing.T) {
+	defer func(l _log.Log) { log = l }(log)
+	log = _log.New("pkg", "srv")
+	hook := test.NewLocal(log.Logger)
+
+	method := authorize("send-transaction", scopeSend, false,
+		func(context.Context, json.RawMessage) interface{} {
+			return "ok"
+		})
+	params := json.RawMessage(`{"secret":"Fs1KWJrpLdfucvmYwN2nWrwepLn8` +
+		`ercpMbzXshd1g8zyhKXLVLWj"}`)
+	ctx := context.WithValue(context.Background(), callerCtxKey{},
+		caller{RemoteAddr: "127.0.0.1"})
+	method(ctx, params)
+
+	entry := hook.LastEntry()
+	require.NotNil(t, entry)
+	assert.Equal(t, fmt.Sprintf(
+		"audit: 127.0.0.1: send-transaction params sha256:%x: ok",
+		sha256.Sum256(params)), entry.Message)
+	assert.NotContains(t, entry.Message, "secret")
+}
@@ -0,0 +1,92 @@
+// MIT License
+//
+// Copyright 2018 Canonical Ledgers, LLC
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package srv
+
+import (
+	"strings"
+
+	"github.com/Factom-Asset-Tokens/fatd/internal/flag"
+	"github.com/rs/cors"
+)
+
+// newCORS returns the CORS policy for the API. Browsers from any of the
+// -apicorsorigins or -apicorssendorigins may make requests, but only the
+// -apicorssendorigins may call sendMethods, which is enforced by authorize.
+func newCORS() *cors.Cors {
+	origins := append(flag.StringList{}, flag.APICORSOrigins...)
+	for _, origin := range flag.APICORSSendOrigins {
+		if !originAllowed(origins, origin) {
+			origins = append(origins, origin)
+		}
+	}
+	return cors.New(cors.Options{
+		AllowedOrigins:   origins,
+		AllowedMethods:   flag.APICORSMethods,
+		AllowedHeaders:   flag.APICORSHeaders,
+		AllowCredentials: flag.APICORSCredentials,
+	})
+}
+
+// originAllowed returns true if origin matches any of the allowed origins. An
+// allowed origin may be "*", or contain one "*" wildcard, as in
+// "https://*.example.com".
+func originAllowed(allowed []string, origin string) bool {
+	origin = strings.ToLower(origin)
+	for _, a := range allowed {
+		a = strings.ToLower(a)
+		if a == "*" || a == origin {
+			return true
+		}
+		i := strings.IndexByte(a, '*')
+		if i < 0 {
+			continue
+		}
+		prefix, suffix := a[:i], a[i+1:]
+		if len(origin) < len(prefix)+len(suffix) ||
+			!strings.HasPrefix(origin, prefix) ||
+			!strings.HasSuffix(origin, suffix) {
+			continue
+		}
+		// The wildcard may not match across the scheme or a path.
+		wild := origin[len(prefix) : len(origin)-len(suffix)]
+		if strings.IndexByte(wild, '/') < 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// wsOriginAllowed returns true if origin is empty, as it is for requests not
+// made by a browser, or if it is allowed by the CORS policy returned by
+// newCORS.
+func wsOriginAllowed(origin string) bool {
+	return len(origin) == 0 ||
+		originAllowed(flag.APICORSOrigins, origin) ||
+		originAllowed(flag.APICORSSendOrigins, origin)
+}
+
+// sendOriginAllowed returns true if origin is empty, as it is for requests
+// not made by a browser, or if it is one of the -apicorssendorigins.
+func sendOriginAllowed(origin string) bool {
+	return len(origin) == 0 || originAllowed(flag.APICORSSendOrigins, origin)
+}
@@ -0,0 +1,156 @@
+// MIT License
+//
+// Copyright 2018 Canonical Ledgers, LLC
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package srv
+
+import (
+	"testing"
+
+	"github.com/Factom-Asset-Tokens/fatd/internal/flag"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOriginAllowed(t *testing.T) {
+	for _, test := range []struct {
+		Name    string
+		Allowed []string
+		Origin  string
+		Exp     bool
+	}{{
+		Name:    "none",
+		Allowed: nil,
+		Origin:  "https://example.com",
+	}, {
+		Name:    "any",
+		Allowed: []string{"*"},
+		Origin:  "https://example.com",
+		Exp:     true,
+	}, {
+		Name:    "exact",
+		Allowed: []string{"https://other.com", "https://example.com"},
+		Origin:  "https://example.com",
+		Exp:     true,
+	}, {
+		Name:    "case insensitive",
+		Allowed: []string{"https://Example.com"},
+		Origin:  "HTTPS://EXAMPLE.COM",
+		Exp:     true,
+	}, {
+		Name:    "different scheme",
+		Allowed: []string{"https://example.com"},
+		Origin:  "http://example.com",
+	}, {
+		Name:    "different port",
+		Allowed: []string{"https://example.com"},
+		Origin:  "https://example.com:8080",
+	}, {
+		Name:    "wildcard subdomain",
+		Allowed: []string{"https://*.example.com"},
+		Origin:  "https://app.example.com",
+		Exp:     true,
+	}, {
+		Name:    "wildcard nested subdomain",
+		Allowed: []string{"https://*.example.com"},
+		Origin:  "https://a.b.example.com",
+		Exp:     true,
+	}, {
+		Name:    "wildcard without subdomain",
+		Allowed: []string{"https://*.example.com"},
+		Origin:  "https://example.com",
+	}, {
+		Name:    "wildcard suffix only",
+		Allowed: []string{"https://*.example.com"},
+		Origin:  "https://evil.com/.example.com",
+	}, {
+		Name:    "wildcard other suffix",
+		Allowed: []string{"https://*.example.com"},
+		Origin:  "https://app.example.com.evil.com",
+	}, {
+		Name:    "wildcard other scheme",
+		Allowed: []string{"https://*.example.com"},
+		Origin:  "http://app.example.com",
+	}, {
+		Name:    "wildcard prefix and suffix overlap",
+		Allowed: []string{"https://a*a.com"},
+		Origin:  "https://a.com",
+	}, {
+		Name:    "wildcard port",
+		Allowed: []string{"http://localhost:*"},
+		Origin:  "http://localhost:3000",
+		Exp:     true,
+	}} {
+		t.Run(test.Name, func(t *testing.T) {
+			assert.Equal(t, test.Exp,
+				originAllowed(test.Allowed, test.Origin))
+		})
+	}
+}
+
+func TestSendOriginAllowed(t *testing.T) {
+	defer func(origins, sendOrigins flag.StringList) {
+		flag.APICORSOrigins = origins
+		flag.APICORSSendOrigins = sendOrigins
+	}(flag.APICORSOrigins, flag.APICORSSendOrigins)
+	flag.APICORSOrigins = flag.StringList{"*"}
+	flag.APICORSSendOrigins = flag.StringList{"https://*.example.com"}
+
+	for _, test := range []struct {
+		Origin   string
+		Send, WS bool
+	}{{
+		Origin: "",
+		Send:   true,
+		WS:     true,
+	}, {
+		Origin: "https://wallet.example.com",
+		Send:   true,
+		WS:     true,
+	}, {
+		Origin: "https://explorer.com",
+		WS:     true,
+	}} {
+		assert.Equalf(t, test.Send, sendOriginAllowed(test.Origin),
+			"send origin: %q", test.Origin)
+		assert.Equalf(t, test.WS, wsOriginAllowed(test.Origin),
+			"ws origin: %q", test.Origin)
+	}
+
+	flag.APICORSOrigins = flag.StringList{"https://explorer.com"}
+	for _, test := range []struct {
+		Origin string
+		WS     bool
+	}{{
+		Origin: "",
+		WS:     true,
+	}, {
+		Origin: "https://explorer.com",
+		WS:     true,
+	}, {
+		Origin: "https://wallet.example.com",
+		WS:     true,
+	}, {
+		Origin: "https://evil.com",
+	}} {
+		assert.Equalf(t, test.WS, wsOriginAllowed(test.Origin),
+			"ws origin: %q", test.Origin)
+	}
+}
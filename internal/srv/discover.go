@@ -0,0 +1,619 @@
+// MIT License
+//
+// Copyright 2018 Canonical Ledgers, LLC
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package srv
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	jsonrpc2 "github.com/AdamSLevy/jsonrpc2/v14"
+	"github.com/Factom-Asset-Tokens/factom"
+	"github.com/Factom-Asset-Tokens/factom/fat0"
+	"github.com/Factom-Asset-Tokens/factom/fat1"
+	"github.com/Factom-Asset-Tokens/fatd/api"
+	"github.com/Factom-Asset-Tokens/fatd/internal/flag"
+	"github.com/Factom-Asset-Tokens/fatd/internal/standard"
+)
+
+// methodSchema describes the Go types of the params and result of a method.
+type methodSchema struct {
+	Summary string
+	Params  interface{}
+	Result  interface{}
+	// Paged is true if the method returns an api.ResultPage of Result when
+	// a "cursor" is used.
+	Paged bool
+}
+
+// methodSchemas describe the methods in jsonrpc2Methods, sendMethods and
+// adminMethods for rpc.discover.
+var methodSchemas = map[string]methodSchema{
+	"get-issuance": {"Get the issuance of a token",
+		api.ParamsToken{}, api.ResultGetIssuance{}, false},
+	"get-issuance-entry": {"Get the issuance entry of a token",
+		api.ParamsToken{}, factom.Entry{}, false},
+	"get-transaction": {"Get a transaction",
+		api.ParamsGetTransaction{}, api.ResultGetTransaction{}, false},
+	"get-transaction-entry": {"Get the entry of a transaction",
+		api.ParamsGetTransaction{}, factom.Entry{}, false},
+	"get-transaction-proof": {"Get a proof that a transaction is in the Factom blockchain",
+		api.ParamsGetTransactionProof{},
+		api.ResultGetTransactionProof{}, false},
+	"get-transactions": {"Get a page of transactions",
+		api.ParamsGetTransactions{}, []api.ResultGetTransaction{}, true},
+	"get-transactions-entry": {"Get a page of transaction entries",
+		api.ParamsGetTransactions{}, []factom.Entry{}, true},
+	"get-invalid-transactions": {"Get a page of invalid transactions",
+		api.ParamsGetInvalidTransactions{},
+		[]api.ResultGetTransaction{}, false},
+	"get-balance": {"Get the balance of an address",
+		api.ParamsGetBalance{}, uint64(0), false},
+	"get-balances": {"Get the balances of an address for all tokens",
+		api.ParamsGetBalances{}, api.ResultGetBalances{}, false},
+	"get-nf-balance": {"Get the NFTokens owned by an address",
+		api.ParamsGetNFBalance{}, fat1.NFTokens{}, true},
+	"get-stats": {"Get statistics for a token",
+		api.ParamsGetStats{}, api.ResultGetStats{}, false},
+	"get-holders": {"Get the holders of a token",
+		api.ParamsGetHolders{}, api.ResultGetHolders{}, false},
+	"get-state-hash": {"Get the state hash of a token",
+		api.ParamsGetStateHash{}, api.ResultGetStateHash{}, false},
+	"get-nf-token": {"Get an NFToken",
+		api.ParamsGetNFToken{}, api.ResultGetNFToken{}, false},
+	"get-nf-tokens": {"Get a page of NFTokens",
+		api.ParamsGetAllNFTokens{}, []api.ResultGetNFToken{}, true},
+	"get-nf-token-history": {"Get the ownership history of an NFToken",
+		api.ParamsGetNFToken{}, api.ResultGetNFTokenHistory{}, false},
+	"search-nf-tokens": {"Search the metadata of NFTokens",
+		api.ParamsSearchNFTokens{}, []api.ResultGetNFToken{}, false},
+
+	"compose-transaction": {"Compose an unsigned transaction",
+		api.ParamsComposeTransaction{},
+		api.ResultComposeTransaction{}, false},
+	"assemble-transaction": {"Assemble a signed transaction entry",
+		api.ParamsAssembleTransaction{},
+		api.ResultAssembleTransaction{}, false},
+	"send-transaction": {"Submit a transaction",
+		api.ParamsSendTransaction{}, api.ResultSendTransaction{}, false},
+	"get-submission-status": {"Get the status of a submitted transaction",
+		api.ParamsGetSubmissionStatus{},
+		api.ResultGetSubmissionStatus{}, false},
+
+	"get-daemon-tokens": {"Get the tokens tracked by fatd",
+		nil, []api.ParamsToken{}, false},
+	"search-tokens": {"Search the tokens tracked by fatd",
+		api.ParamsSearchTokens{}, []api.ParamsToken{}, false},
+	"get-daemon-properties": {"Get the properties of fatd",
+		nil, api.ResultGetDaemonProperties{}, false},
+	"get-sync-status": {"Get the sync status of fatd",
+		nil, api.ResultGetSyncStatus{}, false},
+
+	"rewind": {"Rewind a token to a past height and re-sync it",
+		api.ParamsRewind{}, true, false},
+}
+
+// paramsRules are the validation rules of params types that cannot be
+// expressed in the schema of a single param. Rules of the form
+// `required: "name"` mark the param as required. The rules are checked by
+// the IsValid method of each type, and so must match the errors it returns.
+var paramsRules = map[reflect.Type][]string{
+	reflect.TypeOf(api.ParamsToken{}): {
+		`required: either "chainid" or both "tokenid" and "issuerid"`,
+		`cannot use "chainid" with "tokenid" or "issuerid"`,
+	},
+	reflect.TypeOf(api.ParamsPagination{}): {
+		`cannot use "page" with "cursor"`,
+		`"page" may not be 0`,
+		`"order" value must be either "asc" or "desc"`,
+	},
+	reflect.TypeOf(api.ParamsHeight{}): {
+		`cannot use "height" with "timestamp"`,
+		`cannot use "includepending" with "height" or "timestamp"`,
+	},
+	reflect.TypeOf(api.ParamsGetTransaction{}): {
+		`required: "entryhash"`,
+	},
+	reflect.TypeOf(api.ParamsGetTransactionProof{}): {
+		`"includepending" is not supported`,
+		`"includeinvalid" is not supported`,
+	},
+	reflect.TypeOf(api.ParamsGetTransactions{}): {
+		`"addresses" may not be empty when "tofrom" is set`,
+		`"tofrom" value must be either "to" or "from"`,
+		`"fromheight" may not be greater than "toheight"`,
+		`"fromtimestamp" may not be greater than "totimestamp"`,
+	},
+	reflect.TypeOf(api.ParamsGetNFToken{}): {
+		`required: "nftokenid"`,
+	},
+	reflect.TypeOf(api.ParamsGetBalance{}): {
+		`required: "address"`,
+	},
+	reflect.TypeOf(api.ParamsGetBalances{}): {
+		`required: "address"`,
+	},
+	reflect.TypeOf(api.ParamsGetNFBalance{}): {
+		`required: "address"`,
+	},
+	reflect.TypeOf(api.ParamsSearchNFTokens{}): {
+		`"order" may not be used, results are ordered by relevance`,
+	},
+	reflect.TypeOf(api.ParamsSearchTokens{}): {
+		`required: "query"`,
+	},
+	reflect.TypeOf(api.ParamsSendTransaction{}): {
+		`required: "raw" or "content" and "extids"`,
+		`"raw" cannot be used with "content" or "extids"`,
+	},
+	reflect.TypeOf(api.ParamsComposeTransaction{}): {
+		`required: "inputs"`,
+		`required: "outputs"`,
+		`"includepending" is not supported`,
+	},
+	reflect.TypeOf(api.ParamsAssembleTransaction{}): {
+		`required: "content"`,
+		`required: "timestampsalt"`,
+		`required: "signatures"`,
+		`"includepending" is not supported`,
+	},
+	reflect.TypeOf(api.ParamsGetSubmissionStatus{}): {
+		`required: exactly one of "entryhash" or "txid"`,
+	},
+	reflect.TypeOf(api.ParamsRewind{}): {
+		`required: "height"`,
+		`"includepending" is not supported`,
+	},
+}
+
+// paramsSchemas express the rules of paramsRules that involve more than one
+// param as JSON Schemas of the params object. The schema of a type replaces
+// those of any structs it embeds.
+var paramsSchemas = map[reflect.Type]schema{
+	reflect.TypeOf(api.ParamsToken{}): tokenSchema,
+	reflect.TypeOf(api.ParamsSendTransaction{}): {"oneOf": []schema{{
+		"required": []string{"raw"},
+		"not": anyRequired("chainid", "tokenid", "issuerid",
+			"content", "extids"),
+	}, {
+		"allOf":    []schema{tokenSchema},
+		"required": []string{"content", "extids"},
+		"not":      anyRequired("raw"),
+	}}},
+	reflect.TypeOf(api.ParamsGetSubmissionStatus{}): {"oneOf": []schema{
+		{"required": []string{"entryhash"}},
+		{"required": []string{"txid"}},
+	}},
+}
+
+// tokenSchema requires either "chainid" or both "tokenid" and "issuerid".
+var tokenSchema = schema{"oneOf": []schema{{
+	"required": []string{"chainid"},
+	"not":      anyRequired("tokenid", "issuerid"),
+}, {
+	"required": []string{"tokenid", "issuerid"},
+	"not":      anyRequired("chainid"),
+}}}
+
+// anyRequired returns a schema that is valid if any of names are present.
+func anyRequired(names ...string) schema {
+	var anyOf []schema
+	for _, name := range names {
+		anyOf = append(anyOf, schema{"required": []string{name}})
+	}
+	return schema{"anyOf": anyOf}
+}
+
+// Schemas of types that marshal to something other than what reflection on
+// the Go type suggests.
+var (
+	bytes32Schema   = schema{"type": "string", "pattern": "^[0-9a-f]{64}$"}
+	faAddressSchema = schema{
+		"type": "string", "pattern": "^FA[1-9A-HJ-NP-Za-km-z]{50}$"}
+	nfTokensSchema = schema{
+		"type": "array",
+		"items": schema{"oneOf": []schema{
+			{"type": "integer", "minimum": 0},
+			{"type": "object",
+				"properties": schema{
+					"min": schema{"type": "integer", "minimum": 0},
+					"max": schema{"type": "integer", "minimum": 0},
+				},
+				"required": []string{"min", "max"}},
+		}},
+	}
+	knownSchemas = map[reflect.Type]schema{
+		reflect.TypeOf(factom.Bytes32{}): bytes32Schema,
+		reflect.TypeOf(factom.Bytes{}): {
+			"type": "string", "pattern": "^([0-9a-f]{2})*$"},
+		reflect.TypeOf(factom.FAAddress{}): faAddressSchema,
+		reflect.TypeOf(json.RawMessage{}):  {},
+		reflect.TypeOf(api.ResultGetBalances{}): {
+			"type":                 "object",
+			"propertyNames":        bytes32Schema,
+			"additionalProperties": schema{"type": "integer", "minimum": 0},
+		},
+		reflect.TypeOf(fat1.NFTokens{}): nfTokensSchema,
+		reflect.TypeOf(fat0.AddressAmountMap{}): {
+			"type":                 "object",
+			"propertyNames":        faAddressSchema,
+			"additionalProperties": schema{"type": "integer", "minimum": 0},
+		},
+		reflect.TypeOf(fat1.AddressNFTokensMap{}): {
+			"type":                 "object",
+			"propertyNames":        faAddressSchema,
+			"additionalProperties": nfTokensSchema,
+		},
+	}
+)
+
+type schema map[string]interface{}
+
+// schemaGenerator generates JSON Schemas from Go types. Named struct types are
+// added to Components and referenced.
+type schemaGenerator struct {
+	Components schema
+}
+
+var (
+	textMarshaler = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	jsonMarshaler = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+func (g schemaGenerator) schema(typ reflect.Type) schema {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if s, ok := knownSchemas[typ]; ok {
+		return s
+	}
+	if typ.Implements(textMarshaler) {
+		return schema{"type": "string"}
+	}
+	if typ.Implements(jsonMarshaler) {
+		return schema{}
+	}
+	switch typ.Kind() {
+	case reflect.Bool:
+		return schema{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Int64:
+		return schema{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64:
+		return schema{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return schema{"type": "number"}
+	case reflect.String:
+		return schema{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return schema{"type": "array", "items": g.schema(typ.Elem())}
+	case reflect.Map:
+		return schema{"type": "object",
+			"additionalProperties": g.schema(typ.Elem())}
+	case reflect.Struct:
+		if len(typ.Name()) == 0 {
+			return g.object(typ)
+		}
+		ref := schema{"$ref": "#/components/schemas/" + typ.Name()}
+		if _, ok := g.Components[typ.Name()]; !ok {
+			// Reserve the name first in case typ is recursive.
+			g.Components[typ.Name()] = ref
+			g.Components[typ.Name()] = g.object(typ)
+		}
+		return ref
+	}
+	return schema{}
+}
+
+func (g schemaGenerator) object(typ reflect.Type) schema {
+	properties := schema{}
+	var required []string
+	for _, f := range structFields(typ) {
+		s := g.schema(f.Type)
+		if typ == reflect.TypeOf(api.ResultGetTransaction{}) &&
+			f.Name == "data" {
+			// The "data" depends on the token standard.
+			s = g.transaction()
+		}
+		if f.OmitEmpty {
+			properties[f.Name] = s
+			continue
+		}
+		required = append(required, f.Name)
+		if nullable(f.Type) {
+			s = schema{"oneOf": []schema{s, {"type": "null"}}}
+		}
+		properties[f.Name] = s
+	}
+	s := schema{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s
+}
+
+// transaction returns the schema of the transactions of all registered token
+// standards. The schemas are not added to Components since the Go types of
+// different standards may share a name.
+func (g schemaGenerator) transaction() schema {
+	var txs []schema
+	for _, std := range standard.All() {
+		tx := g.object(reflect.TypeOf(std.Transaction()))
+		tx["title"] = std.Type().String()
+		txs = append(txs, tx)
+	}
+	return schema{"oneOf": txs}
+}
+
+// nullable returns true if a value of typ may be marshaled as null.
+func nullable(typ reflect.Type) bool {
+	switch typ.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return true
+	case reflect.Slice, reflect.Map:
+		return !typ.Implements(textMarshaler) &&
+			!typ.Implements(jsonMarshaler)
+	}
+	return false
+}
+
+type structField struct {
+	Name      string
+	Type      reflect.Type
+	OmitEmpty bool
+}
+
+// structFields returns the fields of typ, including those of embedded
+// structs, as encoding/json would marshal them.
+func structFields(typ reflect.Type) []structField {
+	var fields []structField
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		tag := strings.Split(f.Tag.Get("json"), ",")
+		if tag[0] == "-" || (len(f.PkgPath) > 0 && !f.Anonymous) {
+			continue
+		}
+		if f.Anonymous && len(tag[0]) == 0 &&
+			f.Type.Kind() == reflect.Struct {
+			fields = append(fields, structFields(f.Type)...)
+			continue
+		}
+		field := structField{Name: tag[0], Type: f.Type}
+		if len(field.Name) == 0 {
+			field.Name = f.Name
+		}
+		for _, opt := range tag[1:] {
+			if opt == "omitempty" {
+				field.OmitEmpty = true
+			}
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// rulesOf returns the paramsRules of typ and any structs it embeds.
+func rulesOf(typ reflect.Type) []string {
+	var r []string
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			r = append(r, rulesOf(f.Type)...)
+		}
+	}
+	return append(r, paramsRules[typ]...)
+}
+
+// paramsSchemasOf returns the paramsSchemas of typ, or otherwise of any
+// structs it embeds.
+func paramsSchemasOf(typ reflect.Type) []schema {
+	if s, ok := paramsSchemas[typ]; ok {
+		return []schema{s}
+	}
+	var s []schema
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			s = append(s, paramsSchemasOf(f.Type)...)
+		}
+	}
+	return s
+}
+
+// discover returns the OpenRPC document for all methods in methods.
+func discover(methods ...jsonrpc2.MethodMap) schema {
+	var names []string
+	for _, m := range methods {
+		for name := range m {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	g := schemaGenerator{Components: schema{}}
+	var docMethods []schema
+	for _, name := range names {
+		ms, ok := methodSchemas[name]
+		if !ok {
+			// A method without a methodSchema is still listed, but
+			// with unknown params and result.
+			ms.Params = map[string]interface{}{}
+			ms.Result = new(interface{})
+		}
+		params := []schema{}
+		var r []string
+		var paramsSchema schema
+		if ms.Params != nil {
+			typ := reflect.TypeOf(ms.Params)
+			required := make(map[string]bool)
+			if typ.Kind() == reflect.Struct {
+				r = rulesOf(typ)
+				for _, rule := range r {
+					if param, ok := requiredParam(rule); ok {
+						required[param] = true
+					}
+				}
+				properties := schema{}
+				for _, f := range structFields(typ) {
+					s := g.schema(f.Type)
+					properties[f.Name] = s
+					params = append(params, schema{
+						"name":     f.Name,
+						"required": required[f.Name],
+						"schema":   s,
+					})
+				}
+				paramsSchema = schema{
+					"type":       "object",
+					"properties": properties,
+				}
+				if len(required) > 0 {
+					var names []string
+					for name := range required {
+						names = append(names, name)
+					}
+					sort.Strings(names)
+					paramsSchema["required"] = names
+				}
+				if s := paramsSchemasOf(typ); len(s) > 0 {
+					paramsSchema["allOf"] = s
+				}
+			}
+		}
+		result := g.schema(reflect.TypeOf(ms.Result))
+		if ms.Paged {
+			result = schema{"oneOf": []schema{result, {
+				"type": "object",
+				"properties": schema{
+					"results":    result,
+					"nextcursor": schema{"type": "string"},
+				},
+				"required": []string{"results"},
+			}}}
+		}
+		m := schema{
+			"name":           name,
+			"paramStructure": "by-name",
+			"params":         params,
+			"result":         schema{"name": "result", "schema": result},
+		}
+		if len(ms.Summary) > 0 {
+			m["summary"] = ms.Summary
+		}
+		if len(r) > 0 {
+			m["description"] = "Params: " + strings.Join(r, "; ")
+			m["x-rules"] = r
+		}
+		if paramsSchema != nil {
+			m["x-params-schema"] = paramsSchema
+		}
+		docMethods = append(docMethods, m)
+	}
+
+	errors := schema{}
+	for _, err := range []jsonrpc2.Error{
+		api.ErrorTokenNotFound, api.ErrorTransactionNotFound,
+		api.ErrorInvalidTransaction, api.ErrorTokenSyncing,
+		api.ErrorNoEC, api.ErrorPendingDisabled,
+		api.ErrorSubscriptionNotFound, api.ErrorUnauthorized,
+		api.ErrorRateLimited, api.ErrorFactomd,
+		api.ErrorTooManySubscriptions,
+	} {
+		key := strings.ReplaceAll(err.Message, " ", "")
+		errors[key] = schema{"code": err.Code, "message": err.Message}
+	}
+
+	return schema{
+		"openrpc": "1.2.6",
+		"info": schema{
+			"title":   "fatd",
+			"version": APIVersion,
+			"description": "fatd " + flag.Revision +
+				" JSON-RPC 2.0 API. See RPC.md for details.",
+		},
+		"methods": docMethods,
+		"components": schema{
+			"schemas": g.Components,
+			"errors":  errors,
+		},
+	}
+}
+
+// requiredParam returns the name of the param if rule is of the form
+// `required: "name"`.
+func requiredParam(rule string) (string, bool) {
+	const prefix = `required: "`
+	if !strings.HasPrefix(rule, prefix) || !strings.HasSuffix(rule, `"`) {
+		return "", false
+	}
+	name := rule[len(prefix) : len(rule)-1]
+	if strings.Contains(name, `"`) {
+		return "", false
+	}
+	return name, true
+}
+
+// withDiscover wraps h so that single "rpc.discover" requests are answered
+// with doc, and all other requests are passed on to h.
+func withDiscover(doc schema, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.Body == nil {
+			h.ServeHTTP(w, r)
+			return
+		}
+		body, err := ioutil.ReadAll(r.Body)
+		r.Body.Close()
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		if err != nil {
+			h.ServeHTTP(w, r)
+			return
+		}
+		var req struct {
+			Method string          `json:"method"`
+			ID     json.RawMessage `json:"id"`
+		}
+		if json.Unmarshal(body, &req) != nil ||
+			req.Method != "rpc.discover" {
+			h.ServeHTTP(w, r)
+			return
+		}
+		if len(req.ID) == 0 {
+			req.ID = json.RawMessage("null")
+		}
+		res := struct {
+			JSONRPC string          `json:"jsonrpc"`
+			Result  schema          `json:"result"`
+			ID      json.RawMessage `json:"id"`
+		}{"2.0", doc, req.ID}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(res); err != nil {
+			log.Errorf("json.Encode(): %v", err)
+		}
+	})
+}
@@ -0,0 +1,114 @@
+// MIT License
+//
+// Copyright 2018 Canonical Ledgers, LLC
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package srv
+
+import (
+	"reflect"
+	"testing"
+
+	jsonrpc2 "github.com/AdamSLevy/jsonrpc2/v14"
+	"github.com/Factom-Asset-Tokens/fatd/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMethodSchemas(t *testing.T) {
+	for _, methods := range []jsonrpc2.MethodMap{
+		jsonrpc2Methods, sendMethods, adminMethods,
+	} {
+		for name := range methods {
+			assert.Containsf(t, methodSchemas, name,
+				"%q has no methodSchema", name)
+		}
+	}
+}
+
+func TestParamsSchemas(t *testing.T) {
+	require.ElementsMatch(t, []string{
+		"chainid", "tokenid", "issuerid",
+		"tokenid", "issuerid", "chainid",
+	}, requiredNames(tokenSchema))
+
+	doc := discover(jsonrpc2Methods, sendMethods, adminMethods)
+	for _, m := range doc["methods"].([]schema) {
+		name := m["name"].(string)
+		ms := methodSchemas[name]
+		if ms.Params == nil {
+			assert.NotContainsf(t, m, "x-params-schema", name)
+			continue
+		}
+		s, ok := m["x-params-schema"].(schema)
+		require.Truef(t, ok, "%q has no x-params-schema", name)
+		properties := s["properties"].(schema)
+		for _, f := range structFields(reflect.TypeOf(ms.Params)) {
+			assert.Containsf(t, properties, f.Name, name)
+		}
+		// Every param named by the constraints must exist.
+		for _, param := range requiredNames(s) {
+			assert.Containsf(t, properties, param,
+				"%q: unknown param", name)
+		}
+	}
+
+	get := func(name string) schema {
+		for _, m := range doc["methods"].([]schema) {
+			if m["name"] == name {
+				return m["x-params-schema"].(schema)
+			}
+		}
+		t.Fatalf("%q not found", name)
+		return nil
+	}
+	assert.Equal(t, []schema{tokenSchema}, get("get-issuance")["allOf"])
+	assert.Equal(t, []schema{tokenSchema},
+		get("get-transaction-proof")["allOf"])
+	assert.Equal(t, []string{"entryhash"},
+		get("get-transaction-proof")["required"])
+	assert.Equal(t, []schema{
+		paramsSchemas[reflect.TypeOf(api.ParamsSendTransaction{})],
+	}, get("send-transaction")["allOf"])
+	assert.NotContains(t, get("get-balances"), "allOf")
+}
+
+// requiredNames returns all names listed by "required" anywhere in s.
+func requiredNames(s interface{}) []string {
+	var names []string
+	switch s := s.(type) {
+	case schema:
+		for key, v := range s {
+			if key == "properties" {
+				continue
+			}
+			if r, ok := v.([]string); ok && key == "required" {
+				names = append(names, r...)
+				continue
+			}
+			names = append(names, requiredNames(v)...)
+		}
+	case []schema:
+		for _, s := range s {
+			names = append(names, requiredNames(s)...)
+		}
+	}
+	return names
+}
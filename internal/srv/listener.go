@@ -0,0 +1,320 @@
+// MIT License
+//
+// Copyright 2018 Canonical Ledgers, LLC
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package srv
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	jsonrpc2 "github.com/AdamSLevy/jsonrpc2/v14"
+	"github.com/Factom-Asset-Tokens/fatd/internal/flag"
+)
+
+// listener is the configuration of an address that the API is served on.
+type listener struct {
+	// Address is either "host:port" or "unix:" followed by the path of a
+	// Unix domain socket.
+	Address string `json:"address"`
+
+	// SocketMode is the octal file mode of a Unix domain socket.
+	SocketMode string `json:"socketmode,omitempty"`
+
+	TLSCertFile string `json:"tlscert,omitempty"`
+	TLSKeyFile  string `json:"tlskey,omitempty"`
+
+	// TLSClientCAFile is a CA bundle used to verify client certificates,
+	// which may be used instead of an API key.
+	TLSClientCAFile string `json:"tlsclientca,omitempty"`
+
+	// Auth requires clients to use an API key. It defaults to true if
+	// any API keys are configured.
+	Auth *bool `json:"auth,omitempty"`
+
+	// Methods is an allow list of method names, which may use the
+	// wildcards of path.Match, e.g. "get-*". All methods are allowed if it
+	// is empty. Admin methods are only allowed if Auth is true, or if
+	// listed by their exact name.
+	Methods []string `json:"methods,omitempty"`
+}
+
+const defaultSocketMode = 0660
+
+// loadListeners returns the -apilisteners file entries, or a single listener
+// for -apiaddress, -apitlscert and -apitlskey.
+func loadListeners() ([]listener, error) {
+	if len(flag.APIListenersFile) == 0 {
+		return []listener{{
+			Address:         flag.APIAddress,
+			TLSCertFile:     flag.TLSCertFile,
+			TLSKeyFile:      flag.TLSKeyFile,
+			TLSClientCAFile: flag.TLSClientCAFile,
+		}}, nil
+	}
+	data, err := ioutil.ReadFile(flag.APIListenersFile)
+	if err != nil {
+		return nil, err
+	}
+	var listeners []listener
+	if err := json.Unmarshal(data, &listeners); err != nil {
+		return nil, fmt.Errorf("%v: %w", flag.APIListenersFile, err)
+	}
+	if len(listeners) == 0 {
+		return nil, fmt.Errorf("%v: no listeners", flag.APIListenersFile)
+	}
+	for _, l := range listeners {
+		if err := l.validate(); err != nil {
+			return nil, fmt.Errorf("%v: %v: %w",
+				flag.APIListenersFile, l.Address, err)
+		}
+	}
+	return listeners, nil
+}
+
+func (l listener) validate() error {
+	if len(l.Address) == 0 {
+		return fmt.Errorf("address is required")
+	}
+	if (len(l.TLSCertFile) > 0) != (len(l.TLSKeyFile) > 0) {
+		return fmt.Errorf("tlscert and tlskey must be used together")
+	}
+	if len(l.TLSClientCAFile) > 0 {
+		if !l.HasTLS() {
+			return fmt.Errorf("tlsclientca requires tlscert and tlskey")
+		}
+		if len(flag.APIKeysFile) == 0 {
+			return fmt.Errorf("tlsclientca requires -apikeys")
+		}
+	}
+	if l.Auth != nil && *l.Auth && !flag.HasAuth {
+		return fmt.Errorf(
+			"auth requires -apikeys or -apiusername and -apipassword")
+	}
+	if len(l.SocketMode) > 0 {
+		if !l.IsUnix() {
+			return fmt.Errorf("socketmode requires a unix address")
+		}
+		if _, err := l.socketMode(); err != nil {
+			return err
+		}
+	}
+	for _, pattern := range l.Methods {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("methods: %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// IsUnix returns true if l is a Unix domain socket.
+func (l listener) IsUnix() bool {
+	return strings.HasPrefix(l.Address, "unix:")
+}
+
+func (l listener) HasTLS() bool {
+	return len(l.TLSCertFile) > 0
+}
+
+func (l listener) HasAuth() bool {
+	if l.Auth != nil {
+		return *l.Auth
+	}
+	return flag.HasAuth
+}
+
+func (l listener) socketMode() (os.FileMode, error) {
+	if len(l.SocketMode) == 0 {
+		return defaultSocketMode, nil
+	}
+	mode, err := strconv.ParseUint(l.SocketMode, 8, 32)
+	if err != nil || mode > 0777 {
+		return 0, fmt.Errorf("invalid socketmode: %q", l.SocketMode)
+	}
+	return os.FileMode(mode), nil
+}
+
+// tlsConfig returns the tls.Config that verifies any client certificates
+// using l.TLSClientCAFile, or nil if l does not use client certificates.
+// Clients without a certificate may still use an API key.
+func (l listener) tlsConfig() (*tls.Config, error) {
+	if len(l.TLSClientCAFile) == 0 {
+		return nil, nil
+	}
+	pem, err := ioutil.ReadFile(l.TLSClientCAFile)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("%v: no certificates found",
+			l.TLSClientCAFile)
+	}
+	return &tls.Config{
+		ClientCAs:  pool,
+		ClientAuth: tls.VerifyClientCertIfGiven,
+	}, nil
+}
+
+// allows returns true if name is allowed by l.Methods.
+func (l listener) allows(name string) bool {
+	if len(l.Methods) == 0 {
+		return true
+	}
+	for _, pattern := range l.Methods {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// allowsExactly returns true if name is listed in l.Methods.
+func (l listener) allowsExactly(name string) bool {
+	for _, method := range l.Methods {
+		if method == name {
+			return true
+		}
+	}
+	return false
+}
+
+// methods returns the allowed methods, wrapped with the authorization, rate
+// limits and metrics for l.
+func (l listener) methods() jsonrpc2.MethodMap {
+	methods := make(jsonrpc2.MethodMap)
+	for name, method := range jsonrpc2Methods {
+		if l.allows(name) {
+			methods[name] = method
+		}
+	}
+	for name, method := range sendMethods {
+		if l.allows(name) {
+			methods[name] = authorize(name, scopeSend, l.HasAuth(),
+				method)
+		}
+	}
+	for name, method := range adminMethods {
+		if (l.HasAuth() && l.allows(name)) || l.allowsExactly(name) {
+			methods[name] = authorize(name, scopeAdmin, l.HasAuth(),
+				method)
+		}
+	}
+	return limitAndInstrument(methods)
+}
+
+// wsMethods returns the allowed wsMethods, wrapped with the same rate limits
+// and metrics as the methods. The WebSocket endpoint is only served if any
+// are allowed.
+func (l listener) wsMethods() jsonrpc2.MethodMap {
+	methods := make(jsonrpc2.MethodMap)
+	for name, method := range wsMethods {
+		if l.allows(name) {
+			methods[name] = wsMethod(method)
+		}
+	}
+	return limitAndInstrument(methods)
+}
+
+// limitAndInstrument wraps all methods with the rate limits and metrics, if
+// enabled.
+func limitAndInstrument(methods jsonrpc2.MethodMap) jsonrpc2.MethodMap {
+	if flag.APIRateLimit > 0 || len(flag.APIMethodRateLimits) > 0 ||
+		expensive != nil {
+		for name, method := range methods {
+			methods[name] = rateLimit(name, method)
+		}
+	}
+	if flag.APIMetrics {
+		for name, method := range methods {
+			methods[name] = instrument(name, method)
+		}
+	}
+	return methods
+}
+
+// listen opens the network listener for l. Any stale Unix domain socket at
+// the address is removed first.
+func (l listener) listen() (net.Listener, error) {
+	if !l.IsUnix() {
+		return net.Listen("tcp", l.Address)
+	}
+	socket := strings.TrimPrefix(l.Address, "unix:")
+	if info, err := os.Stat(socket); err == nil &&
+		info.Mode()&os.ModeSocket != 0 {
+		if err := os.Remove(socket); err != nil {
+			return nil, err
+		}
+	}
+	mode, err := l.socketMode()
+	if err != nil {
+		return nil, err
+	}
+
+	// The file mode is the only access control on the socket, so it is
+	// created inside a directory that only we may access, and only moved
+	// into place once its mode is set.
+	dir, err := ioutil.TempDir(filepath.Dir(socket), ".fatd-socket-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	tmp := filepath.Join(dir, "socket")
+	ln, err := net.ListenUnix("unix", &net.UnixAddr{Name: tmp, Net: "unix"})
+	if err != nil {
+		return nil, err
+	}
+	ln.SetUnlinkOnClose(false)
+	if err := os.Chmod(tmp, mode); err != nil {
+		ln.Close()
+		return nil, err
+	}
+	if err := os.Rename(tmp, socket); err != nil {
+		ln.Close()
+		return nil, err
+	}
+	return unixListener{ln, socket}, nil
+}
+
+// unixListener removes the socket at path when it is closed, since the
+// net.UnixListener only knows the path it was created at.
+type unixListener struct {
+	*net.UnixListener
+	path string
+}
+
+func (ln unixListener) Close() error {
+	err := ln.UnixListener.Close()
+	if rmErr := os.Remove(ln.path); err == nil &&
+		!os.IsNotExist(rmErr) {
+		err = rmErr
+	}
+	return err
+}
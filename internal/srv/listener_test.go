@@ -0,0 +1,88 @@
+// MIT License
+//
+// Copyright 2018 Canonical Ledgers, LLC
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package srv
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Factom-Asset-Tokens/fatd/internal/flag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListenUnix(t *testing.T) {
+	require := require.New(t)
+	dir, err := ioutil.TempDir("", "fatd-listen-test-")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "fatd.sock")
+
+	l := listener{Address: "unix:" + socket, SocketMode: "600"}
+	ln, err := l.listen()
+	require.NoError(err)
+
+	info, err := os.Stat(socket)
+	require.NoError(err)
+	assert.NotZero(t, info.Mode()&os.ModeSocket)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// Only the socket may be left in dir.
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(err)
+	assert.Len(t, files, 1)
+
+	go func() {
+		if conn, err := ln.Accept(); err == nil {
+			conn.Close()
+		}
+	}()
+	conn, err := net.Dial("unix", socket)
+	require.NoError(err)
+	conn.Close()
+
+	require.NoError(ln.Close())
+	_, err = os.Stat(socket)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestListenerValidateTLSClientCA(t *testing.T) {
+	defer func(hasAuth bool, apiKeysFile string) {
+		flag.HasAuth, flag.APIKeysFile = hasAuth, apiKeysFile
+	}(flag.HasAuth, flag.APIKeysFile)
+
+	l := listener{Address: ":8078",
+		TLSCertFile:     "cert.pem",
+		TLSKeyFile:      "key.pem",
+		TLSClientCAFile: "ca.pem"}
+
+	// A username and password alone do not support client certificates.
+	flag.HasAuth, flag.APIKeysFile = true, ""
+	assert.EqualError(t, l.validate(), "tlsclientca requires -apikeys")
+
+	flag.APIKeysFile = "apikeys.json"
+	assert.NoError(t, l.validate())
+}
@@ -25,47 +25,77 @@ package srv
 import (
 	"bytes"
 	"context"
+	"crypto/sha512"
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strconv"
+	"time"
 
 	jsonrpc2 "github.com/AdamSLevy/jsonrpc2/v14"
 
 	"github.com/Factom-Asset-Tokens/factom"
 	"github.com/Factom-Asset-Tokens/factom/fat"
-	"github.com/Factom-Asset-Tokens/factom/fat0"
 	"github.com/Factom-Asset-Tokens/factom/fat1"
 	"github.com/Factom-Asset-Tokens/fatd/api"
 	"github.com/Factom-Asset-Tokens/fatd/internal/db/address"
+	"github.com/Factom-Asset-Tokens/fatd/internal/db/eblock"
 	"github.com/Factom-Asset-Tokens/fatd/internal/db/entry"
+	"github.com/Factom-Asset-Tokens/fatd/internal/db/metadata"
 	"github.com/Factom-Asset-Tokens/fatd/internal/db/nftoken"
+	"github.com/Factom-Asset-Tokens/fatd/internal/db/search"
 	"github.com/Factom-Asset-Tokens/fatd/internal/engine"
 	"github.com/Factom-Asset-Tokens/fatd/internal/flag"
+	"github.com/Factom-Asset-Tokens/fatd/internal/outbox"
+	"github.com/Factom-Asset-Tokens/fatd/internal/standard"
+	"github.com/Factom-Asset-Tokens/fatd/internal/standard/rules"
 	"github.com/Factom-Asset-Tokens/fatd/internal/state"
 )
 
 var c = flag.FactomClient
 
 var jsonrpc2Methods = jsonrpc2.MethodMap{
-	"get-issuance":           getIssuance(false),
-	"get-issuance-entry":     getIssuance(true),
-	"get-transaction":        getTransaction(false),
-	"get-transaction-entry":  getTransaction(true),
-	"get-transactions":       getTransactions(false),
-	"get-transactions-entry": getTransactions(true),
-	"get-balance":            getBalance,
-	"get-balances":           getBalances,
-	"get-nf-balance":         getNFBalance,
-	"get-stats":              getStats,
-	"get-nf-token":           getNFToken,
-	"get-nf-tokens":          getNFTokens,
+	"get-issuance":             getIssuance(false),
+	"get-issuance-entry":       getIssuance(true),
+	"get-transaction":          getTransaction(false),
+	"get-transaction-entry":    getTransaction(true),
+	"get-transaction-proof":    getTransactionProof,
+	"get-transactions":         getTransactions(false),
+	"get-transactions-entry":   getTransactions(true),
+	"get-invalid-transactions": getInvalidTransactions,
+	"get-balance":              getBalance,
+	"get-balances":             getBalances,
+	"get-nf-balance":           getNFBalance,
+	"get-stats":                getStats,
+	"get-holders":              getHolders,
+	"get-state-hash":           getStateHash,
+	"get-nf-token":             getNFToken,
+	"get-nf-tokens":            getNFTokens,
+	"get-nf-token-history":     getNFTokenHistory,
+	"search-nf-tokens":         searchNFTokens,
 
-	"send-transaction": sendTransaction,
+	"compose-transaction":   composeTransaction,
+	"assemble-transaction":  assembleTransaction,
+	"get-submission-status": getSubmissionStatus,
 
 	"get-daemon-tokens":     getDaemonTokens,
+	"search-tokens":         searchTokens,
 	"get-daemon-properties": getDaemonProperties,
 	"get-sync-status":       getSyncStatus,
 }
 
+// sendMethods spend Entry Credits, and so require an API key with the send
+// scope when fatd is configured with authentication.
+var sendMethods = jsonrpc2.MethodMap{
+	"send-transaction": sendTransaction,
+}
+
+// adminMethods are only served when fatd is configured with authentication,
+// and require an API key with the admin scope.
+var adminMethods = jsonrpc2.MethodMap{
+	"rewind": rewind,
+}
+
 func getIssuance(entry bool) jsonrpc2.MethodFunc {
 	return func(ctx context.Context, data json.RawMessage) interface{} {
 		var params api.ParamsToken
@@ -100,43 +130,154 @@ func getTransaction(getEntry bool) jsonrpc2.MethodFunc {
 		}
 		defer put()
 
-		entry, err := entry.SelectValidByHash(chain.Conn, params.Hash)
+		e, err := entry.SelectValidByHash(chain.Conn, params.Hash)
 		if err != nil {
 			panic(err)
 		}
-		if !entry.IsPopulated() {
+		var reason string
+		if !e.IsPopulated() && params.IncludeInvalid {
+			e, reason, err = entry.SelectInvalidByHash(
+				chain.Conn, params.Hash)
+			if err != nil {
+				panic(err)
+			}
+		}
+		if !e.IsPopulated() {
 			return api.ErrorTransactionNotFound
 		}
 
 		if getEntry {
-			return entry
+			return e
 		}
 
 		result := api.ResultGetTransaction{
-			Hash:      entry.Hash,
-			Timestamp: entry.Timestamp.Unix(),
-			Pending:   chain.LatestEntryTimestamp().Before(entry.Timestamp),
-		}
-
-		var tx interface{}
-		switch chain.Issuance.Type {
-		case fat0.Type:
-			tx, err = fat0.NewTransaction(entry,
-				(*factom.Bytes32)(chain.Identity.ID1Key))
-		case fat1.Type:
-			tx, err = fat1.NewTransaction(entry,
-				(*factom.Bytes32)(chain.Identity.ID1Key))
-		default:
-			panic(fmt.Sprintf("unknown FAT type: %v", chain.Issuance.Type))
+			Hash:          e.Hash,
+			Timestamp:     e.Timestamp.Unix(),
+			Pending:       chain.LatestEntryTimestamp().Before(e.Timestamp),
+			InvalidReason: reason,
 		}
+
+		// Invalid transactions may not even parse, in which case the
+		// reason says why.
+		tx, err := newTransaction(chain, e)
 		if err != nil {
-			panic(err)
+			if len(reason) == 0 {
+				panic(err)
+			}
+			tx = nil
 		}
 		result.Tx = tx
 		return result
 	}
 }
 
+// checkNFTokens returns an api.ErrorTokenNotFound unless the tokens of chain
+// are NFTokens, as required by the FAT-1 methods and params.
+func checkNFTokens(chain *state.FATChain) error {
+	if standard.MustGet(chain.Issuance.Type).NFTokens() {
+		return nil
+	}
+	err := api.ErrorTokenNotFound
+	err.Data = fmt.Sprintf("%v Token Chain has no NFTokens",
+		chain.Issuance.Type)
+	return err
+}
+
+// newTransaction parses e as a transaction for the type of chain.
+func newTransaction(chain *state.FATChain, e factom.Entry) (interface{}, error) {
+	return standard.MustGet(chain.Issuance.Type).NewTransaction(e,
+		(*factom.Bytes32)(chain.Identity.ID1Key))
+}
+
+func getInvalidTransactions(ctx context.Context, data json.RawMessage) interface{} {
+	var params api.ParamsGetInvalidTransactions
+	chain, put, err := validate(ctx, data, &params)
+	if err != nil {
+		return err
+	}
+	defer put()
+
+	entries, reasons, err := entry.SelectAllInvalid(chain.Conn,
+		params.Order, *params.Page, params.Limit)
+	if err != nil {
+		panic(err)
+	}
+	if len(entries) == 0 {
+		return api.ErrorTransactionNotFound
+	}
+
+	txs := make([]api.ResultGetTransaction, len(entries))
+	for i, e := range entries {
+		txs[i].Hash = e.Hash
+		txs[i].Timestamp = e.Timestamp.Unix()
+		txs[i].Pending = chain.LatestEntryTimestamp().Before(e.Timestamp)
+		txs[i].InvalidReason = reasons[i]
+		if tx, err := newTransaction(chain, e); err == nil {
+			txs[i].Tx = tx
+		}
+	}
+	return txs
+}
+
+func getTransactionProof(ctx context.Context, data json.RawMessage) interface{} {
+	var params api.ParamsGetTransactionProof
+	chain, put, err := validate(ctx, data, &params)
+	if err != nil {
+		return err
+	}
+	eb, dbKeyMR, err := eblock.SelectByValidEntryHash(chain.Conn, params.Hash)
+	// Don't hold the chain while we query factomd.
+	put()
+	if err != nil {
+		panic(err)
+	}
+	if !eb.IsPopulated() {
+		return api.ErrorTransactionNotFound
+	}
+
+	ebData, err := eb.MarshalBinary()
+	if err != nil {
+		panic(err)
+	}
+	entryPath, err := api.NewEntryPath(ebData, params.Hash)
+	if err != nil {
+		panic(err)
+	}
+
+	dblock := factom.DBlock{Height: eb.Height}
+	if err := dblock.Get(ctx, c); err != nil {
+		err := api.ErrorFactomd
+		err.Data = fmt.Sprintf("failed to get DBlock %v", eb.Height)
+		return err
+	}
+	if *dblock.KeyMR != dbKeyMR {
+		err := api.ErrorFactomd
+		err.Data = fmt.Sprintf(
+			"DBlock KeyMR %v does not match saved KeyMR %v",
+			dblock.KeyMR, dbKeyMR)
+		return err
+	}
+	dbData, err := dblock.MarshalBinary()
+	if err != nil {
+		panic(err)
+	}
+	ebPath, err := api.NewEBlockPath(dbData, eb.ChainID)
+	if err != nil {
+		panic(err)
+	}
+
+	return api.ResultGetTransactionProof{
+		EntryHash:    params.Hash,
+		ChainID:      eb.ChainID,
+		EBlock:       ebData,
+		EBlockKeyMR:  eb.KeyMR,
+		EntryPath:    entryPath,
+		DBlockKeyMR:  dblock.KeyMR,
+		DBlockHeight: dblock.Height,
+		EBlockPath:   ebPath,
+	}
+}
+
 func getTransactions(getEntry bool) jsonrpc2.MethodFunc {
 	return func(ctx context.Context, data json.RawMessage) interface{} {
 		var params api.ParamsGetTransactions
@@ -146,10 +287,10 @@ func getTransactions(getEntry bool) jsonrpc2.MethodFunc {
 		}
 		defer put()
 
-		if params.NFTokenID != nil && chain.Issuance.Type != fat1.Type {
-			err := api.ErrorTokenNotFound
-			err.Data = "Token Chain is not FAT-1"
-			return err
+		if params.NFTokenID != nil {
+			if err := checkNFTokens(chain); err != nil {
+				return err
+			}
 		}
 
 		// Lookup Txs
@@ -157,51 +298,60 @@ func getTransactions(getEntry bool) jsonrpc2.MethodFunc {
 		if params.NFTokenID != nil {
 			nfTkns = fat1.NFTokens{*params.NFTokenID: struct{}{}}
 		}
-		entry, err := entry.SelectByAddress(chain.Conn, params.StartHash,
-			params.Addresses, nfTkns,
-			params.ToFrom, params.Order,
-			*params.Page, params.Limit)
+		rng := entry.Range{
+			FromHeight:    params.FromHeight,
+			ToHeight:      params.ToHeight,
+			FromTimestamp: params.FromTimestamp,
+			ToTimestamp:   params.ToTimestamp,
+		}
+		entry, ids, err := entry.SelectByAddress(chain.Conn,
+			params.StartHash, params.Addresses, nfTkns,
+			params.ToFrom, rng, params.CursorID(), params.Order,
+			*params.Page, cursorLimit(&params.ParamsPagination))
 		if err != nil {
 			panic(err)
 		}
-		if len(entry) == 0 {
+		more := uint(len(ids)) > params.Limit
+		if more {
+			entry, ids = entry[:params.Limit], ids[:params.Limit]
+		}
+		if len(entry) == 0 && !params.UseCursor() {
 			return api.ErrorTransactionNotFound
 		}
+
+		var result interface{}
 		if getEntry {
 			// Omit the ChainID from the response since the client
 			// already knows it.
 			for i := range entry {
 				entry[i].ChainID = nil
 			}
-			return entry
-		}
-
-		txs := make([]api.ResultGetTransaction, len(entry))
-		for i := range txs {
-			entry := entry[i]
-			var tx interface{}
-			switch chain.Issuance.Type {
-			case fat0.Type:
-				tx, err = fat0.NewTransaction(entry,
-					(*factom.Bytes32)(chain.Identity.ID1Key))
-			case fat1.Type:
-				tx, err = fat1.NewTransaction(entry,
-					(*factom.Bytes32)(chain.Identity.ID1Key))
-			default:
-				panic(fmt.Sprintf("unknown FAT type: %v",
-					chain.Issuance.Type))
-			}
-			if err != nil {
-				panic(err)
+			result = entry
+		} else {
+			txs := make([]api.ResultGetTransaction, len(entry))
+			for i := range txs {
+				entry := entry[i]
+				tx, err := newTransaction(chain, entry)
+				if err != nil {
+					panic(err)
+				}
+				txs[i].Hash = entry.Hash
+				txs[i].Timestamp = entry.Timestamp.Unix()
+				txs[i].Pending = chain.LatestEntryTimestamp().
+					Before(entry.Timestamp)
+				txs[i].Tx = tx
 			}
-			txs[i].Hash = entry.Hash
-			txs[i].Timestamp = entry.Timestamp.Unix()
-			txs[i].Pending = chain.LatestEntryTimestamp().
-				Before(entry.Timestamp)
-			txs[i].Tx = tx
+			result = txs
 		}
-		return txs
 
+		if params.UseCursor() {
+			var lastID int64
+			if len(ids) > 0 {
+				lastID = ids[len(ids)-1]
+			}
+			return resultPage(result, more, lastID)
+		}
+		return result
 	}
 }
 
@@ -213,6 +363,19 @@ func getBalance(ctx context.Context, data json.RawMessage) interface{} {
 	}
 	defer put()
 
+	if params.IsSet() {
+		eID, err := selectMaxEntryID(chain, params.ParamsHeight)
+		if err != nil {
+			return err
+		}
+		balance, err := address.SelectBalanceAt(
+			chain.Conn, params.Address, eID)
+		if err != nil {
+			panic(err)
+		}
+		return balance
+	}
+
 	_, balance, err := address.SelectIDBalance(chain.Conn, params.Address)
 	if err != nil {
 		panic(err)
@@ -239,7 +402,19 @@ func getBalances(ctx context.Context, data json.RawMessage) interface{} {
 		if !ok {
 			panic("not a FAT chain")
 		}
-		_, balance, err := address.SelectIDBalance(fatChain.Conn, params.Address)
+		var balance uint64
+		if params.IsSet() {
+			var eID int64
+			eID, err = selectMaxEntryID(fatChain, params.ParamsHeight)
+			if err != nil {
+				return err
+			}
+			balance, err = address.SelectBalanceAt(
+				fatChain.Conn, params.Address, eID)
+		} else {
+			_, balance, err = address.SelectIDBalance(
+				fatChain.Conn, params.Address)
+		}
 		if err != nil {
 			panic(err)
 		}
@@ -258,37 +433,77 @@ func getNFBalance(ctx context.Context, data json.RawMessage) interface{} {
 	}
 	defer put()
 
-	if chain.Issuance.Type != fat1.Type {
-		err := api.ErrorTokenNotFound
-		err.Data = "Token Chain is not FAT-1"
+	if err := checkNFTokens(chain); err != nil {
 		return err
 	}
 
-	tkns, err := nftoken.SelectByOwner(chain.Conn, params.Address,
-		*params.Page, params.Limit, params.Order)
+	afterID := cursorNFTokenID(params.ParamsPagination)
+	var tkns fat1.NFTokens
+	if params.IsSet() {
+		eID, err := selectMaxEntryID(chain, params.ParamsHeight)
+		if err != nil {
+			return err
+		}
+		tkns, err = nftoken.SelectByOwnerAt(chain.Conn, params.Address,
+			eID, afterID, *params.Page,
+			cursorLimit(&params.ParamsPagination), params.Order)
+	} else {
+		tkns, err = nftoken.SelectByOwner(chain.Conn, params.Address,
+			afterID, *params.Page,
+			cursorLimit(&params.ParamsPagination), params.Order)
+	}
 	if err != nil {
 		panic(err)
 	}
+	desc := params.Order == "desc"
+	more := uint(len(tkns)) > params.Limit
+	if more {
+		delete(tkns, lastNFTokenID(tkns, desc))
+	}
 
 	// Empty fat1.NFTokens cannot be marshalled by design so substitute an
 	// empty slice.
+	var result interface{} = tkns
 	if len(tkns) == 0 {
-		return []struct{}{}
+		result = []struct{}{}
+	}
+
+	if params.UseCursor() {
+		return resultPage(result, more,
+			int64(lastNFTokenID(tkns, desc)))
 	}
+	return result
+}
 
-	return tkns
+// lastNFTokenID returns the last NFTokenID of a page of tkns, which is the
+// largest, or the smallest if desc.
+func lastNFTokenID(tkns fat1.NFTokens, desc bool) fat1.NFTokenID {
+	var lastID fat1.NFTokenID
+	first := true
+	for nfID := range tkns {
+		if first || (!desc && nfID > lastID) ||
+			(desc && nfID < lastID) {
+			lastID = nfID
+			first = false
+		}
+	}
+	return lastID
 }
 
 var coinbaseRCDHash = fat.Coinbase()
 
 func getStats(ctx context.Context, data json.RawMessage) interface{} {
-	var params api.ParamsToken
+	var params api.ParamsGetStats
 	chain, put, err := validate(ctx, data, &params)
 	if err != nil {
 		return err
 	}
 	defer put()
 
+	if params.IsSet() {
+		return getStatsAt(chain, params.ParamsHeight)
+	}
+
 	_, burned, err := address.SelectIDBalance(chain.Conn, &coinbaseRCDHash)
 	if err != nil {
 		panic(err)
@@ -322,6 +537,167 @@ func getStats(ctx context.Context, data json.RawMessage) interface{} {
 	return res
 }
 
+// getStatsAt returns the api.ResultGetStats for chain as of the DBlock
+// specified by p.
+func getStatsAt(chain *state.FATChain, p api.ParamsHeight) interface{} {
+	eID, err := selectMaxEntryID(chain, p)
+	if err != nil {
+		return err
+	}
+
+	burned, err := address.SelectBalanceAt(chain.Conn, &coinbaseRCDHash, eID)
+	if err != nil {
+		panic(err)
+	}
+	circulating, err := address.SelectCirculatingAt(chain.Conn, eID)
+	if err != nil {
+		panic(err)
+	}
+	txCount, err := entry.SelectCountAt(chain.Conn, eID)
+	if err != nil {
+		panic(err)
+	}
+	e, err := entry.SelectLatestValidAt(chain.Conn, eID)
+	if err != nil {
+		panic(err)
+	}
+	nonZeroBalances, err := address.SelectCountAt(chain.Conn, eID)
+	if err != nil {
+		panic(err)
+	}
+
+	res := api.ResultGetStats{
+		CirculatingSupply: circulating,
+		Burned:            burned,
+		Transactions:      txCount,
+		NonZeroBalances:   nonZeroBalances,
+	}
+	if e.IsPopulated() {
+		res.LastTransactionTimestamp = e.Timestamp.Unix()
+	}
+	// The Issuance is only included if it existed as of the given DBlock.
+	initID, err := metadata.SelectInitEntryID(chain.Conn)
+	if err != nil {
+		panic(err)
+	}
+	if chain.IsIssued() && initID <= eID {
+		res.Issuance = &chain.Issuance
+		res.IssuanceTimestamp = chain.Issuance.Entry.Timestamp.Unix()
+	}
+	res.ChainID = chain.ID
+	res.TokenID = chain.TokenID
+	res.IssuerChainID = chain.Identity.ChainID
+	res.IssuanceHash = chain.Issuance.Entry.Hash
+	return res
+}
+
+// selectMaxEntryID returns the largest entry row id as of the DBlock
+// specified by p. Heights beyond the chain's sync height are rejected since
+// their state is not yet known.
+func selectMaxEntryID(chain *state.FATChain, p api.ParamsHeight) (int64, error) {
+	var eID int64
+	var err error
+	if p.Height != nil {
+		if *p.Height > chain.SyncHeight {
+			return 0, jsonrpc2.ErrorInvalidParams(
+				`"height" is greater than the sync height`)
+		}
+		eID, err = entry.SelectMaxIDAtHeight(chain.Conn, *p.Height)
+	} else {
+		eID, err = entry.SelectMaxIDAtTimestamp(chain.Conn,
+			time.Unix(*p.Timestamp, 0))
+	}
+	if err != nil {
+		panic(err)
+	}
+	return eID, nil
+}
+
+func getStateHash(ctx context.Context, data json.RawMessage) interface{} {
+	var params api.ParamsGetStateHash
+	chain, put, err := validate(ctx, data, &params)
+	if err != nil {
+		return err
+	}
+	defer put()
+
+	var eb factom.EBlock
+	var hash *factom.Bytes32
+	switch {
+	case params.Height != nil:
+		if *params.Height > chain.SyncHeight {
+			return jsonrpc2.ErrorInvalidParams(
+				`"height" is greater than the sync height`)
+		}
+		eb, hash, err = eblock.SelectStateHashAtHeight(
+			chain.Conn, *params.Height)
+	case params.Timestamp != nil:
+		eb, hash, err = eblock.SelectStateHashAtTimestamp(
+			chain.Conn, time.Unix(*params.Timestamp, 0))
+	default:
+		eb, hash, err = eblock.SelectStateHashAtHeight(
+			chain.Conn, chain.SyncHeight)
+	}
+	if err != nil {
+		panic(err)
+	}
+	if !eb.IsPopulated() || hash == nil {
+		err := api.ErrorTokenNotFound
+		err.Data = "No state hash exists at the given height"
+		return err
+	}
+
+	return api.ResultGetStateHash{
+		StateHash:   hash,
+		EBlockKeyMR: eb.KeyMR,
+		Height:      eb.Height,
+		Timestamp:   eb.Timestamp.Unix(),
+	}
+}
+
+func getHolders(ctx context.Context, data json.RawMessage) interface{} {
+	var params api.ParamsGetHolders
+	chain, put, err := validate(ctx, data, &params)
+	if err != nil {
+		return err
+	}
+	defer put()
+
+	if params.NFTokenCount &&
+		!standard.MustGet(chain.Issuance.Type).NFTokens() {
+		return jsonrpc2.ErrorInvalidParams(
+			`"nftokencount" is only valid for FAT-1 tokens`)
+	}
+
+	holders, err := address.SelectHolders(chain.Conn, params.NFTokenCount,
+		params.Order, *params.Page, params.Limit)
+	if err != nil {
+		panic(err)
+	}
+	dist, err := address.SelectDistribution(chain.Conn, params.Top)
+	if err != nil {
+		panic(err)
+	}
+
+	result := api.ResultGetHolders{
+		Holders:     make([]api.Holder, len(holders)),
+		NumHolders:  dist.NumHolders,
+		Circulating: dist.Circulating,
+		Gini:        dist.Gini,
+		Top:         params.Top,
+		TopShare:    dist.TopShare,
+	}
+	for i := range holders {
+		h := &holders[i]
+		result.Holders[i] = api.Holder{
+			Address:      &h.Address,
+			Balance:      h.Balance,
+			NFTokenCount: h.NumNFTokens,
+		}
+	}
+	return result
+}
+
 func getNFToken(ctx context.Context, data json.RawMessage) interface{} {
 	var params api.ParamsGetNFToken
 	chain, put, err := validate(ctx, data, &params)
@@ -330,9 +706,7 @@ func getNFToken(ctx context.Context, data json.RawMessage) interface{} {
 	}
 	defer put()
 
-	if chain.Issuance.Type != fat1.Type {
-		err := api.ErrorTokenNotFound
-		err.Data = "Token Chain is not FAT-1"
+	if err := checkNFTokens(chain); err != nil {
 		return err
 	}
 
@@ -361,6 +735,63 @@ func getNFToken(ctx context.Context, data json.RawMessage) interface{} {
 	return res
 }
 
+func getNFTokenHistory(ctx context.Context, data json.RawMessage) interface{} {
+	var params api.ParamsGetNFToken
+	chain, put, err := validate(ctx, data, &params)
+	if err != nil {
+		return err
+	}
+	defer put()
+
+	if err := checkNFTokens(chain); err != nil {
+		return err
+	}
+
+	owner, creationHash, metadata, err := nftoken.SelectData(
+		chain.Conn, *params.NFTokenID)
+	if err != nil {
+		panic(err)
+	}
+	if creationHash.IsZero() {
+		err := api.ErrorTokenNotFound
+		err.Data = "No such NFTokenID has been issued"
+		return err
+	}
+
+	history, err := nftoken.SelectHistory(chain.Conn, *params.NFTokenID)
+	if err != nil {
+		panic(err)
+	}
+
+	res := api.ResultGetNFTokenHistory{
+		NFTokenID: *params.NFTokenID,
+		Metadata:  metadata,
+		Burned:    owner == fat.Coinbase(),
+		History:   make([]api.NFTokenTransfer, len(history)),
+	}
+	latest := chain.LatestEntryTimestamp()
+	for i := range history {
+		t := &history[i]
+		transfer := api.NFTokenTransfer{
+			Type:      api.NFTokenTransferTransfer,
+			Hash:      &t.Hash,
+			Timestamp: t.Timestamp.Unix(),
+			Pending:   latest.Before(t.Timestamp),
+			From:      &t.From,
+			To:        &t.To,
+		}
+		if t.From == fat.Coinbase() {
+			transfer.Type = api.NFTokenTransferCreation
+			transfer.From = nil
+		} else if t.To == fat.Coinbase() {
+			transfer.Type = api.NFTokenTransferBurn
+			transfer.To = nil
+		}
+		res.History[i] = transfer
+	}
+	return res
+}
+
 func getNFTokens(ctx context.Context, data json.RawMessage) interface{} {
 	var params api.ParamsGetAllNFTokens
 	chain, put, err := validate(ctx, data, &params)
@@ -369,14 +800,62 @@ func getNFTokens(ctx context.Context, data json.RawMessage) interface{} {
 	}
 	defer put()
 
-	if chain.Issuance.Type != fat1.Type {
-		err := api.ErrorTokenNotFound
-		err.Data = "Token Chain is not FAT-1"
+	if err := checkNFTokens(chain); err != nil {
 		return err
 	}
 
+	filters := make([]nftoken.MetadataFilter, len(params.Filters()))
+	for i, f := range params.Filters() {
+		filters[i] = nftoken.MetadataFilter(f)
+	}
 	tkns, owners, creationHashes, metadata, err := nftoken.SelectDataAll(
-		chain.Conn, params.Order, *params.Page, params.Limit)
+		chain.Conn, filters, cursorNFTokenID(params.ParamsPagination),
+		params.Order, *params.Page,
+		cursorLimit(&params.ParamsPagination))
+	if err != nil {
+		panic(err)
+	}
+	more := uint(len(tkns)) > params.Limit
+	if more {
+		tkns = tkns[:params.Limit]
+	}
+
+	res := make([]api.ResultGetNFToken, len(tkns))
+	for i := range res {
+		res[i].NFTokenID = tkns[i]
+		res[i].Metadata = metadata[i]
+		res[i].CreationTx = &creationHashes[i]
+		res[i].Owner = &owners[i]
+		if owners[i] == fat.Coinbase() {
+			res[i].Owner = nil
+			res[i].Burned = true
+		}
+	}
+
+	if params.UseCursor() {
+		var lastID fat1.NFTokenID
+		if len(tkns) > 0 {
+			lastID = tkns[len(tkns)-1]
+		}
+		return resultPage(res, more, int64(lastID))
+	}
+	return res
+}
+
+func searchNFTokens(ctx context.Context, data json.RawMessage) interface{} {
+	var params api.ParamsSearchNFTokens
+	chain, put, err := validate(ctx, data, &params)
+	if err != nil {
+		return err
+	}
+	defer put()
+
+	if err := checkNFTokens(chain); err != nil {
+		return err
+	}
+
+	tkns, owners, creationHashes, metadata, err := search.SelectNFTokens(
+		chain.Conn, search.Query(params.Query), *params.Page, params.Limit)
 	if err != nil {
 		panic(err)
 	}
@@ -408,13 +887,7 @@ func sendTransaction(ctx context.Context, data json.RawMessage) interface{} {
 	}
 
 	entry := params.Entry()
-	var txErr error
-	switch chain.Issuance.Type {
-	case fat0.Type:
-		txErr, err = attemptApplyFAT0Tx(chain, entry)
-	case fat1.Type:
-		txErr, err = attemptApplyFAT1Tx(chain, entry)
-	}
+	txErr, err := checkTransaction(chain, entry)
 	if err != nil {
 		panic(err)
 	}
@@ -441,58 +914,179 @@ func sendTransaction(ctx context.Context, data json.RawMessage) interface{} {
 		if err := c.Commit(ctx, commit); err != nil {
 			panic(err)
 		}
+		err = outbox.Add(chain.ID, entry.Hash, txID, params.Raw)
+		if err != nil {
+			log.Errorf("outbox.Add(): %v", err)
+		}
+		tracked := err == nil
 		if err := c.Reveal(ctx, params.Raw); err != nil {
-			panic(err)
+			if !tracked {
+				panic(err)
+			}
+			// The commit was paid for, so leave the reveal to
+			// the outbox to retry.
+			log.Warnf("factom.Client.Reveal(%v): %v", entry.Hash, err)
+		} else if err := outbox.Revealed(entry.Hash); err != nil {
+			log.Errorf("outbox.Revealed(): %v", err)
 		}
-
 	}
 
-	return struct {
-		ChainID *factom.Bytes32 `json:"chainid"`
-		TxID    *factom.Bytes32 `json:"txid,omitempty"`
-		Hash    *factom.Bytes32 `json:"entryhash"`
-	}{ChainID: chain.ID, TxID: txID, Hash: entry.Hash}
+	return api.ResultSendTransaction{
+		ChainID: chain.ID, TxID: txID, Hash: entry.Hash}
 }
-func attemptApplyFAT0Tx(chain *state.FATChain, e factom.Entry) (txErr, err error) {
-	// Validate tx
-	valid, err := entry.CheckUniquelyValid(chain.Conn, 0, e.Hash)
+
+func getSubmissionStatus(ctx context.Context, data json.RawMessage) interface{} {
+	var params api.ParamsGetSubmissionStatus
+	if _, _, err := validate(ctx, data, &params); err != nil {
+		return err
+	}
+
+	s, err := outbox.Get(params.Hash, params.TxID)
 	if err != nil {
-		return
+		panic(err)
 	}
-	if !valid {
-		txErr = fmt.Errorf("replay: hash previously marked valid")
-		return
+	if s.Hash == nil {
+		return api.ErrorTransactionNotFound
+	}
+	return api.ResultGetSubmissionStatus{
+		ChainID:       s.ChainID,
+		Hash:          s.Hash,
+		TxID:          s.TxID,
+		Status:        s.Status,
+		Valid:         s.Valid,
+		InvalidReason: s.Reason,
+		Reveals:       s.Reveals,
+		Submitted:     s.Submitted.Unix(),
+		Updated:       s.Updated.Unix(),
+	}
+}
+
+func composeTransaction(ctx context.Context, data json.RawMessage) interface{} {
+	var params api.ParamsComposeTransaction
+	chain, put, err := validate(ctx, data, &params)
+	if err != nil {
+		return err
 	}
+	defer put()
 
-	tx, txErr := fat0.NewTransaction(e, (*factom.Bytes32)(chain.Identity.ID1Key))
+	content, inputs, txErr := composeContent(chain, params)
 	if txErr != nil {
-		return
+		err := api.ErrorInvalidTransaction
+		err.Data = txErr.Error()
+		return err
 	}
 
-	if tx.IsCoinbase() {
-		addIssued := tx.Inputs[fat.Coinbase()]
-		if chain.Issuance.Supply > 0 &&
-			int64(chain.NumIssued+addIssued) > chain.Issuance.Supply {
-			txErr = fmt.Errorf("coinbase exceeds max supply")
-			return
-		}
-	} else {
-		// Check all input balances
-		for adr, amount := range tx.Inputs {
-			var bal uint64
-			if _, bal, err = address.SelectIDBalance(
-				chain.Conn, &adr); err != nil {
-				return
-			}
-			if amount > bal {
-				txErr = fmt.Errorf("insufficient balance: %v", adr)
-				return
-			}
+	// The signatures are only valid within 12 hours of the timestamp salt,
+	// so use the current time to give offline signers as much time as
+	// possible.
+	salt := strconv.FormatInt(time.Now().Unix(), 10)
+
+	result := api.ResultComposeTransaction{
+		ChainID:       chain.ID,
+		Content:       content,
+		TimestampSalt: salt,
+		SigningHashes: make([]api.SigningHash, len(inputs)),
+	}
+	for i, adr := range inputs {
+		sh := &result.SigningHashes[i]
+		sh.RCDSigID = i
+		sh.MsgHash = signingHash(i, []byte(salt), chain.ID, content)
+		if adr == fat.Coinbase() {
+			sh.RCDHash = (*factom.Bytes32)(chain.Identity.ID1Key)
+			continue
 		}
+		adr := adr
+		sh.Address = &adr
+		sh.RCDHash = (*factom.Bytes32)(&adr)
+	}
+	return result
+}
+
+// composeContent returns the canonical JSON content of the transaction
+// described by params for the type of chain, along with the input addresses
+// sorted in the order that they must be signed.
+func composeContent(chain *state.FATChain, params api.ParamsComposeTransaction) (
+	content []byte, inputs []factom.FAAddress, txErr error) {
+
+	std := standard.MustGet(chain.Issuance.Type)
+	tx, txErr := std.Compose(params.Inputs, params.Outputs,
+		params.TokenMetadata, params.Metadata)
+	if txErr != nil {
+		return nil, nil, txErr
+	}
+	inputs, _ = std.Addresses(tx)
+
+	content, err := json.Marshal(tx)
+	if err != nil {
+		return nil, nil, err
+	}
+	sort.Slice(inputs, func(i, j int) bool {
+		return bytes.Compare(inputs[i][:], inputs[j][:]) < 0
+	})
+	return content, inputs, nil
+}
+
+// signingHash returns the FAT-103 message hash that must be signed for the
+// RCD/Sig ID rcdSigID: sha512(RCD/Sig ID Salt + Timestamp Salt + Chain ID +
+// Content).
+func signingHash(rcdSigID int, salt []byte,
+	chainID *factom.Bytes32, content []byte) factom.Bytes {
+	rcdSigIDSalt := strconv.Itoa(rcdSigID)
+	msg := make([]byte, 0,
+		len(rcdSigIDSalt)+len(salt)+len(chainID)+len(content))
+	msg = append(msg, rcdSigIDSalt...)
+	msg = append(msg, salt...)
+	msg = append(msg, chainID[:]...)
+	msg = append(msg, content...)
+	hash := sha512.Sum512(msg)
+	return hash[:]
+}
+
+func assembleTransaction(ctx context.Context, data json.RawMessage) interface{} {
+	var params api.ParamsAssembleTransaction
+	chain, put, err := validate(ctx, data, &params)
+	if err != nil {
+		return err
+	}
+	defer put()
+
+	e := factom.Entry{
+		ChainID:   chain.ID,
+		Content:   params.Content,
+		Timestamp: time.Now(),
+		ExtIDs:    make([]factom.Bytes, 1, len(params.Signatures)*2+1),
+	}
+	e.ExtIDs[0] = factom.Bytes(params.TimestampSalt)
+	for _, sig := range params.Signatures {
+		e.ExtIDs = append(e.ExtIDs, sig.RCD, sig.Signature)
+	}
+
+	// Fully validate the signatures so that a bad transaction is caught
+	// before any EC are spent on it.
+	if _, txErr := newTransaction(chain, e); txErr != nil {
+		err := api.ErrorInvalidTransaction
+		err.Data = txErr.Error()
+		return err
+	}
+
+	raw, err := e.MarshalBinary()
+	if err != nil {
+		return jsonrpc2.ErrorInvalidParams(err)
+	}
+	hash := factom.ComputeEntryHash(raw)
+
+	return api.ResultAssembleTransaction{
+		ChainID: chain.ID,
+		Hash:    &hash,
+		ExtIDs:  e.ExtIDs,
+		Content: e.Content,
+		Raw:     raw,
 	}
-	return
 }
-func attemptApplyFAT1Tx(chain *state.FATChain, e factom.Entry) (txErr, err error) {
+
+// checkTransaction returns a txErr if e is not a valid transaction that can
+// be applied to the current state of chain.
+func checkTransaction(chain *state.FATChain, e factom.Entry) (txErr, err error) {
 	// Validate tx
 	valid, err := entry.CheckUniquelyValid(chain.Conn, 0, e.Hash)
 	if err != nil {
@@ -503,63 +1097,18 @@ func attemptApplyFAT1Tx(chain *state.FATChain, e factom.Entry) (txErr, err error
 		return
 	}
 
-	tx, txErr := fat1.NewTransaction(e, (*factom.Bytes32)(chain.Identity.ID1Key))
+	std := rules.MustGet(chain.Issuance.Type)
+	tx, txErr := std.NewTransaction(e,
+		(*factom.Bytes32)(chain.Identity.ID1Key))
 	if txErr != nil {
 		return
 	}
 
-	if tx.IsCoinbase() {
-		nfTkns := tx.Inputs[fat.Coinbase()]
-		addIssued := uint64(len(nfTkns))
-		if chain.Issuance.Supply > 0 &&
-			int64(chain.NumIssued+addIssued) > chain.Issuance.Supply {
-			txErr = fmt.Errorf("coinbase exceeds max supply")
-			return
-		}
-		for nfID := range nfTkns {
-			var ownerID int64
-			ownerID, err = nftoken.SelectOwnerID(chain.Conn, nfID)
-			if err != nil {
-				return
-			}
-			if ownerID != -1 {
-				txErr = fmt.Errorf("NFTokenID{%v} already exists", nfID)
-				return
-			}
-		}
-	} else {
-		for adr, nfTkns := range tx.Inputs {
-			var adrID int64
-			var bal uint64
-			adrID, bal, err = address.SelectIDBalance(chain.Conn, &adr)
-			if err != nil {
-				return
-			}
-			amount := uint64(len(nfTkns))
-			if amount > bal {
-				txErr = fmt.Errorf("insufficient balance: %v", adr)
-				return
-			}
-			for nfTkn := range nfTkns {
-				var ownerID int64
-				ownerID, err = nftoken.SelectOwnerID(
-					chain.Conn, nfTkn)
-				if err != nil {
-					return
-				}
-				if ownerID == -1 {
-					txErr = fmt.Errorf("no such NFToken{%v}", nfTkn)
-					return
-				}
-				if ownerID != adrID {
-					txErr = fmt.Errorf("NFToken{%v} not owned by %v",
-						nfTkn, adr)
-					return
-				}
-			}
-		}
-	}
-	return
+	return std.Check(rules.Chain{
+		Conn:      chain.Conn,
+		Issuance:  chain.Issuance,
+		NumIssued: chain.NumIssued,
+	}, tx)
 }
 func getDaemonTokens(ctx context.Context, data json.RawMessage) interface{} {
 	if _, _, err := validate(ctx, data, nil); err != nil {
@@ -591,6 +1140,54 @@ func getDaemonTokens(ctx context.Context, data json.RawMessage) interface{} {
 	return chains
 }
 
+func searchTokens(ctx context.Context, data json.RawMessage) interface{} {
+	var params api.ParamsSearchTokens
+	if _, _, err := validate(ctx, data, &params); err != nil {
+		return err
+	}
+	query := search.Query(params.Query)
+
+	type match struct {
+		api.ParamsToken
+		rank float64
+	}
+	var matches []match
+	for _, chainID := range engine.IssuedIDs() {
+		chain, put, err := engine.Get(ctx, chainID, params.IncludePending)
+		if err != nil {
+			// ctx is done
+			return err
+		}
+		defer put()
+		fatChain, ok := state.ToFATChain(chain)
+		if !ok {
+			panic("not a FAT chain")
+		}
+		rank, ok, err := search.SelectIssuanceRank(fatChain.Conn, query)
+		if err != nil {
+			panic(err)
+		}
+		if !ok {
+			continue
+		}
+		matches = append(matches, match{api.ParamsToken{
+			ChainID:       chainID,
+			TokenID:       fatChain.TokenID,
+			IssuerChainID: fatChain.Identity.ChainID,
+		}, rank})
+	}
+
+	// Lower ranks are better matches.
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].rank < matches[j].rank
+	})
+	chains := make([]api.ParamsToken, len(matches))
+	for i := range matches {
+		chains[i] = matches[i].ParamsToken
+	}
+	return chains
+}
+
 func getDaemonProperties(ctx context.Context, data json.RawMessage) interface{} {
 	if _, _, err := validate(ctx, data, nil); err != nil {
 		return err
@@ -607,6 +1204,64 @@ func getSyncStatus(ctx context.Context, data json.RawMessage) interface{} {
 	return api.ResultGetSyncStatus{Sync: sync, Current: current}
 }
 
+func rewind(ctx context.Context, data json.RawMessage) interface{} {
+	var params api.ParamsRewind
+	chain, put, err := validate(ctx, data, &params)
+	if err != nil {
+		return err
+	}
+	chainID, syncHeight := chain.ID, chain.SyncHeight
+	// The rewind takes the chain lock itself, so we must not hold it.
+	put()
+
+	if *params.Height >= syncHeight {
+		return jsonrpc2.ErrorInvalidParams(fmt.Sprintf(
+			`"height" must be less than the sync height %v`,
+			syncHeight))
+	}
+
+	if err := engine.Rewind(ctx, chainID, *params.Height); err != nil {
+		log.Errorf("engine.Rewind(): %v", err)
+		return jsonrpc2.NewError(jsonrpc2.ErrorCodeInternal,
+			jsonrpc2.ErrorMessageInternal, "rewind failed")
+	}
+	return true
+}
+
+// cursorLimit returns the number of results to select for a page. When a
+// Cursor is used, one more than the Limit is selected so that a full final
+// page can be told apart from one that is followed by more results. The Limit
+// is lowered if needed so that the extra result is within the -apimaxlimit.
+func cursorLimit(params *api.ParamsPagination) uint {
+	if !params.UseCursor() {
+		return params.Limit
+	}
+	if max := uint(flag.APIMaxLimit); max > 1 && params.Limit >= max {
+		params.Limit = max - 1
+	}
+	return params.Limit + 1
+}
+
+// resultPage returns the results of a method that supports a cursor in an
+// api.ResultPage. The NextCursor follows lastID, if there are more results.
+func resultPage(results interface{}, more bool, lastID int64) api.ResultPage {
+	res := api.ResultPage{Results: results}
+	if more {
+		res.NextCursor = api.NewCursor(lastID)
+	}
+	return res
+}
+
+// cursorNFTokenID returns the NFTokenID of the cursor, if any.
+func cursorNFTokenID(params api.ParamsPagination) *fat1.NFTokenID {
+	id := params.CursorID()
+	if id == nil {
+		return nil
+	}
+	nfID := fat1.NFTokenID(*id)
+	return &nfID
+}
+
 func validate(ctx context.Context,
 	data json.RawMessage, params api.Params) (*state.FATChain, func(), error) {
 	if params == nil {
@@ -0,0 +1,415 @@
+// MIT License
+//
+// Copyright 2018 Canonical Ledgers, LLC
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package srv
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"sort"
+	"testing"
+
+	jsonrpc2 "github.com/AdamSLevy/jsonrpc2/v14"
+	"github.com/Factom-Asset-Tokens/factom"
+	"github.com/Factom-Asset-Tokens/factom/fat1"
+	"github.com/Factom-Asset-Tokens/fatd/api"
+	"github.com/Factom-Asset-Tokens/fatd/internal/db"
+	"github.com/Factom-Asset-Tokens/fatd/internal/engine"
+	_log "github.com/Factom-Asset-Tokens/fatd/internal/log"
+	"github.com/Factom-Asset-Tokens/fatd/internal/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	fat0ChainID = factom.NewBytes32(
+		"b54c4310530dc4dd361101644fa55cb10aec561e7874a7b786ea3b66f2c6fdfb")
+	fat1ChainID = factom.NewBytes32(
+		"0692c0f9c3171575cf53d6d8067139bad3f56169f94966341018b1950542f3dd")
+)
+
+// testState serves the chains in a copy of the state package's test
+// database, without running the engine.
+type testState struct {
+	engine.State
+	chains map[factom.Bytes32]*state.FATChain
+	ids    []*factom.Bytes32
+}
+
+func (s testState) Get(_ context.Context, id *factom.Bytes32, _ bool) (
+	state.Chain, func(), error) {
+	chain, ok := s.chains[*id]
+	if !ok {
+		return nil, nil, nil
+	}
+	return chain, func() {}, nil
+}
+func (s testState) IssuedIDs() []*factom.Bytes32  { return s.ids }
+func (s testState) TrackedIDs() []*factom.Bytes32 { return s.ids }
+
+// openTestState opens a copy of the test database and sets it as the engine
+// State. The returned func closes the chains and removes the copy.
+func openTestState(t *testing.T) func() {
+	require := require.New(t)
+	log = _log.New("pkg", "srv")
+
+	dir, err := ioutil.TempDir("", "fatd-srv-test-")
+	require.NoError(err)
+	dbPath := dir + "/test-fatd.db/"
+	require.NoError(exec.Command("cp", "-r",
+		"../state/test-fatd.db", dbPath).Run())
+
+	chains, err := db.OpenAllFATChains(context.Background(), dbPath)
+	require.NoError(err)
+	s := testState{chains: make(map[factom.Bytes32]*state.FATChain)}
+	for i := range chains {
+		chain := state.FATChain(chains[i])
+		s.chains[*chain.ID] = &chain
+		s.ids = append(s.ids, chain.ID)
+	}
+	engine.SetState(s)
+
+	return func() {
+		for _, chain := range s.chains {
+			chain.Close()
+		}
+		os.RemoveAll(dir)
+	}
+}
+
+// call calls method with params and unmarshals the result into result, or
+// returns any jsonrpc2.Error.
+func call(t *testing.T, method jsonrpc2.MethodFunc,
+	params interface{}, result interface{}) error {
+	data, err := json.Marshal(params)
+	require.NoError(t, err)
+	ret := method(context.Background(), data)
+	if err, ok := ret.(jsonrpc2.Error); ok {
+		return err
+	}
+	data, err = json.Marshal(ret)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, result))
+	return nil
+}
+
+func TestGetBalancesAtHeight(t *testing.T) {
+	defer openTestState(t)()
+	adr := factom.FAAddress(factom.NewBytes32(
+		"6689af8ca36a701bb67e0906272267b05842dc8e7e160db1dd5a64b8ecbb2538"))
+
+	for _, test := range []struct {
+		Height uint32
+		Exp    api.ResultGetBalances
+	}{{
+		Height: 172943,
+		Exp:    api.ResultGetBalances{},
+	}, {
+		Height: 173260,
+		Exp:    api.ResultGetBalances{fat0ChainID: 5000},
+	}, {
+		Height: 173370,
+		Exp:    api.ResultGetBalances{fat0ChainID: 3697},
+	}, {
+		Height: 213493,
+		Exp: api.ResultGetBalances{
+			fat0ChainID: 3269,
+			fat1ChainID: 960,
+		},
+	}} {
+		height := test.Height
+		params := api.ParamsGetBalances{
+			Address:      &adr,
+			ParamsHeight: api.ParamsHeight{Height: &height},
+		}
+		var balances api.ResultGetBalances
+		require.NoError(t, call(t, getBalances, params, &balances))
+		assert.Equalf(t, test.Exp, balances, "height: %v", height)
+
+		// Each balance must match get-balance for the same height.
+		for chainID, balance := range balances {
+			chainID := chainID
+			params := api.ParamsGetBalance{
+				ParamsToken:  api.ParamsToken{ChainID: &chainID},
+				Address:      &adr,
+				ParamsHeight: api.ParamsHeight{Height: &height},
+			}
+			var exp uint64
+			require.NoError(t, call(t, getBalance, params, &exp))
+			assert.Equalf(t, exp, balance, "height: %v", height)
+		}
+	}
+
+	var balances api.ResultGetBalances
+	require.NoError(t, call(t, getBalances,
+		api.ParamsGetBalances{Address: &adr}, &balances))
+	assert.Equal(t, api.ResultGetBalances{
+		fat0ChainID: 3249,
+		fat1ChainID: 960,
+	}, balances, "current")
+}
+
+func TestGetTransactionsByAddressAndNFToken(t *testing.T) {
+	defer openTestState(t)()
+	adr := func(s string) factom.FAAddress {
+		return factom.FAAddress(factom.NewBytes32(s))
+	}
+	issuer := adr("031cce24bcc43b596af105167de2c03603c20ada3314a7cfb47befcad4883e6f")
+	holder := adr("6a0c800d303c0260dd998e6609f89909974d9576ec72d070f9ec7aa493e84884")
+	other := adr("33eaaf5c0689ecf7bed3028f34674697b916df94b7dd7b84db4ff72ddf4d0582")
+	nfID := fat1.NFTokenID(70)
+
+	for _, test := range []struct {
+		Name      string
+		NFTokenID *fat1.NFTokenID
+		Addresses []factom.FAAddress
+		ToFrom    string
+		Exp       []string
+	}{{
+		Name:      "addresses",
+		Addresses: []factom.FAAddress{other},
+		Exp: []string{
+			"4c90cffb6363b4ca74d60925fd8a06c9f7cf9ec84f8994d41dbb97f8666765fa",
+		},
+	}, {
+		Name:      "nftokenid",
+		NFTokenID: &nfID,
+		Exp: []string{
+			"4725918eac2b5e322c2db652b6d9f8bb6bf7c3ee214fc349a57b60b8eb4fc17a",
+			"3264784587ea1c365efeae8669466a151cd1225af203240bc28401acc9380dbf",
+			"fde58143c55a080ce6fa65cc500cd4dd569e449b3c8018618b68f81aba7eb778",
+			"035db3f43dde5316f45e2ad3dda4969fb270cc5bd7af757d691f746a0d964b67",
+			"adb9b72739642c09a7802fe875de83d5d965bd63bdda12343222263001150d37",
+			"56e25bd944903f92ae6374b82d9c965547b3f2765fa22da9c41b2fc578d41480",
+		},
+	}, {
+		Name:      "nftokenid and addresses",
+		NFTokenID: &nfID,
+		Addresses: []factom.FAAddress{issuer},
+		Exp: []string{
+			"4725918eac2b5e322c2db652b6d9f8bb6bf7c3ee214fc349a57b60b8eb4fc17a",
+		},
+	}, {
+		Name:      "nftokenid and addresses to",
+		NFTokenID: &nfID,
+		Addresses: []factom.FAAddress{holder},
+		ToFrom:    "to",
+		Exp: []string{
+			"3264784587ea1c365efeae8669466a151cd1225af203240bc28401acc9380dbf",
+			"035db3f43dde5316f45e2ad3dda4969fb270cc5bd7af757d691f746a0d964b67",
+			"56e25bd944903f92ae6374b82d9c965547b3f2765fa22da9c41b2fc578d41480",
+		},
+	}, {
+		Name:      "nftokenid and unrelated addresses",
+		NFTokenID: &nfID,
+		Addresses: []factom.FAAddress{other},
+	}} {
+		t.Run(test.Name, func(t *testing.T) {
+			params := api.ParamsGetTransactions{
+				ParamsToken: api.ParamsToken{ChainID: &fat1ChainID},
+				NFTokenID:   test.NFTokenID,
+				Addresses:   test.Addresses,
+				ToFrom:      test.ToFrom,
+			}
+			var txs []api.ResultGetTransaction
+			err := call(t, getTransactions(false), params, &txs)
+			if len(test.Exp) == 0 {
+				assert.Equal(t, api.ErrorTransactionNotFound, err)
+				return
+			}
+			require.NoError(t, err)
+			hashes := make([]string, len(txs))
+			for i, tx := range txs {
+				hashes[i] = tx.Hash.String()
+			}
+			assert.Equal(t, test.Exp, hashes)
+		})
+	}
+}
+
+func TestCursorPagination(t *testing.T) {
+	defer openTestState(t)()
+	nfID := fat1.NFTokenID(70)
+
+	// pages calls method with params for each page using a cursor, until
+	// no nextcursor is returned, and returns the results of each page.
+	pages := func(t *testing.T, method jsonrpc2.MethodFunc,
+		params interface{}, pagination *api.ParamsPagination,
+		results func(json.RawMessage) int) []int {
+		var sizes []int
+		cursor := ""
+		for {
+			pagination.Cursor = &cursor
+			var page struct {
+				Results    json.RawMessage `json:"results"`
+				NextCursor string          `json:"nextcursor"`
+			}
+			require.NoError(t, call(t, method, params, &page))
+			sizes = append(sizes, results(page.Results))
+			if page.NextCursor == "" {
+				return sizes
+			}
+			cursor = page.NextCursor
+			require.Lessf(t, len(sizes), 1000, "too many pages")
+		}
+	}
+
+	for _, order := range []string{"asc", "desc"} {
+		order := order
+		t.Run("get-transactions "+order, func(t *testing.T) {
+			var all []api.ResultGetTransaction
+			require.NoError(t, call(t, getTransactions(false),
+				api.ParamsGetTransactions{
+					ParamsToken: api.ParamsToken{
+						ChainID: &fat1ChainID},
+					ParamsPagination: api.ParamsPagination{
+						Order: order},
+					NFTokenID: &nfID,
+				}, &all))
+			require.Len(t, all, 6)
+
+			for _, limit := range []uint{1, 3, 4, 6, 7} {
+				var got []api.ResultGetTransaction
+				params := api.ParamsGetTransactions{
+					ParamsToken: api.ParamsToken{
+						ChainID: &fat1ChainID},
+					ParamsPagination: api.ParamsPagination{
+						Order: order, Limit: limit},
+					NFTokenID: &nfID,
+				}
+				sizes := pages(t, getTransactions(false), &params,
+					&params.ParamsPagination,
+					func(data json.RawMessage) int {
+						var txs []api.ResultGetTransaction
+						require.NoError(t,
+							json.Unmarshal(data, &txs))
+						got = append(got, txs...)
+						return len(txs)
+					})
+				assert.Equalf(t, all, got, "limit: %v", limit)
+				assertPageSizes(t, sizes, len(all), limit)
+			}
+		})
+
+		t.Run("get-nf-tokens "+order, func(t *testing.T) {
+			var all []api.ResultGetNFToken
+			require.NoError(t, call(t, getNFTokens,
+				api.ParamsGetAllNFTokens{
+					ParamsToken: api.ParamsToken{
+						ChainID: &fat1ChainID},
+					ParamsPagination: api.ParamsPagination{
+						Order: order, Limit: 2000},
+				}, &all))
+			require.Len(t, all, 1001)
+
+			for _, limit := range []uint{7, 100, 143, 1001} {
+				var got []api.ResultGetNFToken
+				params := api.ParamsGetAllNFTokens{
+					ParamsToken: api.ParamsToken{
+						ChainID: &fat1ChainID},
+					ParamsPagination: api.ParamsPagination{
+						Order: order, Limit: limit},
+				}
+				sizes := pages(t, getNFTokens, &params,
+					&params.ParamsPagination,
+					func(data json.RawMessage) int {
+						var tkns []api.ResultGetNFToken
+						require.NoError(t,
+							json.Unmarshal(data, &tkns))
+						got = append(got, tkns...)
+						return len(tkns)
+					})
+				assert.Equalf(t, all, got, "limit: %v", limit)
+				assertPageSizes(t, sizes, len(all), limit)
+			}
+		})
+
+		t.Run("get-nf-balance "+order, func(t *testing.T) {
+			adr := factom.FAAddress(factom.NewBytes32(
+				"6a0c800d303c0260dd998e6609f89909974d9576ec72d070f9ec7aa493e84884"))
+			var all fat1.NFTokens
+			require.NoError(t, call(t, getNFBalance,
+				api.ParamsGetNFBalance{
+					ParamsToken: api.ParamsToken{
+						ChainID: &fat1ChainID},
+					ParamsPagination: api.ParamsPagination{
+						Limit: 100},
+					Address: &adr,
+				}, &all))
+			require.Len(t, all, 36)
+
+			for _, limit := range []uint{1, 5, 12, 36, 40} {
+				got := make(fat1.NFTokens)
+				var ids []fat1.NFTokenID
+				params := api.ParamsGetNFBalance{
+					ParamsToken: api.ParamsToken{
+						ChainID: &fat1ChainID},
+					ParamsPagination: api.ParamsPagination{
+						Order: order, Limit: limit},
+					Address: &adr,
+				}
+				sizes := pages(t, getNFBalance, &params,
+					&params.ParamsPagination,
+					func(data json.RawMessage) int {
+						var tkns fat1.NFTokens
+						require.NoError(t,
+							json.Unmarshal(data, &tkns))
+						// Sort each page, so that all
+						// pages together must be sorted.
+						page := make([]fat1.NFTokenID, 0,
+							len(tkns))
+						for nfID := range tkns {
+							got[nfID] = struct{}{}
+							page = append(page, nfID)
+						}
+						sort.Slice(page, func(i, j int) bool {
+							return (page[i] < page[j]) ==
+								(order == "asc")
+						})
+						ids = append(ids, page...)
+						return len(tkns)
+					})
+				assert.True(t, sort.SliceIsSorted(ids,
+					func(i, j int) bool {
+						return (ids[i] < ids[j]) ==
+							(order == "asc")
+					}), "limit: %v", limit)
+				assert.Equalf(t, all, got, "limit: %v", limit)
+				assertPageSizes(t, sizes, len(all), limit)
+			}
+		})
+	}
+}
+
+// assertPageSizes asserts that n results were split into full pages of limit
+// results, followed by a final non-empty page.
+func assertPageSizes(t *testing.T, sizes []int, n int, limit uint) {
+	exp := make([]int, (n+int(limit)-1)/int(limit))
+	for i := range exp {
+		exp[i] = int(limit)
+	}
+	exp[len(exp)-1] = n - (len(exp)-1)*int(limit)
+	assert.Equalf(t, exp, sizes, "limit: %v", limit)
+}
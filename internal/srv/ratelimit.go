@@ -0,0 +1,166 @@
+// MIT License
+//
+// Copyright 2018 Canonical Ledgers, LLC
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package srv
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"sync"
+	"time"
+
+	jsonrpc2 "github.com/AdamSLevy/jsonrpc2/v14"
+	"github.com/Factom-Asset-Tokens/fatd/api"
+	"github.com/Factom-Asset-Tokens/fatd/internal/flag"
+	"golang.org/x/time/rate"
+)
+
+// expensiveMethods may scan large parts of a chain database, and so are
+// limited to -apimaxexpensive concurrent calls.
+var expensiveMethods = map[string]bool{
+	"get-transactions":         true,
+	"get-transactions-entry":   true,
+	"get-invalid-transactions": true,
+	"get-balances":             true,
+	"get-nf-balance":           true,
+	"get-holders":              true,
+	"get-nf-tokens":            true,
+	"search-nf-tokens":         true,
+	"search-tokens":            true,
+}
+
+// limiters holds a rate.Limiter for each client, or client and method.
+type limiters struct {
+	sync.Mutex
+	m map[string]*limiter
+}
+
+type limiter struct {
+	*rate.Limiter
+	lastSeen time.Time
+}
+
+// reserve takes a token from the limiter for key, creating it if needed, and
+// returns zero. If no token is available, no token is taken and the time until
+// one is available is returned.
+func (l *limiters) reserve(key string, r uint64) time.Duration {
+	burst := int(flag.APIRateBurst)
+	if burst < 1 {
+		burst = 1
+	}
+	l.Lock()
+	lim, ok := l.m[key]
+	if !ok {
+		lim = &limiter{Limiter: rate.NewLimiter(rate.Limit(r), burst)}
+		l.m[key] = lim
+	}
+	lim.lastSeen = time.Now()
+	l.Unlock()
+
+	res := lim.Reserve()
+	if delay := res.Delay(); delay > 0 {
+		res.Cancel()
+		return delay
+	}
+	return 0
+}
+
+// sweep removes all limiters that have not been used for idle.
+func (l *limiters) sweep(idle time.Duration) {
+	l.Lock()
+	defer l.Unlock()
+	for key, lim := range l.m {
+		if time.Since(lim.lastSeen) > idle {
+			delete(l.m, key)
+		}
+	}
+}
+
+var (
+	clientLimiters = limiters{m: make(map[string]*limiter)}
+	methodLimiters = limiters{m: make(map[string]*limiter)}
+
+	// expensive is a semaphore for expensiveMethods. It is nil if
+	// -apimaxexpensive is 0.
+	expensive chan struct{}
+)
+
+// sweepLimiters periodically removes idle limiters until ctx is done.
+func sweepLimiters(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			clientLimiters.sweep(10 * time.Minute)
+			methodLimiters.sweep(10 * time.Minute)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func errorRateLimited(retryAfter time.Duration) jsonrpc2.Error {
+	err := api.ErrorRateLimited
+	err.Data = struct {
+		RetryAfter int64 `json:"retryafter"`
+	}{int64(math.Ceil(retryAfter.Seconds()))}
+	return err
+}
+
+// rateLimit wraps method so that each client, identified by its API key or
+// IP address, is limited to -apiratelimit requests per second across all
+// methods, and any -apimethodratelimits for name. Calls to expensiveMethods
+// wait for one of the -apimaxexpensive slots, for up to -apitimeout.
+func rateLimit(name string, method jsonrpc2.MethodFunc) jsonrpc2.MethodFunc {
+	methodRate, hasMethodRate := flag.APIMethodRateLimits[name]
+	isExpensive := expensiveMethods[name] && expensive != nil
+	return func(ctx context.Context, params json.RawMessage) interface{} {
+		client := getCaller(ctx).id()
+		if flag.APIRateLimit > 0 {
+			if delay := clientLimiters.reserve(client,
+				flag.APIRateLimit); delay > 0 {
+				return errorRateLimited(delay)
+			}
+		}
+		if hasMethodRate {
+			if delay := methodLimiters.reserve(client+" "+name,
+				methodRate); delay > 0 {
+				return errorRateLimited(delay)
+			}
+		}
+		if isExpensive {
+			timer := time.NewTimer(flag.APITimeout)
+			defer timer.Stop()
+			select {
+			case expensive <- struct{}{}:
+				defer func() { <-expensive }()
+			case <-timer.C:
+				return errorRateLimited(time.Second)
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		return method(ctx, params)
+	}
+}
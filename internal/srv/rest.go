@@ -0,0 +1,444 @@
+// MIT License
+//
+// Copyright 2018 Canonical Ledgers, LLC
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package srv
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+
+	jsonrpc2 "github.com/AdamSLevy/jsonrpc2/v14"
+	"github.com/Factom-Asset-Tokens/fatd/api"
+	"github.com/Factom-Asset-Tokens/fatd/internal/engine"
+	"github.com/Factom-Asset-Tokens/fatd/internal/flag"
+)
+
+// restRoute maps a resource style URL onto a JSON RPC method. Path segments
+// of the form "{name}" and any query parameters are passed to the method as
+// the params of the same name.
+type restRoute struct {
+	Method  string
+	Pattern string
+	RPC     string
+	// Params is the type of the params of RPC, which is used to convert
+	// path and query parameters to JSON.
+	Params interface{}
+}
+
+// restRoutes are matched in order, so literal path segments must come before
+// any "{name}" segment they overlap with.
+var restRoutes = []restRoute{
+	{"GET", "/v1/daemon/properties", "get-daemon-properties", nil},
+	{"GET", "/v1/daemon/sync", "get-sync-status", nil},
+	{"GET", "/v1/daemon/tokens", "get-daemon-tokens", nil},
+	{"GET", "/v1/tokens", "search-tokens", api.ParamsSearchTokens{}},
+	{"GET", "/v1/balances/{address}", "get-balances",
+		api.ParamsGetBalances{}},
+	{"GET", "/v1/submissions", "get-submission-status",
+		api.ParamsGetSubmissionStatus{}},
+
+	{"GET", "/v1/chains/{chainid}", "get-issuance", api.ParamsToken{}},
+	{"GET", "/v1/chains/{chainid}/stats", "get-stats",
+		api.ParamsGetStats{}},
+	{"GET", "/v1/chains/{chainid}/state-hash", "get-state-hash",
+		api.ParamsGetStateHash{}},
+	{"GET", "/v1/chains/{chainid}/holders", "get-holders",
+		api.ParamsGetHolders{}},
+	{"GET", "/v1/chains/{chainid}/balances/{address}", "get-balance",
+		api.ParamsGetBalance{}},
+	{"GET", "/v1/chains/{chainid}/nf-balances/{address}", "get-nf-balance",
+		api.ParamsGetNFBalance{}},
+
+	{"GET", "/v1/chains/{chainid}/transactions", "get-transactions",
+		api.ParamsGetTransactions{}},
+	{"GET", "/v1/chains/{chainid}/transactions/invalid",
+		"get-invalid-transactions", api.ParamsGetInvalidTransactions{}},
+	{"GET", "/v1/chains/{chainid}/transactions/{entryhash}",
+		"get-transaction", api.ParamsGetTransaction{}},
+	{"GET", "/v1/chains/{chainid}/transactions/{entryhash}/proof",
+		"get-transaction-proof", api.ParamsGetTransactionProof{}},
+	{"POST", "/v1/chains/{chainid}/transactions", "send-transaction",
+		api.ParamsSendTransaction{}},
+	{"POST", "/v1/chains/{chainid}/transactions/compose",
+		"compose-transaction", api.ParamsComposeTransaction{}},
+	{"POST", "/v1/chains/{chainid}/transactions/assemble",
+		"assemble-transaction", api.ParamsAssembleTransaction{}},
+
+	{"GET", "/v1/chains/{chainid}/nf-tokens", "get-nf-tokens",
+		api.ParamsGetAllNFTokens{}},
+	{"GET", "/v1/chains/{chainid}/nf-tokens/search", "search-nf-tokens",
+		api.ParamsSearchNFTokens{}},
+	{"GET", "/v1/chains/{chainid}/nf-tokens/{nftokenid}", "get-nf-token",
+		api.ParamsGetNFToken{}},
+	{"GET", "/v1/chains/{chainid}/nf-tokens/{nftokenid}/history",
+		"get-nf-token-history", api.ParamsGetNFToken{}},
+}
+
+// match returns the path parameters of path if it matches the Pattern of r.
+func (r restRoute) match(path []string) (map[string]string, bool) {
+	pattern := strings.Split(strings.Trim(r.Pattern, "/"), "/")
+	if len(pattern) != len(path) {
+		return nil, false
+	}
+	vars := make(map[string]string)
+	for i, seg := range pattern {
+		if strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") {
+			vars[seg[1:len(seg)-1]] = path[i]
+			continue
+		}
+		if seg != path[i] {
+			return nil, false
+		}
+	}
+	return vars, true
+}
+
+// restHandler returns an http.Handler that serves restRoutes using methods,
+// which should already be wrapped with the same authorization, rate limits
+// and metrics as the JSON RPC API.
+//
+// Successful GET responses have an ETag keyed on the sync height, so clients
+// and caches may revalidate them cheaply. Responses for a past "height" are
+// cacheable for a day. See cacheHeaders. Errors use the HTTP status codes
+// listed in RPC.md and the JSON RPC error object as the body.
+func restHandler(methods jsonrpc2.MethodMap) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+		var allowed []string
+		for _, route := range restRoutes {
+			method, ok := methods[route.RPC]
+			if !ok {
+				// The method is not allowed on this listener.
+				continue
+			}
+			vars, ok := route.match(path)
+			if !ok {
+				continue
+			}
+			if route.Method != r.Method {
+				allowed = append(allowed, route.Method)
+				continue
+			}
+			route.serve(w, r, method, vars)
+			return
+		}
+		if len(allowed) > 0 {
+			w.Header().Set("Allow", strings.Join(allowed, ", "))
+			writeRESTError(w, http.StatusMethodNotAllowed,
+				jsonrpc2.NewError(jsonrpc2.ErrorCodeMethodNotFound,
+					"Method Not Allowed", nil))
+			return
+		}
+		writeRESTError(w, http.StatusNotFound,
+			jsonrpc2.NewError(jsonrpc2.ErrorCodeMethodNotFound,
+				"Not Found", r.URL.Path))
+	})
+}
+
+func (route restRoute) serve(w http.ResponseWriter, r *http.Request,
+	method jsonrpc2.MethodFunc, vars map[string]string) {
+	params := make(map[string]json.RawMessage)
+	if r.Method == "POST" {
+		body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, 1<<20))
+		if err != nil {
+			writeRESTError(w, http.StatusBadRequest,
+				jsonrpc2.ErrorInvalidParams(err))
+			return
+		}
+		if len(body) > 0 {
+			if err := json.Unmarshal(body, &params); err != nil {
+				writeRESTError(w, http.StatusBadRequest,
+					jsonrpc2.ErrorInvalidParams(err))
+				return
+			}
+		}
+	}
+	if err := route.setParams(params, vars, r.URL.Query()); err != nil {
+		writeRESTError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var etag, cacheControl string
+	if r.Method == "GET" {
+		etag, cacheControl = cacheHeaders(route.RPC, params)
+	}
+
+	var data json.RawMessage
+	if route.Params != nil {
+		var err error
+		if data, err = json.Marshal(params); err != nil {
+			panic(err)
+		}
+	}
+	res := callMethod(r.Context(), method, data)
+	if err, ok := res.(error); ok {
+		jErr, ok := err.(jsonrpc2.Error)
+		if jp, isPtr := err.(*jsonrpc2.Error); isPtr {
+			jErr, ok = *jp, true
+		}
+		if !ok {
+			log.Errorf("%v: %v", route.RPC, err)
+			jErr = jsonrpc2.NewError(jsonrpc2.ErrorCodeInternal,
+				jsonrpc2.ErrorMessageInternal, nil)
+		}
+		writeRESTError(w, restStatus(jErr.Code), jErr)
+		return
+	}
+
+	// Only a successful result may be revalidated, so that a stale ETag
+	// never hides an error.
+	header := w.Header()
+	if len(cacheControl) > 0 {
+		header.Set("Cache-Control", cacheControl)
+	}
+	if len(etag) > 0 {
+		header.Set("ETag", etag)
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+	writeREST(w, http.StatusOK, res)
+}
+
+// callMethod calls method, and returns an Internal Error if it panics.
+func callMethod(ctx context.Context, method jsonrpc2.MethodFunc,
+	params json.RawMessage) (res interface{}) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Errorf("method panic: %v", r)
+			res = jsonrpc2.NewError(jsonrpc2.ErrorCodeInternal,
+				jsonrpc2.ErrorMessageInternal, nil)
+		}
+	}()
+	return method(ctx, params)
+}
+
+// setParams adds the path and query parameters to params, converting them to
+// JSON based on the type of the corresponding field of route.Params. Path
+// parameters take precedence over query parameters, which take precedence
+// over any POST body.
+func (route restRoute) setParams(params map[string]json.RawMessage,
+	vars map[string]string, query url.Values) error {
+	if route.Params == nil {
+		if len(query) > 0 {
+			return jsonrpc2.ErrorInvalidParams(
+				"no parameters are accepted")
+		}
+		return nil
+	}
+	fields := jsonFields(reflect.TypeOf(route.Params))
+	for name, values := range query {
+		if _, ok := vars[name]; ok {
+			continue
+		}
+		typ, ok := fields[name]
+		if !ok {
+			return jsonrpc2.ErrorInvalidParams(
+				fmt.Sprintf("unknown parameter %q", name))
+		}
+		v, err := queryJSON(typ, values)
+		if err != nil {
+			return jsonrpc2.ErrorInvalidParams(
+				fmt.Sprintf("%q: %v", name, err))
+		}
+		params[name] = v
+	}
+	for name, value := range vars {
+		v, err := queryJSON(fields[name], []string{value})
+		if err != nil {
+			return jsonrpc2.ErrorInvalidParams(
+				fmt.Sprintf("%q: %v", name, err))
+		}
+		params[name] = v
+	}
+	return nil
+}
+
+// jsonFields returns the types of the fields of the struct typ, including
+// those of embedded structs, by their JSON names.
+func jsonFields(typ reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			for name, typ := range jsonFields(f.Type) {
+				fields[name] = typ
+			}
+			continue
+		}
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if len(name) == 0 || name == "-" || len(f.PkgPath) > 0 {
+			continue
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
+
+// queryJSON converts the query parameter values to JSON for a field of type
+// typ. Slices may be given as repeated or comma separated values. Booleans
+// and numbers are used as is, and anything else is a JSON string.
+func queryJSON(typ reflect.Type, values []string) (json.RawMessage, error) {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ.Kind() == reflect.Slice && typ.Elem().Kind() != reflect.Uint8 {
+		var elems []json.RawMessage
+		for _, value := range values {
+			for _, value := range strings.Split(value, ",") {
+				elem, err := queryJSON(typ.Elem(),
+					[]string{value})
+				if err != nil {
+					return nil, err
+				}
+				elems = append(elems, elem)
+			}
+		}
+		return json.Marshal(elems)
+	}
+	value := values[len(values)-1]
+	switch typ.Kind() {
+	case reflect.Bool:
+		if len(value) == 0 {
+			value = "true"
+		}
+		if _, err := strconv.ParseBool(value); err != nil {
+			return nil, err
+		}
+		return json.RawMessage(value), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16,
+		reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return nil, fmt.Errorf("invalid number")
+		}
+		return json.RawMessage(value), nil
+	}
+	return json.Marshal(value)
+}
+
+// noStoreRPCs are the methods whose results change independently of the
+// sync height, and so may never be cached.
+var noStoreRPCs = map[string]bool{
+	"get-sync-status":       true,
+	"get-submission-status": true,
+}
+
+// cacheHeaders returns the ETag and Cache-Control headers for a GET request
+// for rpc with params. Results of noStoreRPCs, or that include pending
+// entries, are not cached.
+func cacheHeaders(rpc string, params map[string]json.RawMessage) (
+	etag, cacheControl string) {
+	if noStoreRPCs[rpc] {
+		return "", "no-store"
+	}
+	var includePending bool
+	json.Unmarshal(params["includepending"], &includePending)
+	if includePending {
+		return "", "no-store"
+	}
+
+	// The sync height must be read before calling the method so that the
+	// ETag is never newer than the result.
+	sync, _ := engine.GetSyncStatus()
+	etag = fmt.Sprintf(`"%v-%v"`, APIVersion, sync)
+
+	cacheControl = "public"
+	if flag.HasAuth {
+		cacheControl = "private"
+	}
+	var height *uint32
+	json.Unmarshal(params["height"], &height)
+	if height != nil && *height <= sync {
+		return etag, cacheControl + ", max-age=86400"
+	}
+	return etag, cacheControl + ", no-cache"
+}
+
+// restStatus returns the HTTP status code for a JSON RPC error code.
+func restStatus(code jsonrpc2.ErrorCode) int {
+	switch code {
+	case jsonrpc2.ErrorCodeParse, jsonrpc2.ErrorCodeInvalidRequest,
+		jsonrpc2.ErrorCodeInvalidParams:
+		return http.StatusBadRequest
+	case jsonrpc2.ErrorCodeMethodNotFound:
+		return http.StatusNotFound
+	case api.ErrorTokenNotFound.Code, api.ErrorTransactionNotFound.Code,
+		api.ErrorSubscriptionNotFound.Code:
+		return http.StatusNotFound
+	case api.ErrorInvalidTransaction.Code, api.ErrorPendingDisabled.Code:
+		return http.StatusBadRequest
+	case api.ErrorTokenSyncing.Code, api.ErrorNoEC.Code:
+		return http.StatusServiceUnavailable
+	case api.ErrorFactomd.Code:
+		return http.StatusBadGateway
+	case api.ErrorUnauthorized.Code:
+		return http.StatusForbidden
+	case api.ErrorRateLimited.Code:
+		return http.StatusTooManyRequests
+	}
+	return http.StatusInternalServerError
+}
+
+func writeRESTError(w http.ResponseWriter, status int, err error) {
+	if jErr, ok := err.(jsonrpc2.Error); ok {
+		switch jErr.Code {
+		case api.ErrorRateLimited.Code:
+			data, _ := json.Marshal(jErr.Data)
+			var retry struct {
+				RetryAfter int64 `json:"retryafter"`
+			}
+			json.Unmarshal(data, &retry)
+			w.Header().Set("Retry-After",
+				strconv.FormatInt(retry.RetryAfter, 10))
+		case api.ErrorTokenSyncing.Code:
+			// The chain is synced further each scan.
+			retry := int64(math.Ceil(flag.FactomScanInterval.Seconds()))
+			if retry < 1 {
+				retry = 1
+			}
+			w.Header().Set("Retry-After", strconv.FormatInt(retry, 10))
+		}
+	}
+	writeREST(w, status, err)
+}
+
+func writeREST(w http.ResponseWriter, status int, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		log.Errorf("json.Marshal(): %v", err)
+		status = http.StatusInternalServerError
+		data = []byte(`{}`)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(data)
+}
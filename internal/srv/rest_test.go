@@ -0,0 +1,105 @@
+// MIT License
+//
+// Copyright 2018 Canonical Ledgers, LLC
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package srv
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	jsonrpc2 "github.com/AdamSLevy/jsonrpc2/v14"
+	"github.com/Factom-Asset-Tokens/fatd/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRESTCacheHeaders(t *testing.T) {
+	found := true
+	result := func(v interface{}) jsonrpc2.MethodFunc {
+		return func(context.Context, json.RawMessage) interface{} {
+			if !found {
+				return api.ErrorTokenNotFound
+			}
+			return v
+		}
+	}
+	handler := restHandler(jsonrpc2.MethodMap{
+		"get-issuance":          result(struct{}{}),
+		"get-sync-status":       result(api.ResultGetSyncStatus{}),
+		"get-submission-status": result(struct{}{}),
+	})
+	chain := "/v1/chains/" + fat0ChainID.String()
+	get := func(path, etag string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", path, nil)
+		if len(etag) > 0 {
+			r.Header.Set("If-None-Match", etag)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	w := get(chain, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	etag := w.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+	assert.Contains(t, w.Header().Get("Cache-Control"), "no-cache")
+
+	w = get(chain, etag)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Equal(t, etag, w.Header().Get("ETag"))
+
+	// A matching ETag must not hide that the chain is no longer found.
+	found = false
+	w = get(chain, etag)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Empty(t, w.Header().Get("ETag"))
+	assert.Empty(t, w.Header().Get("Cache-Control"))
+
+	found = true
+	for _, path := range []string{
+		"/v1/daemon/sync",
+		"/v1/submissions",
+	} {
+		w = get(path, etag)
+		assert.Equal(t, http.StatusOK, w.Code, path)
+		assert.Empty(t, w.Header().Get("ETag"), path)
+		assert.Equal(t, "no-store", w.Header().Get("Cache-Control"),
+			path)
+	}
+}
+
+func TestRESTTokenSyncing(t *testing.T) {
+	syncing := func(context.Context, json.RawMessage) interface{} {
+		return api.ErrorTokenSyncing
+	}
+	handler := restHandler(jsonrpc2.MethodMap{"get-issuance": syncing})
+	r := httptest.NewRequest("GET",
+		"/v1/chains/"+fat0ChainID.String(), nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+}
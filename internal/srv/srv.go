@@ -24,19 +24,20 @@ package srv
 
 import (
 	"context"
+	"encoding/json"
+	"net"
 	"net/http"
+	"sync"
 	"time"
 
 	jsonrpc2 "github.com/AdamSLevy/jsonrpc2/v14"
 	"github.com/Factom-Asset-Tokens/fatd/internal/flag"
 	_log "github.com/Factom-Asset-Tokens/fatd/internal/log"
-	"github.com/goji/httpauth"
-	"github.com/rs/cors"
+	"github.com/Factom-Asset-Tokens/fatd/internal/metrics"
 )
 
 var (
 	log _log.Log
-	srv http.Server
 
 	APIVersion              = "1"
 	FatdVersionHeaderKey    = http.CanonicalHeaderKey("Fatd-Version")
@@ -47,71 +48,192 @@ var (
 // closed and any goroutines will exit. The done channel is closed when the
 // server exits for any reason. If the done channel is closed before the stop
 // channel is closed, an error occurred. Errors are logged.
+//
+// The API is served on each of the -apilisteners, or on -apiaddress. If any
+// listener fails, all are closed.
 func Start(ctx context.Context) (done <-chan struct{}) {
 	log = _log.New("pkg", "srv")
 
-	// Set up JSON RPC 2.0 handler with correct headers.
 	jsonrpc2.DebugMethodFunc = true
-	jrpcHandler := jsonrpc2.HTTPRequestHandler(jsonrpc2Methods, log)
-
-	var handler http.Handler = http.HandlerFunc(
-		func(w http.ResponseWriter, r *http.Request) {
-			header := w.Header()
-			header.Add(FatdVersionHeaderKey, flag.Revision)
-			header.Add(FatdAPIVersionHeaderKey, APIVersion)
-			jrpcHandler(w, r)
-		})
 	if flag.HasAuth {
-		authOpts := httpauth.AuthOptions{
-			User:     flag.Username,
-			Password: flag.Password,
-			UnauthorizedHandler: http.HandlerFunc(
-				func(w http.ResponseWriter, _ *http.Request) {
-					w.WriteHeader(http.StatusUnauthorized)
-					w.Write([]byte(`{}`))
-				}),
+		var err error
+		if apiKeys, err = loadAPIKeys(); err != nil {
+			log.Errorf("loadAPIKeys(): %v", err)
+			return nil
 		}
-		handler = httpauth.BasicAuth(authOpts)(handler)
+	}
+	listeners, err := loadListeners()
+	if err != nil {
+		log.Errorf("loadListeners(): %v", err)
+		return nil
+	}
+	if flag.APIMaxExpensive > 0 {
+		expensive = make(chan struct{}, flag.APIMaxExpensive)
+	}
+	if flag.APIRateLimit > 0 || len(flag.APIMethodRateLimits) > 0 ||
+		expensive != nil {
+		go sweepLimiters(ctx)
 	}
 
-	// Set up server.
-	srvMux := http.NewServeMux()
-
-	srvMux.Handle("/", handler)
-	srvMux.Handle("/v1", handler)
-
-	cors := cors.New(cors.Options{AllowedOrigins: []string{"*"}})
-	srv = http.Server{Handler: cors.Handler(srvMux)}
-
-	srv.Addr = flag.APIAddress
+	servers := make([]*http.Server, len(listeners))
+	lns := make([]net.Listener, len(listeners))
+	for i, l := range listeners {
+		ln, err := l.listen()
+		if err != nil {
+			log.Errorf("listen %v: %v", l.Address, err)
+			for _, ln := range lns[:i] {
+				ln.Close()
+			}
+			return nil
+		}
+		lns[i] = ln
+		tlsConfig, err := l.tlsConfig()
+		if err != nil {
+			log.Errorf("%v: %v", l.Address, err)
+			for _, ln := range lns[:i+1] {
+				ln.Close()
+			}
+			return nil
+		}
+		servers[i] = &http.Server{
+			Handler:   l.handler(ctx),
+			TLSConfig: tlsConfig,
+		}
+	}
 
-	// Start server.
+	// Start servers.
 	_done := make(chan struct{})
-	log.Infof("Listening on %v...", flag.APIAddress)
+	var wg sync.WaitGroup
+	var once sync.Once
+	shutdown := func() {
+		once.Do(func() {
+			ctx, cancel := context.WithTimeout(
+				context.Background(), 5*time.Second)
+			defer cancel()
+			for _, srv := range servers {
+				if err := srv.Shutdown(ctx); err != nil {
+					log.Errorf("srv.Shutdown(): %v", err)
+				}
+			}
+		})
+	}
+	for i, l := range listeners {
+		l, srv, ln := l, servers[i], lns[i]
+		log.Infof("Listening on %v...", l.Address)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var err error
+			if l.HasTLS() {
+				err = srv.ServeTLS(ln, l.TLSCertFile, l.TLSKeyFile)
+			} else {
+				err = srv.Serve(ln)
+			}
+			if err != http.ErrServerClosed {
+				log.Errorf("srv.Serve(%v): %v", l.Address, err)
+				go shutdown()
+			}
+		}()
+	}
 	go func() {
-		var err error
-		if flag.HasTLS {
-			err = srv.ListenAndServeTLS(flag.TLSCertFile, flag.TLSKeyFile)
-		} else {
-			err = srv.ListenAndServe()
-		}
-		if err != http.ErrServerClosed {
-			log.Errorf("srv.ListenAndServe(): %v", err)
-		}
+		wg.Wait()
 		close(_done)
 	}()
 	// Listen for stop signal.
 	go func() {
 		select {
 		case <-ctx.Done():
-			ctx, cancel := context.WithTimeout(
-				context.Background(), 5*time.Second)
-			defer cancel()
-			if err := srv.Shutdown(ctx); err != nil {
-				log.Errorf("srv.Shutdown(): %w", err)
-			}
+			shutdown()
 		case <-_done:
 		}
 	}()
 	return _done
 }
+
+// handler returns the http.Handler for all endpoints served on l. WebSocket
+// connections are closed when ctx is done.
+func (l listener) handler(ctx context.Context) http.Handler {
+	methods := l.methods()
+	wsMethods := l.wsMethods()
+	wsUpgrade := wsHandler(ctx, wsMethods)
+
+	// The rpc.discover method must be handled before the jsonrpc2
+	// handler, which reserves all "rpc." methods.
+	jrpcHandler := withDiscover(discover(methods),
+		jsonrpc2.HTTPRequestHandler(methods, log))
+	rest := restHandler(methods)
+
+	// Set up JSON RPC 2.0 handler with correct headers.
+	var handler http.Handler = withCaller(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			header := w.Header()
+			header.Add(FatdVersionHeaderKey, flag.Revision)
+			header.Add(FatdAPIVersionHeaderKey, APIVersion)
+			jrpcHandler.ServeHTTP(w, r)
+		}))
+
+	// Set up the REST gateway with the same headers.
+	var restGateway http.Handler = withCaller(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			header := w.Header()
+			header.Add(FatdVersionHeaderKey, flag.Revision)
+			header.Add(FatdAPIVersionHeaderKey, APIVersion)
+			rest.ServeHTTP(w, r)
+		}))
+
+	// Set up WebSocket subscription handler with the same headers.
+	var subHandler http.Handler = withCaller(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			header := w.Header()
+			header.Add(FatdVersionHeaderKey, flag.Revision)
+			header.Add(FatdAPIVersionHeaderKey, APIVersion)
+			wsUpgrade(w, r)
+		}))
+
+	metricsHandler := metrics.Handler()
+
+	if l.HasAuth() {
+		handler = requireAuth(handler)
+		restGateway = requireAuth(restGateway)
+		subHandler = requireAuth(subHandler)
+		metricsHandler = requireAuth(metricsHandler)
+	}
+
+	// Set up server.
+	srvMux := http.NewServeMux()
+
+	srvMux.Handle("/", handler)
+	srvMux.Handle("/v1", handler)
+	srvMux.Handle("/v1/", restGateway)
+	if len(wsMethods) > 0 {
+		srvMux.Handle("/ws", subHandler)
+	}
+	// The probe endpoints are never authenticated.
+	srvMux.HandleFunc("/healthz", healthz)
+	srvMux.HandleFunc("/readyz", readyz)
+	if flag.APIMetrics {
+		srvMux.Handle("/metrics", metricsHandler)
+	}
+
+	return newCORS().Handler(srvMux)
+}
+
+// instrument wraps method so that its latency and any errors it returns are
+// recorded in the RPC metrics for name.
+func instrument(name string, method jsonrpc2.MethodFunc) jsonrpc2.MethodFunc {
+	return func(ctx context.Context, params json.RawMessage) (res interface{}) {
+		start := time.Now()
+		defer func() {
+			metrics.RPCDuration.WithLabelValues(name).
+				Observe(time.Since(start).Seconds())
+			if r := recover(); r != nil {
+				metrics.RPCErrors.WithLabelValues(name).Inc()
+				panic(r)
+			}
+			if _, ok := res.(error); ok {
+				metrics.RPCErrors.WithLabelValues(name).Inc()
+			}
+		}()
+		return method(ctx, params)
+	}
+}
@@ -0,0 +1,347 @@
+// MIT License
+//
+// Copyright 2018 Canonical Ledgers, LLC
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package srv
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+
+	jsonrpc2 "github.com/AdamSLevy/jsonrpc2/v14"
+	"github.com/Factom-Asset-Tokens/factom"
+	"github.com/Factom-Asset-Tokens/fatd/api"
+	"github.com/Factom-Asset-Tokens/fatd/internal/events"
+	"github.com/gorilla/websocket"
+)
+
+// wsMethodFunc is like a jsonrpc2.MethodFunc but also receives the wsSession
+// that the request arrived on so that it may manage subscriptions.
+type wsMethodFunc func(context.Context, *wsSession, json.RawMessage) interface{}
+
+type wsSessionCtxKey struct{}
+
+// wsMethod returns a jsonrpc2.MethodFunc that calls method with the wsSession
+// of ctx, so that it may be wrapped with the same rate limits and metrics as
+// all other methods.
+func wsMethod(method wsMethodFunc) jsonrpc2.MethodFunc {
+	return func(ctx context.Context, params json.RawMessage) interface{} {
+		s := ctx.Value(wsSessionCtxKey{}).(*wsSession)
+		return method(ctx, s, params)
+	}
+}
+
+var wsMethods = map[string]wsMethodFunc{
+	"subscribe-transactions": subscribeTransactions,
+	"subscribe-address":      subscribeAddress,
+	"subscribe-sync":         subscribeSync,
+	"unsubscribe":            unsubscribe,
+}
+
+// maxWSSubscriptions is the maximum number of active subscriptions on a
+// single WebSocket connection.
+const maxWSSubscriptions = 100
+
+// SubscriptionMethod is the method name used for the JSON-RPC 2.0
+// Notifications that deliver subscribed events.
+const SubscriptionMethod = "subscription"
+
+var upgrader = websocket.Upgrader{
+	// Browsers do not apply CORS to WebSockets, so apply the same
+	// origins here.
+	CheckOrigin: func(r *http.Request) bool {
+		return wsOriginAllowed(r.Header.Get("Origin"))
+	},
+}
+
+// wsSession holds the subscriptions for a single WebSocket connection. All
+// writes to the connection are done by the write goroutine, which receives
+// Responses and Notifications on out.
+type wsSession struct {
+	conn *websocket.Conn
+	out  chan interface{}
+
+	// methods are the wsMethods allowed on the listener, wrapped by
+	// listener.wsMethods.
+	methods jsonrpc2.MethodMap
+
+	ctx    context.Context
+	cancel func()
+
+	mtx    sync.Mutex
+	subs   map[uint64]*events.Subscription
+	nextID uint64
+}
+
+// wsHandler returns an http.HandlerFunc that upgrades the connection to a
+// WebSocket and serves requests for methods until the client disconnects or
+// ctx is done. The request must have a caller, so that methods are rate
+// limited per client.
+func wsHandler(ctx context.Context,
+	methods jsonrpc2.MethodMap) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			// The Upgrader already replied with an HTTP error.
+			log.Debugf("websocket.Upgrader.Upgrade(): %v", err)
+			return
+		}
+		ctx := context.WithValue(ctx, callerCtxKey{},
+			getCaller(r.Context()))
+		ctx, cancel := context.WithCancel(ctx)
+		s := &wsSession{
+			conn:    conn,
+			out:     make(chan interface{}),
+			methods: methods,
+			cancel:  cancel,
+			subs:    make(map[uint64]*events.Subscription),
+		}
+		s.ctx = context.WithValue(ctx, wsSessionCtxKey{}, s)
+		defer s.close()
+		go s.write()
+		s.read()
+	}
+}
+
+// read requests from the connection until it is closed.
+func (s *wsSession) read() {
+	for {
+		_, data, err := s.conn.ReadMessage()
+		if err != nil {
+			if s.ctx.Err() == nil && !websocket.IsCloseError(err,
+				websocket.CloseNormalClosure,
+				websocket.CloseGoingAway) {
+				log.Debugf("websocket.Conn.ReadMessage(): %v", err)
+			}
+			return
+		}
+		res, ok := s.handle(data)
+		if !ok {
+			continue
+		}
+		select {
+		case s.out <- res:
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+// write all Responses and Notifications sent on out to the connection until
+// ctx is done.
+func (s *wsSession) write() {
+	defer s.conn.Close()
+	for {
+		select {
+		case msg := <-s.out:
+			if err := s.conn.WriteJSON(msg); err != nil {
+				log.Debugf("websocket.Conn.WriteJSON(): %v", err)
+				s.cancel()
+				return
+			}
+		case <-s.ctx.Done():
+			s.conn.WriteMessage(websocket.CloseMessage,
+				websocket.FormatCloseMessage(
+					websocket.CloseGoingAway, ""))
+			return
+		}
+	}
+}
+
+// close all subscriptions and the connection.
+func (s *wsSession) close() {
+	s.cancel()
+	s.conn.Close()
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	for id, sub := range s.subs {
+		delete(s.subs, id)
+		sub.Close()
+	}
+}
+
+// handle a single JSON-RPC 2.0 Request. If ok is false, the request was a
+// Notification and no Response should be sent.
+func (s *wsSession) handle(data []byte) (res jsonrpc2.Response, ok bool) {
+	if !json.Valid(data) {
+		return jsonrpc2.Response{Error: jsonrpc2.NewError(
+			jsonrpc2.ErrorCodeParse, jsonrpc2.ErrorMessageParse,
+			nil)}, true
+	}
+	var req jsonrpc2.Request
+	if err := json.Unmarshal(data, &req); err != nil {
+		return jsonrpc2.Response{Error: jsonrpc2.NewError(
+			jsonrpc2.ErrorCodeInvalidRequest,
+			jsonrpc2.ErrorMessageInvalidRequest, err)}, true
+	}
+	id, params := req.ID.(json.RawMessage), req.Params.(json.RawMessage)
+	if id == nil {
+		return res, false
+	}
+	res.ID = id
+
+	method, ok := s.methods[req.Method]
+	if !ok {
+		res.Error = jsonrpc2.NewError(jsonrpc2.ErrorCodeMethodNotFound,
+			jsonrpc2.ErrorMessageMethodNotFound, req.Method)
+		return res, true
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			log.Errorf("panic running method %q: %v", req.Method, r)
+			res.Result = nil
+			res.Error = jsonrpc2.NewError(jsonrpc2.ErrorCodeInternal,
+				jsonrpc2.ErrorMessageInternal, nil)
+		}
+	}()
+
+	result := method(s.ctx, params)
+	if err, ok := result.(error); ok {
+		var jErr jsonrpc2.Error
+		if !errors.As(err, &jErr) {
+			jErr = jsonrpc2.NewError(jsonrpc2.ErrorCodeInternal,
+				jsonrpc2.ErrorMessageInternal, nil)
+		}
+		res.Error = jErr
+		return res, true
+	}
+	res.Result = result
+	return res, true
+}
+
+// subscribe creates a new events.Subscription with filter and forwards all of
+// its events to the client as Notifications. If the subscriber falls too far
+// behind, the connection is closed. No more than maxWSSubscriptions may be
+// active at once.
+func (s *wsSession) subscribe(filter func(interface{}) bool) interface{} {
+	s.mtx.Lock()
+	if len(s.subs) >= maxWSSubscriptions {
+		s.mtx.Unlock()
+		return api.ErrorTooManySubscriptions
+	}
+	sub := events.Subscribe(filter)
+	s.nextID++
+	id := s.nextID
+	s.subs[id] = sub
+	s.mtx.Unlock()
+
+	go func() {
+		for ev := range sub.C {
+			if tx, ok := ev.(events.Transaction); ok {
+				ev = tx.EventTransaction
+			}
+			n := jsonrpc2.Request{Method: SubscriptionMethod,
+				Params: api.EventNotification{
+					Subscription: id, Result: ev}}
+			select {
+			case s.out <- n:
+			case <-s.ctx.Done():
+				return
+			}
+		}
+		// The Subscription was closed. If it is still in the session,
+		// then the hub closed it because we fell behind.
+		s.mtx.Lock()
+		_, ok := s.subs[id]
+		s.mtx.Unlock()
+		if ok {
+			log.Debugf("websocket subscriber fell behind, closing")
+			s.cancel()
+		}
+	}()
+
+	return api.ResultSubscribe{Subscription: id}
+}
+
+func subscribeTransactions(ctx context.Context,
+	s *wsSession, data json.RawMessage) interface{} {
+	var params api.ParamsToken
+	chain, put, err := validate(ctx, data, &params)
+	if err != nil {
+		return err
+	}
+	chainID := *chain.ID
+	put()
+
+	return s.subscribe(func(ev interface{}) bool {
+		tx, ok := ev.(events.Transaction)
+		return ok && *tx.ChainID == chainID &&
+			(params.IncludePending ||
+				tx.Status == api.EventStatusConfirmed)
+	})
+}
+
+func subscribeAddress(ctx context.Context,
+	s *wsSession, data json.RawMessage) interface{} {
+	var params api.ParamsSubscribeAddress
+	chain, put, err := validate(ctx, data, &params)
+	if err != nil {
+		return err
+	}
+	var chainID *factom.Bytes32
+	if chain != nil {
+		chainID = new(factom.Bytes32)
+		*chainID = *chain.ID
+		put()
+	}
+
+	adr := *params.Address
+	return s.subscribe(func(ev interface{}) bool {
+		tx, ok := ev.(events.Transaction)
+		return ok && (chainID == nil || *tx.ChainID == *chainID) &&
+			(params.IncludePending ||
+				tx.Status == api.EventStatusConfirmed) &&
+			tx.Involves(adr)
+	})
+}
+
+func subscribeSync(ctx context.Context,
+	s *wsSession, data json.RawMessage) interface{} {
+	if _, _, err := validate(ctx, data, nil); err != nil {
+		return err
+	}
+	return s.subscribe(func(ev interface{}) bool {
+		_, ok := ev.(api.EventSync)
+		return ok
+	})
+}
+
+func unsubscribe(ctx context.Context,
+	s *wsSession, data json.RawMessage) interface{} {
+	var params api.ParamsUnsubscribe
+	if _, _, err := validate(ctx, data, &params); err != nil {
+		return err
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	sub, ok := s.subs[*params.Subscription]
+	if !ok {
+		return api.ErrorSubscriptionNotFound
+	}
+	delete(s.subs, *params.Subscription)
+	sub.Close()
+	return true
+}
@@ -0,0 +1,137 @@
+// MIT License
+//
+// Copyright 2018 Canonical Ledgers, LLC
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package srv
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	jsonrpc2 "github.com/AdamSLevy/jsonrpc2/v14"
+	"github.com/Factom-Asset-Tokens/fatd/api"
+	"github.com/Factom-Asset-Tokens/fatd/internal/events"
+	"github.com/Factom-Asset-Tokens/fatd/internal/flag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListenerWSMethods(t *testing.T) {
+	l := listener{Methods: []string{"get-*", "subscribe-sync"}}
+	methods := l.wsMethods()
+	assert.Len(t, methods, 1)
+	assert.Contains(t, methods, "subscribe-sync")
+
+	l.Methods = []string{"get-*"}
+	assert.Empty(t, l.wsMethods())
+
+	l.Methods = nil
+	assert.Len(t, l.wsMethods(), len(wsMethods))
+}
+
+func TestWSSessionMethodNotAllowed(t *testing.T) {
+	s := wsSession{methods: listener{
+		Methods: []string{"subscribe-sync"}}.wsMethods()}
+	for _, method := range []string{
+		"subscribe-address", "subscribe-transactions", "unsubscribe",
+	} {
+		data, err := json.Marshal(jsonrpc2.Request{
+			Method: method, Params: json.RawMessage(`{}`), ID: 1})
+		require.NoError(t, err)
+		res, ok := s.handle(data)
+		require.True(t, ok)
+		require.True(t, res.HasError(), method)
+		assert.Equal(t, jsonrpc2.ErrorCodeMethodNotFound,
+			res.Error.Code, method)
+	}
+}
+
+// newTestWSSession returns a wsSession without a connection, for the methods
+// allowed on l, with the caller c. The returned func closes all
+// subscriptions.
+func newTestWSSession(l listener, c caller) (*wsSession, func()) {
+	ctx := context.WithValue(context.Background(), callerCtxKey{}, c)
+	ctx, cancel := context.WithCancel(ctx)
+	s := &wsSession{
+		out:     make(chan interface{}, 1),
+		methods: l.wsMethods(),
+		cancel:  cancel,
+		subs:    make(map[uint64]*events.Subscription),
+	}
+	s.ctx = context.WithValue(ctx, wsSessionCtxKey{}, s)
+	return s, func() {
+		cancel()
+		s.mtx.Lock()
+		defer s.mtx.Unlock()
+		for _, sub := range s.subs {
+			sub.Close()
+		}
+	}
+}
+
+// wsCall calls method on s and returns the Response.
+func wsCall(t *testing.T, s *wsSession, method string) jsonrpc2.Response {
+	data, err := json.Marshal(jsonrpc2.Request{Method: method, ID: 1})
+	require.NoError(t, err)
+	res, ok := s.handle(data)
+	require.True(t, ok)
+	return res
+}
+
+func TestWSMaxSubscriptions(t *testing.T) {
+	s, close := newTestWSSession(listener{}, caller{})
+	defer close()
+	for i := 0; i < maxWSSubscriptions; i++ {
+		res := wsCall(t, s, "subscribe-sync")
+		require.False(t, res.HasError(), i)
+	}
+	res := wsCall(t, s, "subscribe-sync")
+	require.True(t, res.HasError())
+	assert.Equal(t, api.ErrorTooManySubscriptions.Code, res.Error.Code)
+}
+
+func TestWSRateLimit(t *testing.T) {
+	defer func(limit, burst uint64) {
+		flag.APIRateLimit, flag.APIRateBurst = limit, burst
+	}(flag.APIRateLimit, flag.APIRateBurst)
+	flag.APIRateLimit, flag.APIRateBurst = 1, 1
+
+	// Both sessions of the same client share its rate limit.
+	c := caller{RemoteAddr: "192.0.2.1:1234"}
+	s1, close1 := newTestWSSession(listener{}, c)
+	defer close1()
+	s2, close2 := newTestWSSession(listener{}, c)
+	defer close2()
+
+	res := wsCall(t, s1, "subscribe-sync")
+	require.False(t, res.HasError())
+	res = wsCall(t, s2, "subscribe-sync")
+	require.True(t, res.HasError())
+	assert.Equal(t, api.ErrorRateLimited.Code, res.Error.Code)
+
+	// Other clients are not limited.
+	s3, close3 := newTestWSSession(listener{},
+		caller{RemoteAddr: "192.0.2.2:1234"})
+	defer close3()
+	res = wsCall(t, s3, "subscribe-sync")
+	assert.False(t, res.HasError())
+}
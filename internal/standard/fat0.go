@@ -0,0 +1,110 @@
+// MIT License
+//
+// Copyright 2018 Canonical Ledgers, LLC
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package standard
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/Factom-Asset-Tokens/factom"
+	"github.com/Factom-Asset-Tokens/factom/fat"
+	"github.com/Factom-Asset-Tokens/factom/fat0"
+	"github.com/Factom-Asset-Tokens/factom/fat1"
+)
+
+func init() {
+	Register(FAT0{})
+}
+
+// FAT0 implements TokenStandard for FAT-0 fungible tokens. Transactions are
+// fat0.Transaction values.
+type FAT0 struct{}
+
+func (FAT0) Type() fat.Type           { return fat0.Type }
+func (FAT0) Transaction() interface{} { return fat0.Transaction{} }
+func (FAT0) NFTokens() bool           { return false }
+
+// CreateTable returns an empty string since FAT-0 only uses the tables common
+// to all chains.
+func (FAT0) CreateTable() string { return "" }
+func (FAT0) Tables() []string    { return nil }
+
+func (FAT0) NewTransaction(e factom.Entry,
+	idKey *factom.Bytes32) (interface{}, error) {
+	return fat0.NewTransaction(e, idKey)
+}
+
+func (FAT0) Compose(inputs, outputs, tokenMetadata,
+	metadata json.RawMessage) (interface{}, error) {
+	if len(tokenMetadata) > 0 {
+		return nil, fmt.Errorf(
+			`"tokenmetadata" is not supported for %v`, fat0.Type)
+	}
+	var tx fat0.Transaction
+	if err := tx.Inputs.UnmarshalJSON(inputs); err != nil {
+		return nil, fmt.Errorf("inputs: %w", err)
+	}
+	if err := tx.Outputs.UnmarshalJSON(outputs); err != nil {
+		return nil, fmt.Errorf("outputs: %w", err)
+	}
+	if tx.Inputs.Sum() != tx.Outputs.Sum() {
+		return nil, fmt.Errorf("sum(inputs) != sum(outputs)")
+	}
+	if tx.IsCoinbase() && len(tx.Inputs) != 1 {
+		return nil, fmt.Errorf("invalid coinbase transaction")
+	}
+	tx.Metadata = metadata
+	return tx, nil
+}
+
+func (FAT0) Addresses(txI interface{}) (inputs, outputs []factom.FAAddress) {
+	tx := txI.(fat0.Transaction)
+	for adr := range tx.Inputs {
+		inputs = append(inputs, adr)
+	}
+	for adr := range tx.Outputs {
+		outputs = append(outputs, adr)
+	}
+	return
+}
+
+func (FAT0) Coinbase(txI interface{}) (interface{}, error) {
+	tx := txI.(fat0.Transaction)
+	tx.Inputs = fat0.AddressAmountMap{fat.Coinbase(): tx.Outputs.Sum()}
+	return tx, nil
+}
+
+func (FAT0) Sign(txI interface{}, chainID *factom.Bytes32,
+	signers ...factom.RCDSigner) (factom.Entry, error) {
+	tx := txI.(fat0.Transaction)
+	tx.Entry.ChainID = chainID
+	return tx.Sign(signers...)
+}
+
+func (FAT0) InputAmount(txI interface{}, adr factom.FAAddress) uint64 {
+	return txI.(fat0.Transaction).Inputs[adr]
+}
+
+func (FAT0) InputNFTokens(interface{}, factom.FAAddress) fat1.NFTokens {
+	return nil
+}
@@ -0,0 +1,130 @@
+// MIT License
+//
+// Copyright 2018 Canonical Ledgers, LLC
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package standard
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/Factom-Asset-Tokens/factom"
+	"github.com/Factom-Asset-Tokens/factom/fat"
+	"github.com/Factom-Asset-Tokens/factom/fat1"
+)
+
+func init() {
+	Register(FAT1{})
+}
+
+// FAT1 implements TokenStandard for FAT-1 non-fungible tokens. Transactions
+// are fat1.Transaction values.
+type FAT1 struct{}
+
+func (FAT1) Type() fat.Type           { return fat1.Type }
+func (FAT1) Transaction() interface{} { return fat1.Transaction{} }
+func (FAT1) NFTokens() bool           { return true }
+
+// CreateTable returns an empty string since the NFToken tables are common to
+// all chains.
+func (FAT1) CreateTable() string { return "" }
+func (FAT1) Tables() []string    { return nil }
+
+func (FAT1) NewTransaction(e factom.Entry,
+	idKey *factom.Bytes32) (interface{}, error) {
+	return fat1.NewTransaction(e, idKey)
+}
+
+func (FAT1) Compose(inputs, outputs, tokenMetadata,
+	metadata json.RawMessage) (interface{}, error) {
+	var tx fat1.Transaction
+	if err := tx.Inputs.UnmarshalJSON(inputs); err != nil {
+		return nil, fmt.Errorf("inputs: %w", err)
+	}
+	if err := tx.Outputs.UnmarshalJSON(outputs); err != nil {
+		return nil, fmt.Errorf("outputs: %w", err)
+	}
+	tkns, err := tx.Inputs.AllNFTokens()
+	if err != nil {
+		return nil, fmt.Errorf("inputs: %w", err)
+	}
+	if tx.Inputs.Sum() != tx.Outputs.Sum() {
+		return nil, fmt.Errorf("number of NFTokenIDs differ")
+	}
+	for _, outTkns := range tx.Outputs {
+		if err := tkns.ContainsAll(outTkns); err != nil {
+			return nil, fmt.Errorf(
+				"Inputs and Outputs mismatch: %w", err)
+		}
+	}
+	if len(tokenMetadata) > 0 {
+		if !tx.IsCoinbase() {
+			return nil, fmt.Errorf(
+				`non-coinbase transaction with "tokenmetadata"`)
+		}
+		if err := tx.TokenMetadata.UnmarshalJSON(
+			tokenMetadata); err != nil {
+			return nil, fmt.Errorf("tokenmetadata: %w", err)
+		}
+	}
+	if tx.IsCoinbase() && len(tx.Inputs) != 1 {
+		return nil, fmt.Errorf("invalid coinbase transaction")
+	}
+	tx.Metadata = metadata
+	return tx, nil
+}
+
+func (FAT1) Addresses(txI interface{}) (inputs, outputs []factom.FAAddress) {
+	tx := txI.(fat1.Transaction)
+	for adr := range tx.Inputs {
+		inputs = append(inputs, adr)
+	}
+	for adr := range tx.Outputs {
+		outputs = append(outputs, adr)
+	}
+	return
+}
+
+func (FAT1) Coinbase(txI interface{}) (interface{}, error) {
+	tx := txI.(fat1.Transaction)
+	tkns, err := tx.Outputs.AllNFTokens()
+	if err != nil {
+		return nil, err
+	}
+	tx.Inputs = fat1.AddressNFTokensMap{fat.Coinbase(): tkns}
+	return tx, nil
+}
+
+func (FAT1) Sign(txI interface{}, chainID *factom.Bytes32,
+	signers ...factom.RCDSigner) (factom.Entry, error) {
+	tx := txI.(fat1.Transaction)
+	tx.Entry.ChainID = chainID
+	return tx.Sign(signers...)
+}
+
+func (FAT1) InputAmount(txI interface{}, adr factom.FAAddress) uint64 {
+	return uint64(len(txI.(fat1.Transaction).Inputs[adr]))
+}
+
+func (FAT1) InputNFTokens(txI interface{},
+	adr factom.FAAddress) fat1.NFTokens {
+	return txI.(fat1.Transaction).Inputs[adr]
+}
@@ -0,0 +1,111 @@
+// MIT License
+//
+// Copyright 2018 Canonical Ledgers, LLC
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package rules
+
+import (
+	"fmt"
+
+	"github.com/Factom-Asset-Tokens/factom/fat"
+	"github.com/Factom-Asset-Tokens/factom/fat0"
+	"github.com/Factom-Asset-Tokens/fatd/internal/db/address"
+	"github.com/Factom-Asset-Tokens/fatd/internal/standard"
+)
+
+func init() {
+	Register(FAT0{})
+}
+
+// FAT0 implements Rules for FAT-0 fungible tokens.
+type FAT0 struct{ standard.FAT0 }
+
+func (FAT0) Check(chain Chain, txI interface{}) (txErr, err error) {
+	tx := txI.(fat0.Transaction)
+	if tx.IsCoinbase() {
+		return chain.checkSupply(tx.Inputs[fat.Coinbase()]), nil
+	}
+	// Check all input balances
+	for adr, amount := range tx.Inputs {
+		var bal uint64
+		if _, bal, err = address.SelectIDBalance(
+			chain.Conn, &adr); err != nil {
+			return
+		}
+		if amount > bal {
+			txErr = fmt.Errorf("insufficient balance: %v", adr)
+			return
+		}
+	}
+	return
+}
+
+// Apply calls Check first, so that the rules are only written once, and then
+// updates the balances of tx.
+func (r FAT0) Apply(chain Chain, eID int64, txI interface{}) (
+	issued uint64, txErr, err error) {
+	if txErr, err = r.Check(chain, txI); err != nil || txErr != nil {
+		return
+	}
+	tx := txI.(fat0.Transaction)
+
+	if tx.IsCoinbase() {
+		issued = tx.Inputs[fat.Coinbase()]
+		if _, err = address.InsertTxRelation(chain.Conn,
+			address.CoinbaseID, eID, false); err != nil {
+			return
+		}
+	} else {
+		for adr, amount := range tx.Inputs {
+			var ai int64
+			ai, txErr, err = address.Sub(chain.Conn, &adr, amount)
+			if err != nil || txErr != nil {
+				return
+			}
+			if err = address.InsertBalanceDelta(
+				chain.Conn, ai, eID, -int64(amount)); err != nil {
+				return
+			}
+			if _, err = address.InsertTxRelation(
+				chain.Conn, ai, eID, false); err != nil {
+				return
+			}
+		}
+	}
+
+	for adr, amount := range tx.Outputs {
+		var ai int64
+		ai, err = address.Add(chain.Conn, &adr, amount)
+		if err != nil {
+			return
+		}
+		if err = address.InsertBalanceDelta(
+			chain.Conn, ai, eID, int64(amount)); err != nil {
+			return
+		}
+		if _, err = address.InsertTxRelation(
+			chain.Conn, ai, eID, true); err != nil {
+			return
+		}
+	}
+
+	return
+}
@@ -0,0 +1,193 @@
+// MIT License
+//
+// Copyright 2018 Canonical Ledgers, LLC
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package rules
+
+import (
+	"fmt"
+
+	"github.com/Factom-Asset-Tokens/factom/fat"
+	"github.com/Factom-Asset-Tokens/factom/fat1"
+	"github.com/Factom-Asset-Tokens/fatd/internal/db/address"
+	"github.com/Factom-Asset-Tokens/fatd/internal/db/nftoken"
+	"github.com/Factom-Asset-Tokens/fatd/internal/db/search"
+	"github.com/Factom-Asset-Tokens/fatd/internal/standard"
+)
+
+func init() {
+	Register(FAT1{})
+}
+
+// FAT1 implements Rules for FAT-1 non-fungible tokens.
+type FAT1 struct{ standard.FAT1 }
+
+func (FAT1) Check(chain Chain, txI interface{}) (txErr, err error) {
+	tx := txI.(fat1.Transaction)
+	if tx.IsCoinbase() {
+		nfTkns := tx.Inputs[fat.Coinbase()]
+		if txErr = chain.checkSupply(uint64(len(nfTkns))); txErr != nil {
+			return
+		}
+		for nfID := range nfTkns {
+			var ownerID int64
+			ownerID, err = nftoken.SelectOwnerID(chain.Conn, nfID)
+			if err != nil {
+				return
+			}
+			if ownerID != -1 {
+				txErr = fmt.Errorf("NFTokenID{%v} already exists", nfID)
+				return
+			}
+		}
+		return
+	}
+	for adr, nfTkns := range tx.Inputs {
+		var adrID int64
+		var bal uint64
+		adrID, bal, err = address.SelectIDBalance(chain.Conn, &adr)
+		if err != nil {
+			return
+		}
+		amount := uint64(len(nfTkns))
+		if amount > bal {
+			txErr = fmt.Errorf("insufficient balance: %v", adr)
+			return
+		}
+		for nfTkn := range nfTkns {
+			var ownerID int64
+			ownerID, err = nftoken.SelectOwnerID(chain.Conn, nfTkn)
+			if err != nil {
+				return
+			}
+			if ownerID == -1 {
+				txErr = fmt.Errorf("no such NFToken{%v}", nfTkn)
+				return
+			}
+			if ownerID != adrID {
+				txErr = fmt.Errorf("NFToken{%v} not owned by %v",
+					nfTkn, adr)
+				return
+			}
+		}
+	}
+	return
+}
+
+// Apply calls Check first, so that the rules are only written once, and then
+// updates the balances and NFToken owners of tx.
+func (r FAT1) Apply(chain Chain, eID int64, txI interface{}) (
+	issued uint64, txErr, err error) {
+	if txErr, err = r.Check(chain, txI); err != nil || txErr != nil {
+		return
+	}
+	tx := txI.(fat1.Transaction)
+
+	if tx.IsCoinbase() {
+		nfTkns := tx.Inputs[fat.Coinbase()]
+		issued = uint64(len(nfTkns))
+		var adrTxID int64
+		adrTxID, err = address.InsertTxRelation(chain.Conn,
+			address.CoinbaseID, eID, false)
+		if err != nil {
+			return
+		}
+		for nfID := range nfTkns {
+			// Insert the NFToken with the coinbase address as a
+			// placeholder for the owner.
+			txErr, err = nftoken.Insert(chain.Conn,
+				nfID, address.CoinbaseID, eID)
+			if err != nil || txErr != nil {
+				return
+			}
+			if err = nftoken.InsertTxRelation(
+				chain.Conn, nfID, adrTxID); err != nil {
+				return
+			}
+			metadata := tx.TokenMetadata[nfID]
+			if len(metadata) == 0 {
+				continue
+			}
+			if err = nftoken.SetMetadata(
+				chain.Conn, nfID, metadata); err != nil {
+				return
+			}
+			if err = search.InsertNFToken(
+				chain.Conn, nfID, metadata); err != nil {
+				return
+			}
+		}
+	} else {
+		for adr, nfTkns := range tx.Inputs {
+			var ai int64
+			ai, txErr, err = address.Sub(
+				chain.Conn, &adr, uint64(len(nfTkns)))
+			if err != nil || txErr != nil {
+				return
+			}
+			if err = address.InsertBalanceDelta(chain.Conn,
+				ai, eID, -int64(len(nfTkns))); err != nil {
+				return
+			}
+			var adrTxID int64
+			adrTxID, err = address.InsertTxRelation(
+				chain.Conn, ai, eID, false)
+			if err != nil {
+				return
+			}
+			for nfTkn := range nfTkns {
+				if err = nftoken.InsertTxRelation(
+					chain.Conn, nfTkn, adrTxID); err != nil {
+					return
+				}
+			}
+		}
+	}
+
+	for adr, nfTkns := range tx.Outputs {
+		var ai int64
+		ai, err = address.Add(chain.Conn, &adr, uint64(len(nfTkns)))
+		if err != nil {
+			return
+		}
+		if err = address.InsertBalanceDelta(chain.Conn,
+			ai, eID, int64(len(nfTkns))); err != nil {
+			return
+		}
+		var adrTxID int64
+		adrTxID, err = address.InsertTxRelation(
+			chain.Conn, ai, eID, true)
+		if err != nil {
+			return
+		}
+		for nfID := range nfTkns {
+			if err = nftoken.SetOwner(chain.Conn, nfID, ai); err != nil {
+				return
+			}
+			if err = nftoken.InsertTxRelation(
+				chain.Conn, nfID, adrTxID); err != nil {
+				return
+			}
+		}
+	}
+
+	return
+}
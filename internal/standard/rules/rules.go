@@ -0,0 +1,102 @@
+// MIT License
+//
+// Copyright 2018 Canonical Ledgers, LLC
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+// Package rules provides the Rules interface, which checks and applies the
+// transactions of a FAT token standard to the database of a chain, and a
+// registry of the Rules of each supported standard. The Rules of FAT-0 and
+// FAT-1 are registered by this package.
+//
+// This is kept apart from package standard so that the database, and so CGo,
+// is not required by users of standard.TokenStandard, such as fat-cli.
+package rules
+
+import (
+	"fmt"
+
+	"crawshaw.io/sqlite"
+	"github.com/Factom-Asset-Tokens/factom/fat"
+	"github.com/Factom-Asset-Tokens/fatd/internal/standard"
+)
+
+// Rules checks and applies the transactions of a token standard to the
+// database of a chain.
+type Rules interface {
+	standard.TokenStandard
+
+	// Check returns a txErr if tx cannot be applied to chain. The
+	// database is not modified.
+	Check(chain Chain, tx interface{}) (txErr, err error)
+
+	// Apply applies tx, which is the entry at row id eID, to chain and
+	// returns the number of newly issued tokens. Apply is always called
+	// within a savepoint, which is rolled back if txErr or err is not
+	// nil.
+	Apply(chain Chain, eID int64, tx interface{}) (
+		issued uint64, txErr, err error)
+}
+
+// Chain is the state of a token chain that transactions are checked and
+// applied against.
+type Chain struct {
+	Conn      *sqlite.Conn
+	Issuance  fat.Issuance
+	NumIssued uint64
+}
+
+// checkSupply returns a txErr if issuing add more tokens would exceed the max
+// supply of chain.
+func (chain Chain) checkSupply(add uint64) error {
+	if chain.Issuance.Supply > 0 &&
+		int64(chain.NumIssued+add) > chain.Issuance.Supply {
+		return fmt.Errorf("coinbase exceeds max supply")
+	}
+	return nil
+}
+
+var rules = make(map[fat.Type]Rules)
+
+// Register adds r to the supported Rules. Register panics if Rules are
+// already registered for r.Type(). It must only be called from an init
+// function.
+func Register(r Rules) {
+	if _, ok := rules[r.Type()]; ok {
+		panic(fmt.Errorf("rules already registered: %v", r.Type()))
+	}
+	rules[r.Type()] = r
+}
+
+// Get returns the Rules for typ, if any are registered.
+func Get(typ fat.Type) (Rules, bool) {
+	r, ok := rules[typ]
+	return r, ok
+}
+
+// MustGet returns the Rules for typ and panics if none are registered. The
+// Issuance of a chain is only ever applied if its Rules are registered, so
+// this is safe to use with the Type of any issued chain.
+func MustGet(typ fat.Type) Rules {
+	r, ok := Get(typ)
+	if !ok {
+		panic(fmt.Errorf("invalid FAT Type %v", typ))
+	}
+	return r
+}
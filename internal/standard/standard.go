@@ -0,0 +1,158 @@
+// MIT License
+//
+// Copyright 2018 Canonical Ledgers, LLC
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+// Package standard provides the TokenStandard interface, which describes the
+// transactions of a FAT token standard, and a registry of the supported
+// standards. FAT-0 and FAT-1 are registered by this package.
+//
+// The API, the CLI and the engine look up the TokenStandard for the Issuance
+// Type of a chain instead of handling each standard themselves, so that a new
+// standard only needs to implement TokenStandard, along with rules.Rules to
+// apply its transactions, and call Register.
+//
+// A standard may also add its own tables to the chain databases with
+// CreateTable, which db.NewFATChain and db.OpenFATChain apply alongside the
+// common schema in internal/db.
+package standard
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/Factom-Asset-Tokens/factom"
+	"github.com/Factom-Asset-Tokens/factom/fat"
+	"github.com/Factom-Asset-Tokens/factom/fat1"
+)
+
+// TokenStandard describes the transactions of a FAT token standard.
+//
+// Transactions are passed around as interface{} and must be values returned
+// by NewTransaction, Compose or Coinbase of the same TokenStandard. They are
+// marshaled to JSON as the "data" of API results and the "tx" of events.
+//
+// This package does not depend on the database, so that it may be used by
+// fat-cli.
+type TokenStandard interface {
+	// Type returns the Issuance Type of chains using this standard.
+	Type() fat.Type
+
+	// CreateTable returns a SQL string that creates any tables used only
+	// by this standard, or an empty string if there are none. It is
+	// executed on every chain database each time it is created or
+	// opened, since the standard of a chain is not known until its
+	// Issuance is applied, so it must use IF NOT EXISTS.
+	CreateTable() string
+
+	// Tables returns the names of the tables created by CreateTable. All
+	// rows are deleted from these tables when a chain is replayed.
+	Tables() []string
+
+	// Transaction returns the zero value of the transactions of this
+	// standard, which describes the "data" of transaction RPC results.
+	Transaction() interface{}
+
+	// NFTokens returns true if the tokens of this standard are
+	// non-fungible and identified by a fat1.NFTokenID. Only chains of
+	// such standards support the NFToken RPC methods and params, such as
+	// get-nf-balance, and their balances are described by the
+	// fat1.NFTokens held by an address.
+	NFTokens() bool
+
+	// NewTransaction parses and validates e as a transaction, without
+	// regard to the state of the chain.
+	NewTransaction(e factom.Entry, idKey *factom.Bytes32) (interface{}, error)
+
+	// Compose returns a new unsigned transaction from the JSON of its
+	// fields, as passed to compose-transaction.
+	Compose(inputs, outputs, tokenMetadata,
+		metadata json.RawMessage) (interface{}, error)
+
+	// Coinbase returns tx with a single coinbase input that issues all of
+	// its outputs.
+	Coinbase(tx interface{}) (interface{}, error)
+
+	// Sign returns the entry of tx for chainID, signed by signers.
+	Sign(tx interface{}, chainID *factom.Bytes32,
+		signers ...factom.RCDSigner) (factom.Entry, error)
+
+	// Addresses returns the input and output addresses of tx.
+	Addresses(tx interface{}) (inputs, outputs []factom.FAAddress)
+
+	// InputAmount returns the number of tokens that adr inputs to tx.
+	InputAmount(tx interface{}, adr factom.FAAddress) uint64
+
+	// InputNFTokens returns the NFTokens that adr inputs to tx, which are
+	// nil unless NFTokens returns true.
+	InputNFTokens(tx interface{}, adr factom.FAAddress) fat1.NFTokens
+}
+
+var standards = make(map[fat.Type]TokenStandard)
+
+// Register adds s to the supported standards. Register panics if a standard
+// is already registered for s.Type(). It must only be called from an init
+// function.
+func Register(s TokenStandard) {
+	if _, ok := standards[s.Type()]; ok {
+		panic(fmt.Errorf("standard already registered: %v", s.Type()))
+	}
+	standards[s.Type()] = s
+}
+
+// Get returns the TokenStandard for typ, if one is registered.
+func Get(typ fat.Type) (TokenStandard, bool) {
+	s, ok := standards[typ]
+	return s, ok
+}
+
+// MustGet returns the TokenStandard for typ and panics if none is registered.
+// The Issuance of a chain is only ever applied if its standard is
+// registered, so this is safe to use with the Type of any issued chain.
+func MustGet(typ fat.Type) TokenStandard {
+	s, ok := Get(typ)
+	if !ok {
+		panic(fmt.Errorf("invalid FAT Type %v", typ))
+	}
+	return s
+}
+
+// All returns all registered standards ordered by Type.
+func All() []TokenStandard {
+	all := make([]TokenStandard, 0, len(standards))
+	for _, s := range standards {
+		all = append(all, s)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].Type() < all[j].Type()
+	})
+	return all
+}
+
+// CreateTables returns the concatenated CreateTable SQL strings of all
+// registered standards.
+func CreateTables() string {
+	var sql string
+	for _, s := range All() {
+		sql += s.CreateTable()
+	}
+	return sql
+}
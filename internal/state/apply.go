@@ -26,9 +26,11 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/Factom-Asset-Tokens/factom"
 	"github.com/Factom-Asset-Tokens/factom/fat"
+	"github.com/Factom-Asset-Tokens/fatd/internal/metrics"
 )
 
 func Apply(chain Chain, dbKeyMR *factom.Bytes32, eb factom.EBlock) (err error) {
@@ -41,9 +43,36 @@ func Apply(chain Chain, dbKeyMR *factom.Bytes32, eb factom.EBlock) (err error) {
 	}
 
 	// Insert each entry and attempt to apply it...
+	fatChain, isFATChain := ToFATChain(chain)
+	var numValid, numInvalid int
 	for _, e := range eb.Entries {
-		if _, err := chain.ApplyEntry(e); err != nil {
-			return fmt.Errorf("state.Chain.ApplyEntry(): %w", err)
+		if !isFATChain {
+			if _, err := chain.ApplyEntry(e); err != nil {
+				return fmt.Errorf("state.Chain.ApplyEntry(): %w", err)
+			}
+			continue
+		}
+		// Check for a transaction before e is applied, since it may be
+		// the Issuance.
+		isTx := fatChain.isTx(e)
+		_, valid, err := fatChain.applyEntryValid(e)
+		if err != nil {
+			return fmt.Errorf(
+				"state.FATChain.applyEntryValid(): %w", err)
+		}
+		if !isTx {
+			continue
+		}
+		if valid {
+			numValid++
+		} else {
+			numInvalid++
+		}
+	}
+
+	if isFATChain {
+		if err := fatChain.updateStateHash(); err != nil {
+			return fmt.Errorf("state.FATChain.updateStateHash(): %w", err)
 		}
 	}
 
@@ -51,6 +80,16 @@ func Apply(chain Chain, dbKeyMR *factom.Bytes32, eb factom.EBlock) (err error) {
 		return fmt.Errorf("state.Chain.SetSync(): %w", err)
 	}
 
+	chainID := eb.ChainID.String()
+	metrics.EntriesApplied.WithLabelValues(chainID).
+		Add(float64(len(eb.Entries)))
+	if isFATChain {
+		metrics.Transactions.WithLabelValues(chainID, "true").
+			Add(float64(numValid))
+		metrics.Transactions.WithLabelValues(chainID, "false").
+			Add(float64(numInvalid))
+	}
+
 	return nil
 }
 
@@ -214,7 +253,9 @@ func (state *State) Get(ctx context.Context,
 			factomChain.CloseMtx.RUnlock()
 		}
 	}()
+	start := time.Now()
 	read := factomChain.Pool.Get(ctx)
+	metrics.DBPoolWait.Observe(time.Since(start).Seconds())
 	if read == nil {
 		return nil, nil, ctx.Err()
 	}
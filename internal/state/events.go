@@ -0,0 +1,117 @@
+// MIT License
+//
+// Copyright 2018 Canonical Ledgers, LLC
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package state
+
+import (
+	"fmt"
+
+	"github.com/Factom-Asset-Tokens/factom"
+	"github.com/Factom-Asset-Tokens/fatd/api"
+	"github.com/Factom-Asset-Tokens/fatd/internal/db/entry"
+	"github.com/Factom-Asset-Tokens/fatd/internal/events"
+	"github.com/Factom-Asset-Tokens/fatd/internal/standard"
+)
+
+// publishPending publishes a pending events.Transaction for the Entry e
+// which was just applied at row eID.
+func (chain *FATChain) publishPending(eID int64, e factom.Entry) error {
+	if !chain.isTx(e) || !events.HasSubscribers() {
+		return nil
+	}
+	valid, err := entry.SelectIsValid(chain.Conn, eID)
+	if err != nil {
+		return fmt.Errorf("entry.SelectIsValid(): %w", err)
+	}
+	events.Publish(chain.newTxEvent(e, api.EventStatusPending, valid))
+	return nil
+}
+
+// publishEBlock publishes a confirmed events.Transaction for each Entry in eb,
+// which must already be applied, and a dropped events.Transaction for each
+// Entry in pending that was not included in eb.
+func (chain *FATChain) publishEBlock(eb factom.EBlock,
+	pending map[factom.Bytes32]factom.Entry) error {
+	if !events.HasSubscribers() {
+		return nil
+	}
+
+	confirmed := make(map[factom.Bytes32]struct{}, len(eb.Entries))
+	if eb.IsPopulated() {
+		valid, err := entry.SelectIsValidByEBlock(chain.Conn, eb.Sequence)
+		if err != nil {
+			return fmt.Errorf("entry.SelectIsValidByEBlock(): %w", err)
+		}
+		if len(valid) != len(eb.Entries) {
+			return fmt.Errorf("expected %v entries but found %v",
+				len(eb.Entries), len(valid))
+		}
+		for i, e := range eb.Entries {
+			confirmed[*e.Hash] = struct{}{}
+			if !chain.isTx(e) {
+				continue
+			}
+			events.Publish(chain.newTxEvent(e,
+				api.EventStatusConfirmed, valid[i]))
+		}
+	}
+
+	for hash, e := range pending {
+		if _, ok := confirmed[hash]; ok || !chain.isTx(e) {
+			continue
+		}
+		events.Publish(chain.newTxEvent(e, api.EventStatusDropped, false))
+	}
+	return nil
+}
+
+// isTx returns true if e is a transaction on an issued chain, as opposed to
+// the issuance or an entry prior to issuance.
+func (chain *FATChain) isTx(e factom.Entry) bool {
+	return chain.IsIssued() && *e.Hash != *chain.Issuance.Entry.Hash
+}
+
+func (chain *FATChain) newTxEvent(e factom.Entry,
+	status string, valid bool) events.Transaction {
+
+	ev := events.Transaction{EventTransaction: api.EventTransaction{
+		ChainID:   chain.ID,
+		Hash:      e.Hash,
+		Timestamp: e.Timestamp.Unix(),
+		Status:    status,
+		Valid:     valid,
+	}}
+
+	idKey := (*factom.Bytes32)(chain.Identity.ID1Key)
+	std, ok := standard.Get(chain.Issuance.Type)
+	if !ok {
+		return ev
+	}
+	tx, err := std.NewTransaction(e, idKey)
+	if err != nil {
+		return ev
+	}
+	ev.Tx = tx
+	inputs, outputs := std.Addresses(tx)
+	ev.Addresses = append(inputs, outputs...)
+	return ev
+}
@@ -32,13 +32,12 @@ import (
 	jsonrpc2 "github.com/AdamSLevy/jsonrpc2/v14"
 	"github.com/Factom-Asset-Tokens/factom"
 	"github.com/Factom-Asset-Tokens/factom/fat"
-	"github.com/Factom-Asset-Tokens/factom/fat0"
-	"github.com/Factom-Asset-Tokens/factom/fat1"
 	"github.com/Factom-Asset-Tokens/fatd/internal/db"
-	"github.com/Factom-Asset-Tokens/fatd/internal/db/address"
+	"github.com/Factom-Asset-Tokens/fatd/internal/db/eblock"
 	"github.com/Factom-Asset-Tokens/fatd/internal/db/entry"
 	"github.com/Factom-Asset-Tokens/fatd/internal/db/metadata"
-	"github.com/Factom-Asset-Tokens/fatd/internal/db/nftoken"
+	"github.com/Factom-Asset-Tokens/fatd/internal/db/search"
+	"github.com/Factom-Asset-Tokens/fatd/internal/standard/rules"
 )
 
 type FATChain db.FATChain
@@ -78,35 +77,61 @@ func (chain *FATChain) ToFactomChain() *db.FactomChain {
 }
 
 func (chain *FATChain) ApplyEntry(e factom.Entry) (eID int64, err error) {
+	eID, _, err = chain.applyEntryValid(e)
+	return
+}
+
+// applyEntryValid is ApplyEntry, but also returns whether e is valid.
+func (chain *FATChain) applyEntryValid(e factom.Entry) (
+	eID int64, valid bool, err error) {
 
 	eID, err = (*FactomChain)(&chain.FactomChain).ApplyEntry(e)
 	if err != nil {
 		return
 	}
 
-	var txErr error
 	entryType := "Tx"
-	defer chain.save()(&txErr, &err)
 	if !chain.IsIssued() {
-		txErr, err = chain.ApplyIssuance(eID, e)
 		entryType = "Issuance"
-	} else {
-		_, txErr, err = chain.ApplyTx(eID, e)
+	}
+	txErr, err := chain.applyEntry(eID, e)
+	if err != nil {
+		return
+	}
+	if txErr == nil {
+		valid = true
+		return
 	}
 
-	//if txErr != nil {
-	//	chain.Log.Debugf("Invalid %v: %v %v", entryType, txErr, e.Hash)
-	//} else {
-	//	chain.Log.Debugf("Valid %v: %v", entryType, e.Hash)
-	//}
-	_ = entryType
+	chain.Log.Debugf("Invalid %v: %v %v", entryType, txErr, e.Hash)
+	// Record why the entry is invalid, now that any changes it made have
+	// been rolled back.
+	err = entry.InsertInvalid(chain.Conn, eID, txErr.Error())
+	return
+}
 
+func (chain *FATChain) applyEntry(eID int64, e factom.Entry) (txErr, err error) {
+	defer chain.save()(&txErr, &err)
+	if !chain.IsIssued() {
+		return chain.ApplyIssuance(eID, e)
+	}
+	_, txErr, err = chain.ApplyTx(eID, e)
 	return
 }
+
 func (chain *FATChain) IsIssued() bool {
 	return chain.Issuance.Entry.IsPopulated()
 }
 
+// updateStateHash computes the state hash and saves it with the Head EBlock.
+func (chain *FATChain) updateStateHash() error {
+	hash, err := chain.ToDBFATChain().ComputeStateHash(chain.Head.Sequence)
+	if err != nil {
+		return fmt.Errorf("db.FATChain.ComputeStateHash(): %w", err)
+	}
+	return eblock.SetStateHash(chain.Conn, chain.Head.Sequence, &hash)
+}
+
 func (chain *FATChain) save() func(_, _ *error) {
 	rollback := chain.Save()
 	return func(txErr, err *error) {
@@ -130,10 +155,18 @@ func (chain *FATChain) ApplyIssuance(ei int64, e factom.Entry) (txErr, err error
 	if txErr != nil {
 		return
 	}
+	if _, ok := rules.Get(issuance.Type); !ok {
+		txErr = fmt.Errorf("unsupported FAT Type %v", issuance.Type)
+		return
+	}
 
 	if err = metadata.SetInitEntryID(chain.Conn, ei); err != nil {
 		return
 	}
+	if err = search.InsertIssuance(chain.Conn, chain.TokenID,
+		issuance.Symbol, issuance.Metadata); err != nil {
+		return
+	}
 	chain.Issuance = issuance
 	return
 }
@@ -150,194 +183,28 @@ func (chain *FATChain) ApplyTx(eID int64, e factom.Entry) (tx interface{},
 		return
 	}
 
-	switch chain.Issuance.Type {
-	case fat.TypeFAT0:
-		_, txErr, err = chain.applyFAT0Tx(eID, e)
-	case fat.TypeFAT1:
-		_, txErr, err = chain.applyFAT1Tx(eID, e)
-	default:
-		panic(fmt.Errorf("invalid FAT Type %v", chain.Issuance.Type))
-	}
-
-	if txErr != nil || err != nil {
-		return
-	}
-
-	if err = entry.SetValid(chain.Conn, eID); err != nil {
-		return
-	}
-
-	return
-}
-
-func (chain *FATChain) ApplyFAT0Tx(eID int64, e factom.Entry) (tx fat0.Transaction,
-	txErr, err error) {
-	var txI interface{}
-	txI, txErr, err = chain.ApplyTx(eID, e)
-	tx = txI.(fat0.Transaction)
-	return
-}
-func (chain *FATChain) applyFAT0Tx(eID int64, e factom.Entry) (tx fat0.Transaction,
-	txErr, err error) {
-
-	tx, txErr = fat0.NewTransaction(e, (*factom.Bytes32)(chain.Identity.ID1Key))
+	std := rules.MustGet(chain.Issuance.Type)
+	tx, txErr = std.NewTransaction(e, (*factom.Bytes32)(chain.Identity.ID1Key))
 	if txErr != nil {
 		return
 	}
 
-	if tx.IsCoinbase() {
-		addIssued := tx.Inputs[fat.Coinbase()]
-		if chain.Issuance.Supply > 0 &&
-			int64(chain.NumIssued+addIssued) > chain.Issuance.Supply {
-			txErr = fmt.Errorf("coinbase exceeds max supply")
-			return
-		}
-		if err = chain.ToDBFATChain().AddNumIssued(addIssued); err != nil {
-			return
-		}
-		if _, err = address.InsertTxRelation(
-			chain.Conn, 1, eID, false); err != nil {
-			return
-		}
-	} else {
-		for adr, amount := range tx.Inputs {
-			var ai int64
-			ai, txErr, err = address.Sub(chain.Conn, &adr, amount)
-			if err != nil || txErr != nil {
-				return
-			}
-			if _, err = address.InsertTxRelation(
-				chain.Conn, ai, eID, false); err != nil {
-				return
-			}
-		}
-	}
-
-	for adr, amount := range tx.Outputs {
-		var ai int64
-		ai, err = address.Add(chain.Conn, &adr, amount)
-		if err != nil {
-			return
-		}
-		if _, err = address.InsertTxRelation(
-			chain.Conn, ai, eID, true); err != nil {
-			return
-		}
-	}
-
-	return
-}
-
-func (chain *FATChain) ApplyFAT1Tx(eID int64, e factom.Entry) (tx fat1.Transaction,
-	txErr, err error) {
-	var txI interface{}
-	txI, txErr, err = chain.ApplyTx(eID, e)
-	tx = txI.(fat1.Transaction)
-	return
-}
-func (chain *FATChain) applyFAT1Tx(eID int64, e factom.Entry) (tx fat1.Transaction,
-	txErr, err error) {
-
-	tx, txErr = fat1.NewTransaction(e, (*factom.Bytes32)(chain.Identity.ID1Key))
-	if txErr != nil {
+	issued, txErr, err := std.Apply(rules.Chain{
+		Conn:      chain.Conn,
+		Issuance:  chain.Issuance,
+		NumIssued: chain.NumIssued,
+	}, eID, tx)
+	if txErr != nil || err != nil {
 		return
 	}
-
-	if tx.IsCoinbase() {
-		nfTkns := tx.Inputs[fat.Coinbase()]
-		addIssued := uint64(len(nfTkns))
-		if chain.Issuance.Supply > 0 &&
-			int64(chain.NumIssued+addIssued) > chain.Issuance.Supply {
-			txErr = fmt.Errorf("coinbase exceeds max supply")
+	if issued > 0 {
+		if err = chain.ToDBFATChain().AddNumIssued(issued); err != nil {
 			return
 		}
-		if err = (*db.FATChain)(chain).AddNumIssued(addIssued); err != nil {
-			return
-		}
-		var adrTxID int64
-		adrTxID, err = address.InsertTxRelation(chain.Conn,
-			1, eID, false)
-		if err != nil {
-			return
-		}
-		for nfID := range nfTkns {
-			// Insert the NFToken with the coinbase address as a
-			// placeholder for the owner.
-			txErr, err = nftoken.Insert(chain.Conn, nfID, 1, eID)
-			if err != nil || txErr != nil {
-				return
-			}
-			if err = nftoken.InsertTxRelation(
-				chain.Conn, nfID, adrTxID); err != nil {
-				return
-			}
-			metadata := tx.TokenMetadata[nfID]
-			if len(metadata) == 0 {
-				continue
-			}
-			if err = nftoken.SetMetadata(
-				chain.Conn, nfID, metadata); err != nil {
-				return
-			}
-		}
-	} else {
-		for adr, nfTkns := range tx.Inputs {
-			var ai int64
-			ai, txErr, err = address.Sub(
-				chain.Conn, &adr, uint64(len(nfTkns)))
-			if err != nil || txErr != nil {
-				return
-			}
-			var adrTxID int64
-			adrTxID, err = address.InsertTxRelation(
-				chain.Conn, ai, eID, false)
-			if err != nil {
-				return
-			}
-			for nfTkn := range nfTkns {
-				var ownerID int64
-				ownerID, err = nftoken.SelectOwnerID(chain.Conn, nfTkn)
-				if err != nil {
-					return
-				}
-				if ownerID == -1 {
-					txErr = fmt.Errorf("no such NFToken{%v}", nfTkn)
-					return
-				}
-				if ownerID != ai {
-					txErr = fmt.Errorf("NFToken{%v} not owned by %v",
-						nfTkn, adr)
-					return
-				}
-				if err = nftoken.InsertTxRelation(
-					chain.Conn, nfTkn, adrTxID); err != nil {
-					return
-				}
-			}
-		}
 	}
 
-	for adr, nfTkns := range tx.Outputs {
-		var ai int64
-		ai, err = address.Add(chain.Conn, &adr, uint64(len(nfTkns)))
-		if err != nil {
-			return
-		}
-		var adrTxID int64
-		adrTxID, err = address.InsertTxRelation(
-			chain.Conn, ai, eID, true)
-		if err != nil {
-			return
-		}
-		for nfID := range nfTkns {
-			if err = nftoken.SetOwner(chain.Conn, nfID, ai); err != nil {
-				return
-			}
-			if err = nftoken.InsertTxRelation(
-				chain.Conn, nfID, adrTxID); err != nil {
-				return
-			}
-		}
+	if err = entry.SetValid(chain.Conn, eID); err != nil {
+		return
 	}
 
 	return
@@ -129,6 +129,10 @@ func (state *State) NewParallelChain(ctx context.Context,
 
 	pChain.Lock()
 
+	state.Lock()
+	state.syncing++
+	state.Unlock()
+
 	state.g.Go(func() (err error) {
 
 		defer func() {
@@ -139,6 +143,9 @@ func (state *State) NewParallelChain(ctx context.Context,
 		}()
 
 		pChain.Chain, err = init(state.ctx, state.c, head)
+		state.Lock()
+		state.syncing--
+		state.Unlock()
 		if err != nil {
 			state.Log.Debugf("NewParallelChain(): init(): %v %v",
 				head.ChainID, err)
@@ -205,7 +212,9 @@ func (chain *ParallelChain) processEBlock(state *State, eb dbKeyMREBlock) error
 	defer chain.Unlock()
 
 	// Rollback any pending entries on the chain.
+	var pendingEntries map[factom.Bytes32]factom.Entry
 	if pending, ok := ToPendingChain(chain.Chain); ok {
+		pendingEntries = pending.Entries
 		pending.LoadFromCache(&eb.EBlock)
 		var err error
 		chain.Chain, err = pending.Revert()
@@ -222,7 +231,7 @@ func (chain *ParallelChain) processEBlock(state *State, eb dbKeyMREBlock) error
 				err)
 
 		}
-		return nil
+		return chain.publishEBlock(eb.EBlock, pendingEntries)
 	}
 
 	if err := eb.GetEntries(state.ctx, state.c); err != nil {
@@ -236,6 +245,10 @@ func (chain *ParallelChain) processEBlock(state *State, eb dbKeyMREBlock) error
 		return fmt.Errorf("state.Apply(): %w", err)
 	}
 
+	if err := chain.publishEBlock(eb.EBlock, pendingEntries); err != nil {
+		return err
+	}
+
 	if err := sqlitex.ExecScript(chain.ToFactomChain().Conn,
 		`PRAGMA main.wal_checkpoint;`); err != nil {
 		chain.ToFactomChain().Log.Error(err)
@@ -254,6 +267,21 @@ func (chain *ParallelChain) processEBlock(state *State, eb dbKeyMREBlock) error
 
 	return nil
 }
+
+// publishEBlock publishes events for eb and any pendingEntries that were not
+// included in it, if the underlying Chain is a FATChain.
+func (chain *ParallelChain) publishEBlock(eb factom.EBlock,
+	pendingEntries map[factom.Bytes32]factom.Entry) error {
+	fatChain, ok := ToFATChain(chain.Chain)
+	if !ok {
+		return nil
+	}
+	if err := fatChain.publishEBlock(eb, pendingEntries); err != nil {
+		return fmt.Errorf("state.FATChain.publishEBlock(): %w", err)
+	}
+	return nil
+}
+
 func (chain *ParallelChain) processPending(state *State, es []factom.Entry) error {
 
 	chain.Lock()
@@ -7,8 +7,12 @@ import (
 	"time"
 
 	"crawshaw.io/sqlite"
+	"crawshaw.io/sqlite/sqlitex"
 	"github.com/Factom-Asset-Tokens/factom"
+	"github.com/Factom-Asset-Tokens/factom/fat1"
 	"github.com/Factom-Asset-Tokens/fatd/internal/db"
+	"github.com/Factom-Asset-Tokens/fatd/internal/db/search"
+	"github.com/Factom-Asset-Tokens/fatd/internal/metrics"
 )
 
 type PendingChain struct {
@@ -55,7 +59,7 @@ func NewPendingChain(ctx context.Context, c *factom.Client, chain Chain) (
 			session.Delete()
 		}
 	}()
-	if err := session.Attach(""); err != nil {
+	if err := attachTables(factomChain.Conn, session); err != nil {
 		return nil, err
 	}
 
@@ -92,13 +96,22 @@ func (pending *PendingChain) ApplyPendingEntries(es []factom.Entry) error {
 		// use the current time for now.
 		e.Timestamp = time.Now()
 
-		if _, err := pending.Chain.ApplyEntry(e); err != nil {
+		eID, err := pending.Chain.ApplyEntry(e)
+		if err != nil {
 			return fmt.Errorf("state.Chain.ApplyEntry(): %w", err)
 		}
+		if fatChain, ok := ToFATChain(pending.Chain); ok {
+			if err := fatChain.publishPending(eID, e); err != nil {
+				return fmt.Errorf(
+					"state.FATChain.publishPending(): %w", err)
+			}
+		}
 
 		// Cache the entry.
 		pending.Entries[*e.Hash] = e
 	}
+	metrics.PendingEntries.WithLabelValues(pending.ToFactomChain().ID.String()).
+		Set(float64(len(pending.Entries)))
 
 	return nil
 }
@@ -126,6 +139,8 @@ func (pending *PendingChain) Revert() (Chain, error) {
 	// interrupted while reverting.
 	oldDone := factomChain.Conn.SetInterrupt(nil)
 	defer func() {
+		metrics.PendingEntries.WithLabelValues(
+			factomChain.ID.String()).Set(0)
 		pending.Session.Delete()
 		factomChain.Conn.SetInterrupt(oldDone)
 		factomChain.CloseMtx.RUnlock()
@@ -136,6 +151,10 @@ func (pending *PendingChain) Revert() (Chain, error) {
 	if err := pending.Session.Changeset(&changeset); err != nil {
 		return nil, fmt.Errorf("sqlite.Session.Changeset(): %w", err)
 	}
+	nfIDs, issuance, err := searchChanges(changeset.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("state.searchChanges(): %w", err)
+	}
 	inverse := bytes.NewBuffer(make([]byte, 0, changeset.Len()))
 	if err := sqlite.ChangesetInvert(inverse, &changeset); err != nil {
 		return nil, fmt.Errorf("sqlite.ChangesetInvert(): %w", err)
@@ -144,9 +163,83 @@ func (pending *PendingChain) Revert() (Chain, error) {
 		inverse, nil, conflictFn(factomChain)); err != nil {
 		return nil, fmt.Errorf("sqlite.Conn.ChangesetApply(): %w", err)
 	}
+	// The search index is not tracked by the session, so the entries
+	// for any reverted NFTokens or Issuance must be brought back in line
+	// with the reverted state.
+	if err := search.SyncNFTokens(factomChain.Conn, nfIDs); err != nil {
+		return nil, fmt.Errorf("search.SyncNFTokens(): %w", err)
+	}
+	if issuance {
+		if err := search.SyncIssuance(factomChain.Conn); err != nil {
+			return nil, fmt.Errorf("search.SyncIssuance(): %w", err)
+		}
+	}
 
 	return pending.OfficialState, nil
 }
+
+// searchChanges returns the IDs of all NFTokens changed in changeset, and
+// whether the "fat_chain" table, which holds the Issuance, was changed.
+func searchChanges(changeset []byte) (
+	nfIDs []fat1.NFTokenID, issuance bool, err error) {
+	iter, err := sqlite.ChangesetIterStart(bytes.NewReader(changeset))
+	if err != nil {
+		return nil, false, err
+	}
+	defer iter.Finalize()
+	for {
+		hasRow, err := iter.Next()
+		if err != nil {
+			return nil, false, err
+		}
+		if !hasRow {
+			return nfIDs, issuance, nil
+		}
+		table, _, op, _, err := iter.Op()
+		if err != nil {
+			return nil, false, err
+		}
+		switch table {
+		case "fat_chain":
+			issuance = true
+		case "nftoken":
+			// The primary key is always in the new values of an
+			// INSERT, and the old values of an UPDATE or DELETE.
+			get := iter.Old
+			if op == sqlite.SQLITE_INSERT {
+				get = iter.New
+			}
+			id, err := get(0)
+			if err != nil {
+				return nil, false, err
+			}
+			nfIDs = append(nfIDs, fat1.NFTokenID(id.Int64()))
+		}
+	}
+}
+
+// attachTables attaches all tables to the session, except for the search
+// index tables, which are corrupted if reverted by an inverted changeset.
+func attachTables(conn *sqlite.Conn, session *sqlite.Session) error {
+	var names []string
+	err := sqlitex.ExecTransient(conn, `SELECT "name" FROM "sqlite_master"
+                WHERE "type" = 'table';`,
+		func(stmt *sqlite.Stmt) error {
+			if name := stmt.ColumnText(0); !search.IsIndexTable(name) {
+				names = append(names, name)
+			}
+			return nil
+		})
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if err := session.Attach(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 func conflictFn(chain *db.FactomChain) func(
 	sqlite.ConflictType, sqlite.ChangesetIter) sqlite.ConflictAction {
 
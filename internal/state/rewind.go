@@ -0,0 +1,181 @@
+// MIT License
+//
+// Copyright 2018 Canonical Ledgers, LLC
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package state
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/Factom-Asset-Tokens/factom"
+	"github.com/Factom-Asset-Tokens/fatd/internal/db"
+	"github.com/Factom-Asset-Tokens/fatd/internal/db/eblock"
+	"github.com/nightlyone/lockfile"
+)
+
+// Rewind the chain to its state as of the DBlock at the given height. All
+// EBlocks and Entries above height are deleted and the remaining ones are
+// replayed to recompute all balances, NFToken ownership, and the number of
+// issued tokens.
+//
+// The sync height is reset to the height of the last remaining EBlock, since
+// the KeyMRs of any later DBlocks are not known. Thus, on the next sync, any
+// later EBlocks will be downloaded again from factomd.
+func (chain *FATChain) Rewind(ctx context.Context, height uint32) (err error) {
+	read := chain.Pool.Get(ctx)
+	if read == nil {
+		return ctx.Err()
+	}
+	defer chain.Pool.Put(read)
+
+	first, err := eblock.SelectBySequence(read, 0)
+	if err != nil {
+		return err
+	}
+	if !first.IsPopulated() {
+		return fmt.Errorf("no eblocks")
+	}
+	if height < first.Height {
+		return fmt.Errorf("height %v is before the first EBlock at %v",
+			height, first.Height)
+	}
+
+	chain.Log.Infof("Rewinding to height %v...", height)
+	defer chain.Save()(&err)
+	if err := chain.replay(ctx, read, height); err != nil {
+		return err
+	}
+	chain.HeadDBKeyMR = chain.SyncDBKeyMR
+	chain.Log.Infof("Rewound to EBlock %v at height %v.",
+		chain.Head.Sequence, chain.Head.Height)
+	return nil
+}
+
+// RewindDB rewinds the database for chainID in dbPath to the given height. This
+// must not be called while the engine is running, which is enforced by
+// locking dbPath. The chain is resynced from factomd the next time the engine
+// starts.
+func RewindDB(ctx context.Context, dbPath string,
+	chainID *factom.Bytes32, height uint32) (err error) {
+	lockFile, err := lockDBPath(dbPath)
+	if err != nil {
+		return err
+	}
+	defer lockFile.Unlock()
+
+	fname := db.FileName(chainID)
+	if _, err := os.Stat(dbPath + fname); err != nil {
+		return fmt.Errorf("os.Stat(): %w", err)
+	}
+	dbChain, err := db.OpenFATChain(ctx, dbPath, fname)
+	if err != nil {
+		return fmt.Errorf("db.OpenFATChain(): %w", err)
+	}
+	chain := FATChain(dbChain)
+	defer chain.Close()
+
+	return chain.Rewind(ctx, height)
+}
+
+// Rewind the tracked chain with chainID to the given height and then resync
+// it from factomd back up to its prior sync height. This is safe to call
+// while the engine is running.
+func (state *State) Rewind(ctx context.Context,
+	chainID *factom.Bytes32, height uint32) error {
+	chain, _ := state.get(chainID)
+	if chain == nil {
+		return fmt.Errorf("chain not tracked: %v", chainID)
+	}
+	return ToParallelChain(chain).rewind(ctx, state.c, height)
+}
+
+func (chain *ParallelChain) rewind(ctx context.Context,
+	c *factom.Client, height uint32) (err error) {
+
+	// Holding the lock prevents any EBlocks or pending entries from being
+	// processed until we are done.
+	chain.Lock()
+	defer chain.Unlock()
+
+	// Rollback any pending entries on the chain. They will be re-applied
+	// on the next scan for pending entries.
+	if pending, ok := ToPendingChain(chain.Chain); ok {
+		chain.Chain, err = pending.Revert()
+		if err != nil {
+			return fmt.Errorf("state.PendingChain.Revert(): %w", err)
+		}
+	}
+
+	fatChain, ok := ToFATChain(chain.Chain)
+	if !ok {
+		return fmt.Errorf("not a FAT chain")
+	}
+	if height >= fatChain.SyncHeight {
+		return fmt.Errorf("height %v is not below the sync height %v",
+			height, fatChain.SyncHeight)
+	}
+
+	// Remember the current head so that the EBlocks removed by the rewind
+	// can be downloaded again from factomd, starting from its KeyMR, and
+	// re-applied from a clean state.
+	head := factom.EBlock{ChainID: fatChain.ID, KeyMR: fatChain.Head.KeyMR}
+	headSeq := fatChain.Head.Sequence
+	syncHeight, syncDBKeyMR := fatChain.SyncHeight, fatChain.SyncDBKeyMR
+
+	// Roll back the rewind if we fail to resync.
+	defer fatChain.Save()(&err)
+
+	if err := fatChain.Rewind(ctx, height); err != nil {
+		return fmt.Errorf("state.FATChain.Rewind(): %w", err)
+	}
+
+	// The engine will not send us the EBlocks that we just removed again,
+	// so download them and re-apply them now.
+	eblocks, err := head.GetPrevN(ctx, c, headSeq-fatChain.Head.Sequence)
+	if err != nil {
+		return fmt.Errorf("factom.EBlock.GetPrevN(): %w", err)
+	}
+	if err := SyncEBlocks(ctx, c, fatChain, eblocks); err != nil {
+		return fmt.Errorf("state.SyncEBlocks(): %w", err)
+	}
+	if err := fatChain.SetSync(syncHeight, syncDBKeyMR); err != nil {
+		return fmt.Errorf("state.FATChain.SetSync(): %w", err)
+	}
+	return nil
+}
+
+// lockDBPath locks dbPath so that the engine cannot be started while the
+// databases are in use.
+func lockDBPath(dbPath string) (lockfile.Lockfile, error) {
+	lockFilePath := dbPath + "db.lock"
+	lockFile, err := lockfile.New(lockFilePath)
+	if err != nil {
+		return lockFile, fmt.Errorf("lockfile.New(%q): %w",
+			lockFilePath, err)
+	}
+	if err := lockFile.TryLock(); err != nil {
+		return lockFile, fmt.Errorf("lockfile.Lockfile.TryLock(): %w",
+			err)
+	}
+	return lockFile, nil
+}
@@ -0,0 +1,487 @@
+// MIT License
+//
+// Copyright 2018 Canonical Ledgers, LLC
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package state
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Factom-Asset-Tokens/factom"
+	"github.com/Factom-Asset-Tokens/fatd/internal/db"
+	"github.com/Factom-Asset-Tokens/fatd/internal/flag"
+	"github.com/Factom-Asset-Tokens/fatd/internal/log"
+)
+
+// SnapshotVersion is the version of the snapshot archive format.
+const SnapshotVersion = 1
+
+// Names of the entries in a snapshot archive. The manifest and its signature
+// must be the first two entries, followed by the database of each chain in
+// the manifest under snapshotChainsDir.
+const (
+	snapshotManifest  = "manifest.json"
+	snapshotSignature = "manifest.sig"
+	snapshotChainsDir = "chains/"
+)
+
+// SnapshotManifest describes the contents of a snapshot archive. The manifest
+// is signed, and holds the SHA256 checksum of each database, so the whole
+// archive may be verified.
+type SnapshotManifest struct {
+	Version     uint             `json:"version"`
+	FatdVersion string           `json:"fatdversion"`
+	NetworkID   factom.NetworkID `json:"networkid"`
+	Created     int64            `json:"created"`
+
+	// SyncHeight is the lowest sync height of all chains, which is the
+	// height that fatd resumes syncing from after an import.
+	SyncHeight  uint32          `json:"syncheight"`
+	SyncDBKeyMR *factom.Bytes32 `json:"syncdbkeymr"`
+
+	Chains []SnapshotChain `json:"chains"`
+}
+
+// SnapshotChain describes the database of a single chain in a snapshot.
+type SnapshotChain struct {
+	ChainID     *factom.Bytes32 `json:"chainid"`
+	TokenID     string          `json:"tokenid"`
+	IssuerID    *factom.Bytes32 `json:"issuerid"`
+	SyncHeight  uint32          `json:"syncheight"`
+	SyncDBKeyMR *factom.Bytes32 `json:"syncdbkeymr"`
+	Size        int64           `json:"size"`
+	SHA256      factom.Bytes32  `json:"sha256"`
+}
+
+// SnapshotSignature is the signature of the manifest by the SK1 key of the
+// creator of a snapshot.
+type SnapshotSignature struct {
+	RCD       factom.Bytes `json:"rcd"`
+	Signature factom.Bytes `json:"signature"`
+}
+
+// ExportSnapshot writes a snapshot archive of all chain databases in dbPath to
+// the file out, signed by sk1. This must not be called while the engine is
+// running, which is enforced by locking dbPath.
+func ExportSnapshot(ctx context.Context, dbPath, out string,
+	sk1 factom.SK1Key) (err error) {
+	log := log.New("pkg", "state")
+	lockFile, err := lockDBPath(dbPath)
+	if err != nil {
+		return err
+	}
+	defer lockFile.Unlock()
+
+	chains, err := db.OpenAllFATChains(ctx, dbPath)
+	if err != nil {
+		return fmt.Errorf("db.OpenAllFATChains(): %w", err)
+	}
+	defer func() {
+		for _, chain := range chains {
+			chain.Close()
+		}
+	}()
+	if len(chains) == 0 {
+		return fmt.Errorf("no chains in %q", dbPath)
+	}
+
+	// Back up each database so that the archive holds a single consistent
+	// file for each chain, regardless of any WAL.
+	tmp, err := ioutil.TempDir(filepath.Dir(out), ".fatd-snapshot-")
+	if err != nil {
+		return fmt.Errorf("ioutil.TempDir(): %w", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	m := SnapshotManifest{
+		Version:     SnapshotVersion,
+		FatdVersion: flag.Revision,
+		NetworkID:   chains[0].NetworkID,
+		Created:     time.Now().Unix(),
+		SyncHeight:  chains[0].SyncHeight,
+		SyncDBKeyMR: chains[0].SyncDBKeyMR,
+		Chains:      make([]SnapshotChain, len(chains)),
+	}
+	for i, chain := range chains {
+		if chain.SyncHeight < m.SyncHeight {
+			m.SyncHeight = chain.SyncHeight
+			m.SyncDBKeyMR = chain.SyncDBKeyMR
+		}
+		chain.Log.Info("Exporting...")
+		fname := filepath.Join(tmp, chain.DBFile)
+		backup, err := chain.Conn.BackupToDB("main", fname)
+		if err != nil {
+			return fmt.Errorf("sqlite.Conn.BackupToDB(): %w", err)
+		}
+		if err := backup.Close(); err != nil {
+			return fmt.Errorf("sqlite.Conn.Close(): %w", err)
+		}
+		size, hash, err := sha256File(fname)
+		if err != nil {
+			return err
+		}
+		m.Chains[i] = SnapshotChain{
+			ChainID:     chain.ID,
+			TokenID:     chain.TokenID,
+			IssuerID:    chain.IssuerChainID,
+			SyncHeight:  chain.SyncHeight,
+			SyncDBKeyMR: chain.SyncDBKeyMR,
+			Size:        size,
+			SHA256:      hash,
+		}
+	}
+
+	manifest, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	sig, err := json.Marshal(SnapshotSignature{
+		RCD:       factom.Bytes(sk1.RCD()),
+		Signature: sk1.Sign(manifest),
+	})
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so that out is never left
+	// incomplete.
+	f, err := ioutil.TempFile(tmp, "archive-")
+	if err != nil {
+		return fmt.Errorf("ioutil.TempFile(): %w", err)
+	}
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	if err := writeTarFile(tw, snapshotManifest, manifest); err != nil {
+		return err
+	}
+	if err := writeTarFile(tw, snapshotSignature, sig); err != nil {
+		return err
+	}
+	for _, chain := range chains {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := copyToTar(tw, snapshotChainsDir+chain.DBFile,
+			filepath.Join(tmp, chain.DBFile)); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("tar.Writer.Close(): %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("gzip.Writer.Close(): %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(f.Name(), out); err != nil {
+		return err
+	}
+	log.Infof("Exported %v chains synced to height %v to %q.",
+		len(m.Chains), m.SyncHeight, out)
+	return nil
+}
+
+// ImportSnapshot extracts the snapshot archive in into dbPath, which must not
+// have any chain databases. The archive must be signed by the SK1 key for
+// id1, and be for networkID. Each database is checked against the manifest,
+// and if validate is true, fully validated, before any are moved into dbPath.
+// This must not be called while the engine is running, which is enforced by
+// locking dbPath. The engine resumes syncing from the snapshot height the next
+// time it is started.
+func ImportSnapshot(ctx context.Context, dbPath, in string,
+	id1 factom.ID1Key, networkID factom.NetworkID, validate bool) (err error) {
+	if err := os.MkdirAll(dbPath, 0755); err != nil {
+		return fmt.Errorf("os.MkdirAll(%q): %w", dbPath, err)
+	}
+	log := log.New("pkg", "state")
+	lockFile, err := lockDBPath(dbPath)
+	if err != nil {
+		return err
+	}
+	defer lockFile.Unlock()
+
+	existing, err := filepath.Glob(dbPath + "*.sqlite3")
+	if err != nil {
+		return err
+	}
+	if len(existing) > 0 {
+		return fmt.Errorf("%q already has chain databases", dbPath)
+	}
+
+	f, err := os.Open(in)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("gzip.NewReader(): %w", err)
+	}
+	tr := tar.NewReader(gz)
+
+	m, err := readSnapshotManifest(tr, id1)
+	if err != nil {
+		return err
+	}
+	if m.NetworkID != networkID {
+		return fmt.Errorf("snapshot is for %v, not %v",
+			m.NetworkID, networkID)
+	}
+	log.Infof("Importing %v chains synced to height %v, created %v...",
+		len(m.Chains), m.SyncHeight, time.Unix(m.Created, 0))
+
+	chains := make(map[string]SnapshotChain, len(m.Chains))
+	for _, chain := range m.Chains {
+		chains[snapshotChainsDir+db.FileName(chain.ChainID)] = chain
+	}
+
+	tmp, err := ioutil.TempDir(dbPath, "snapshot-")
+	if err != nil {
+		return fmt.Errorf("ioutil.TempDir(): %w", err)
+	}
+	defer os.RemoveAll(tmp)
+	tmp += string(os.PathSeparator)
+
+	// Extract and check the checksum of each database.
+	for range m.Chains {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		hdr, err := tr.Next()
+		if err != nil {
+			return fmt.Errorf("tar.Reader.Next(): %w", err)
+		}
+		chain, ok := chains[hdr.Name]
+		if !ok {
+			return fmt.Errorf("unexpected file: %q", hdr.Name)
+		}
+		delete(chains, hdr.Name)
+		fname := tmp + db.FileName(chain.ChainID)
+		if err := extractFile(tr, fname, chain.Size,
+			chain.SHA256); err != nil {
+			return fmt.Errorf("%v: %w", hdr.Name, err)
+		}
+	}
+	if _, err := tr.Next(); err != io.EOF {
+		return fmt.Errorf("unexpected data after the last chain")
+	}
+
+	// Check that each database matches the manifest.
+	for _, chain := range m.Chains {
+		if err := checkSnapshotChain(ctx, tmp, chain, networkID,
+			validate); err != nil {
+			return err
+		}
+	}
+
+	for _, chain := range m.Chains {
+		fname := db.FileName(chain.ChainID)
+		if err := os.Rename(tmp+fname, dbPath+fname); err != nil {
+			return err
+		}
+	}
+	log.Infof("Imported %v chains. Syncing will resume from height %v.",
+		len(m.Chains), m.SyncHeight)
+	return nil
+}
+
+// readSnapshotManifest reads the manifest and its signature from tr, and
+// verifies that it is signed by id1.
+func readSnapshotManifest(tr *tar.Reader,
+	id1 factom.ID1Key) (m SnapshotManifest, err error) {
+	manifest, err := readTarFile(tr, snapshotManifest, 1<<26)
+	if err != nil {
+		return m, err
+	}
+	data, err := readTarFile(tr, snapshotSignature, 1<<10)
+	if err != nil {
+		return m, err
+	}
+	var sig SnapshotSignature
+	if err := json.Unmarshal(data, &sig); err != nil {
+		return m, fmt.Errorf("%v: %w", snapshotSignature, err)
+	}
+	rcd := factom.RCD(sig.RCD)
+	if rcd.Hash() != factom.Bytes32(id1) {
+		return m, fmt.Errorf("snapshot is not signed by %v", id1)
+	}
+	if err := rcd.Validate(sig.Signature, manifest,
+		factom.RCDType01); err != nil {
+		return m, fmt.Errorf("invalid signature: %w", err)
+	}
+
+	if err := json.Unmarshal(manifest, &m); err != nil {
+		return m, fmt.Errorf("%v: %w", snapshotManifest, err)
+	}
+	if m.Version != SnapshotVersion {
+		return m, fmt.Errorf("unsupported snapshot version: %v",
+			m.Version)
+	}
+	if len(m.Chains) == 0 {
+		return m, fmt.Errorf("snapshot has no chains")
+	}
+	for _, chain := range m.Chains {
+		if chain.ChainID == nil {
+			return m, fmt.Errorf("%v: missing chainid",
+				snapshotManifest)
+		}
+	}
+	return m, nil
+}
+
+// checkSnapshotChain opens the database for chain in dbPath and checks that
+// it matches the manifest. If validate is true, the database is also fully
+// validated.
+func checkSnapshotChain(ctx context.Context, dbPath string,
+	chain SnapshotChain, networkID factom.NetworkID, validate bool) error {
+	dbChain, err := db.OpenFATChain(ctx, dbPath, db.FileName(chain.ChainID))
+	if err != nil {
+		return fmt.Errorf("db.OpenFATChain(): %w", err)
+	}
+	fatChain := FATChain(dbChain)
+	defer fatChain.Close()
+	if *fatChain.ID != *chain.ChainID ||
+		fatChain.NetworkID != networkID ||
+		fatChain.SyncHeight != chain.SyncHeight ||
+		chain.SyncDBKeyMR == nil ||
+		*fatChain.SyncDBKeyMR != *chain.SyncDBKeyMR {
+		return fmt.Errorf("chain %v does not match the manifest",
+			chain.ChainID)
+	}
+	if validate {
+		if err := fatChain.Validate(ctx, false); err != nil {
+			return fmt.Errorf("state.FATChain.Validate(): %w", err)
+		}
+	}
+	return nil
+}
+
+func sha256File(fname string) (size int64, hash factom.Bytes32, err error) {
+	f, err := os.Open(fname)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	h := sha256.New()
+	if size, err = io.Copy(h, f); err != nil {
+		return
+	}
+	copy(hash[:], h.Sum(nil))
+	return
+}
+
+func writeTarFile(tw *tar.Writer, name string, data []byte) error {
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: time.Now(),
+	}); err != nil {
+		return fmt.Errorf("tar.Writer.WriteHeader(): %w", err)
+	}
+	if _, err := tw.Write(data); err != nil {
+		return fmt.Errorf("tar.Writer.Write(): %w", err)
+	}
+	return nil
+}
+
+func copyToTar(tw *tar.Writer, name, fname string) error {
+	f, err := os.Open(fname)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+	}); err != nil {
+		return fmt.Errorf("tar.Writer.WriteHeader(): %w", err)
+	}
+	if _, err := io.Copy(tw, f); err != nil {
+		return fmt.Errorf("io.Copy(): %w", err)
+	}
+	return nil
+}
+
+// readTarFile reads the next file in tr, which must have the given name and be
+// no larger than max.
+func readTarFile(tr *tar.Reader, name string, max int64) ([]byte, error) {
+	hdr, err := tr.Next()
+	if err != nil {
+		return nil, fmt.Errorf("tar.Reader.Next(): %w", err)
+	}
+	if hdr.Name != name {
+		return nil, fmt.Errorf("expected %q but found %q",
+			name, hdr.Name)
+	}
+	if hdr.Size > max {
+		return nil, fmt.Errorf("%v: too large", name)
+	}
+	return ioutil.ReadAll(io.LimitReader(tr, max))
+}
+
+// extractFile writes the next file in tr to fname, and checks that it has the
+// given size and SHA256 hash.
+func extractFile(tr *tar.Reader, fname string,
+	size int64, hash factom.Bytes32) (err error) {
+	f, err := os.OpenFile(fname, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if e := f.Close(); err == nil {
+			err = e
+		}
+	}()
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(f, h), io.LimitReader(tr, size+1))
+	if err != nil {
+		return err
+	}
+	if n != size {
+		return fmt.Errorf("invalid size")
+	}
+	var sum factom.Bytes32
+	copy(sum[:], h.Sum(nil))
+	if sum != hash {
+		return fmt.Errorf("invalid checksum")
+	}
+	return nil
+}
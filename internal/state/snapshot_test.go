@@ -0,0 +1,198 @@
+// MIT License
+//
+// Copyright 2018 Canonical Ledgers, LLC
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package state
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/Factom-Asset-Tokens/factom"
+	"github.com/Factom-Asset-Tokens/fatd/internal/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshot(t *testing.T) {
+	ctx := context.Background()
+
+	dir, err := ioutil.TempDir("", "fatd-snapshot-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	src := dir + "/src/"
+	require.NoError(t,
+		exec.Command("cp", "-r", "./test-fatd.db", src).Run())
+
+	sk1, err := factom.GenerateSK1Key()
+	require.NoError(t, err)
+	archive := dir + "/snapshot.tar.gz"
+	require.NoError(t, ExportSnapshot(ctx, src, archive, sk1))
+
+	var m SnapshotManifest
+	rewriteSnapshot(t, archive, "", func(name string, data []byte) (
+		string, []byte) {
+		if name == snapshotManifest {
+			require.NoError(t, json.Unmarshal(data, &m))
+		}
+		return name, data
+	})
+	require.NotEmpty(t, m.Chains)
+
+	// dbPath returns a new database directory.
+	var n int
+	dbPath := func() string {
+		n++
+		return fmt.Sprintf("%v/dst-%v/", dir, n)
+	}
+
+	t.Run("round trip", func(t *testing.T) {
+		require := require.New(t)
+		dbPath := dbPath()
+		require.NoError(ImportSnapshot(ctx, dbPath, archive,
+			sk1.ID1Key(), m.NetworkID, true))
+		chains, err := db.OpenAllFATChains(ctx, dbPath)
+		require.NoError(err)
+		defer func() {
+			for _, chain := range chains {
+				chain.Close()
+			}
+		}()
+		require.Len(chains, len(m.Chains))
+		for i, chain := range chains {
+			assert.Equal(t, *m.Chains[i].ChainID, *chain.ID)
+			assert.Equal(t, m.Chains[i].SyncHeight,
+				chain.SyncHeight)
+		}
+
+		// The database directory must not have any chains.
+		assert.Error(t, ImportSnapshot(ctx, dbPath, archive,
+			sk1.ID1Key(), m.NetworkID, false))
+	})
+
+	chainFile := snapshotChainsDir + db.FileName(m.Chains[0].ChainID)
+	for _, test := range []struct {
+		Name      string
+		ID1       factom.ID1Key
+		NetworkID factom.NetworkID
+		Edit      func(name string, data []byte) (string, []byte)
+		Error     string
+	}{{
+		Name:  "wrong signer",
+		ID1:   factom.ID1Key{1},
+		Error: "not signed by",
+	}, {
+		Name:      "wrong network",
+		NetworkID: factom.NetworkID{1, 2, 3, 4},
+		Error:     "snapshot is for",
+	}, {
+		Name: "checksum mismatch",
+		Edit: func(name string, data []byte) (string, []byte) {
+			if name == chainFile {
+				data[len(data)-1]++
+			}
+			return name, data
+		},
+		Error: "invalid checksum",
+	}, {
+		Name: "unexpected file",
+		Edit: func(name string, data []byte) (string, []byte) {
+			if name == chainFile {
+				name = snapshotChainsDir + "other.sqlite3"
+			}
+			return name, data
+		},
+		Error: "unexpected file",
+	}} {
+		t.Run(test.Name, func(t *testing.T) {
+			require := require.New(t)
+			in := archive
+			if test.Edit != nil {
+				in = dir + "/edited.tar.gz"
+				rewriteSnapshot(t, archive, in, test.Edit)
+			}
+			id1 := sk1.ID1Key()
+			if test.ID1 != (factom.ID1Key{}) {
+				id1 = test.ID1
+			}
+			networkID := m.NetworkID
+			if test.NetworkID != (factom.NetworkID{}) {
+				networkID = test.NetworkID
+			}
+			dbPath := dbPath()
+			err := ImportSnapshot(ctx, dbPath, in, id1, networkID,
+				false)
+			require.Error(err)
+			assert.Contains(t, err.Error(), test.Error)
+
+			// Nothing may be imported if any check fails.
+			imported, err := filepath.Glob(dbPath + "*.sqlite3")
+			require.NoError(err)
+			assert.Empty(t, imported)
+		})
+	}
+}
+
+// rewriteSnapshot passes the name and contents of each file in the snapshot
+// archive in to edit, and if out is not empty, writes the results to out.
+func rewriteSnapshot(t *testing.T, in, out string,
+	edit func(name string, data []byte) (string, []byte)) {
+	require := require.New(t)
+	f, err := os.Open(in)
+	require.NoError(err)
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	require.NoError(err)
+	tr := tar.NewReader(gz)
+
+	var tw *tar.Writer
+	if len(out) > 0 {
+		f, err := os.Create(out)
+		require.NoError(err)
+		defer f.Close()
+		gz := gzip.NewWriter(f)
+		defer func() { require.NoError(gz.Close()) }()
+		tw = tar.NewWriter(gz)
+		defer func() { require.NoError(tw.Close()) }()
+	}
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return
+		}
+		require.NoError(err)
+		data, err := ioutil.ReadAll(tr)
+		require.NoError(err)
+		name, data := edit(hdr.Name, data)
+		if tw != nil {
+			require.NoError(writeTarFile(tw, name, data))
+		}
+	}
+}
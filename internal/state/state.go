@@ -41,6 +41,7 @@ type State struct {
 	Chains     map[factom.Bytes32]Chain
 	issuedIDs  []*factom.Bytes32
 	trackedIDs []*factom.Bytes32
+	syncing    int
 	sync.RWMutex
 
 	DBPath    string
@@ -99,6 +100,14 @@ func (state *State) TrackedIDs() []*factom.Bytes32 {
 	return state.trackedIDs
 }
 
+// Syncing returns the number of chains that are still completing their
+// initial sync.
+func (state *State) Syncing() int {
+	state.RLock()
+	defer state.RUnlock()
+	return state.syncing
+}
+
 func (state *State) SetSync(ctx context.Context,
 	height uint32, dbKeyMR *factom.Bytes32) error {
 
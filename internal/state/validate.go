@@ -26,6 +26,7 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"math"
 	"os"
 	"time"
 
@@ -37,6 +38,7 @@ import (
 	"github.com/Factom-Asset-Tokens/fatd/internal/db/eblock"
 	"github.com/Factom-Asset-Tokens/fatd/internal/db/entry"
 	"github.com/Factom-Asset-Tokens/fatd/internal/flag"
+	"github.com/Factom-Asset-Tokens/fatd/internal/standard"
 )
 
 func init() {
@@ -44,7 +46,8 @@ func init() {
 }
 
 // Validate all Entry Hashes and EBlock KeyMRs, as well as the continuity of
-// all stored EBlocks and Entries.
+// all stored EBlocks and Entries. All state, including the state hash of each
+// EBlock, is recomputed and compared against what is stored.
 //
 // This does not validate the validity of the saved DBlock KeyMRs.
 func (chain *FATChain) Validate(ctx context.Context, repair bool) (err error) {
@@ -75,40 +78,113 @@ func (chain *FATChain) Validate(ctx context.Context, repair bool) (err error) {
 	sess.Attach("eblock")
 	sess.Attach("entry")
 	sess.Attach("address")
+	sess.Attach("address_balance_delta")
 	sess.Attach("nftoken")
 	sess.Attach("metadata")
+	// The "entry_invalid" table is deliberately not attached so that the
+	// reasons for invalid entries may be populated by validation after
+	// the table is first added.
 	defer sess.Delete()
 
 	// In case there are any changes, we want to roll back everything. We
 	// don't fix corrupted databases, at least not yet.
 	defer chain.Save()(&err)
 
+	if err := chain.replay(ctx, read, math.MaxUint32); err != nil {
+		return err
+	}
+
+	var changeset bytes.Buffer
+	if err := sess.Changeset(&changeset); err != nil {
+		return fmt.Errorf("sqlite.Session.Changeset(): %w", err)
+	}
+
+	iter, err := sqlite.ChangesetIterStart(&changeset)
+	if err != nil {
+		return fmt.Errorf("sqlite.ChangesetIterStart(): %w", err)
+	}
+	defer func() {
+		if err := iter.Finalize(); err != nil {
+			chain.Log.Errorf("sqlite.ChangesetIter.Finalize(): %w", err)
+		}
+	}()
+	hasRow, err := iter.Next()
+	if err != nil {
+		return fmt.Errorf("sqlite.ChangesetIter.Next(): %w", err)
+	}
+	if hasRow {
+		if repair {
+			chain.Log.Warnf("Corrupted state repaired!")
+			return nil
+		}
+		chain.Log.Error("Corrupted state!")
+		// Write the changeset to a file for later analysis...
+		path := fmt.Sprintf("%v%v-corrupt-%v.changeset",
+			flag.DBPath, chain.ID.String(), time.Now().Unix())
+		chain.Log.Warnf("writing corrupted state changeset to %v", path)
+		f, err := os.Create(path)
+		if err != nil {
+			return fmt.Errorf("os.Create(): %w", err)
+		}
+		if err := sess.Changeset(f); err != nil {
+			return fmt.Errorf("sqlite.Session.Changeset(): %w", err)
+		}
+		if err := f.Close(); err != nil {
+			return fmt.Errorf("os.File.Close(): %w", err)
+		}
+		return fmt.Errorf("Corrupted state!")
+	}
+	return nil
+}
+
+// replay clears all state and chain data and then re-applies all EBlocks and
+// Entries from read, up to and including the given DBlock height. The read
+// Conn must not observe the writes to chain.Conn, so that the original chain
+// data remains available for the duration of the replay.
+//
+// The continuity of all EBlocks and Entries is checked along the way.
+func (chain *FATChain) replay(ctx context.Context,
+	read *sqlite.Conn, height uint32) error {
+	write := chain.Conn
 	chain.Head = factom.EBlock{}
 	chain.SyncHeight = 0
 	chain.SyncDBKeyMR = nil
 
-	// Completely clear the state, while preserving all chain data.
-	err = sqlitex.ExecScript(write, `
+	// Completely clear the state. All chain data is re-inserted from read.
+	err := sqlitex.ExecScript(write, `
                 UPDATE "address" SET "balance" = 0;
                 DELETE FROM "address_tx";
+                DELETE FROM "address_balance_delta";
                 DELETE FROM "nftoken";
                 DELETE FROM "nftoken_tx";
                 DELETE FROM "eblock";
+                DELETE FROM "entry_invalid";
                 DELETE FROM "entry";
+                DELETE FROM "nftoken_search";
+                DELETE FROM "issuance_search";
                 UPDATE "fat_chain" SET ("init_entry_id", "num_issued") = (NULL, NULL);
                 `)
 	if err != nil {
 		return err
 	}
+	for _, std := range standard.All() {
+		for _, table := range std.Tables() {
+			if err := sqlitex.ExecScript(write, fmt.Sprintf(
+				`DELETE FROM %q;`, table)); err != nil {
+				return err
+			}
+		}
+	}
 	chain.NumIssued = 0
 	chain.Issuance = fat.Issuance{}
 
 	eBlockStmt, _, err := read.PrepareTransient(
-		eblock.SelectWhere + `true;`) // SELECT all EBlocks.
+		eblock.SelectWhere + `"db_height" <= ?;`) // SELECT EBlocks to height.
 	if err != nil {
 		panic(err)
 	}
 	defer eBlockStmt.Finalize()
+	eBlockStmt.BindInt64(1, int64(height))
 	entryStmt, _, err := read.PrepareTransient(
 		entry.SelectWhere + `true;`) // SELECT all Entries.
 	if err != nil {
@@ -185,49 +261,5 @@ func (chain *FATChain) Validate(ctx context.Context, repair bool) (err error) {
 	if sequence == 0 {
 		return fmt.Errorf("no eblocks")
 	}
-	if ctx.Err() != nil {
-		return ctx.Err()
-	}
-
-	var changeset bytes.Buffer
-	if err := sess.Changeset(&changeset); err != nil {
-		return fmt.Errorf("sqlite.Session.Changeset(): %w", err)
-	}
-
-	iter, err := sqlite.ChangesetIterStart(&changeset)
-	if err != nil {
-		return fmt.Errorf("sqlite.ChangesetIterStart(): %w", err)
-	}
-	defer func() {
-		if err := iter.Finalize(); err != nil {
-			chain.Log.Errorf("sqlite.ChangesetIter.Finalize(): %w", err)
-		}
-	}()
-	hasRow, err := iter.Next()
-	if err != nil {
-		return fmt.Errorf("sqlite.ChangesetIter.Next(): %w", err)
-	}
-	if hasRow {
-		if repair {
-			chain.Log.Warnf("Corrupted state repaired!")
-			return nil
-		}
-		chain.Log.Error("Corrupted state!")
-		// Write the changeset to a file for later analysis...
-		path := fmt.Sprintf("%v%v-corrupt-%v.changeset",
-			flag.DBPath, chain.ID.String(), time.Now().Unix())
-		chain.Log.Warnf("writing corrupted state changeset to %v", path)
-		f, err := os.Create(path)
-		if err != nil {
-			return fmt.Errorf("os.Create(): %w", err)
-		}
-		if err := sess.Changeset(f); err != nil {
-			return fmt.Errorf("sqlite.Session.Changeset(): %w", err)
-		}
-		if err := f.Close(); err != nil {
-			return fmt.Errorf("os.File.Close(): %w", err)
-		}
-		return fmt.Errorf("Corrupted state!")
-	}
-	return nil
+	return ctx.Err()
 }
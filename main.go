@@ -30,6 +30,7 @@ import (
 	"github.com/Factom-Asset-Tokens/fatd/internal/engine"
 	"github.com/Factom-Asset-Tokens/fatd/internal/flag"
 	"github.com/Factom-Asset-Tokens/fatd/internal/log"
+	"github.com/Factom-Asset-Tokens/fatd/internal/outbox"
 	"github.com/Factom-Asset-Tokens/fatd/internal/srv"
 )
 
@@ -55,6 +56,33 @@ func _main() (ret int) {
 	}()
 
 	log.Info("Fatd Version: ", flag.Revision)
+
+	switch flag.Command {
+	case "rewind":
+		if err := engine.RewindDB(ctx, &flag.RewindChainID,
+			uint32(flag.RewindHeight)); err != nil {
+			log.Errorf("engine.RewindDB(): %v", err)
+			return 1
+		}
+		return 0
+	case "snapshot":
+		switch flag.SnapshotCommand {
+		case "export":
+			if err := engine.ExportSnapshot(ctx, flag.SnapshotOut,
+				flag.SnapshotSK1); err != nil {
+				log.Errorf("engine.ExportSnapshot(): %v", err)
+				return 1
+			}
+		case "import":
+			if err := engine.ImportSnapshot(ctx, flag.SnapshotIn,
+				flag.SnapshotID1, flag.SnapshotValidate); err != nil {
+				log.Errorf("engine.ImportSnapshot(): %v", err)
+				return 1
+			}
+		}
+		return 0
+	}
+
 	defer log.Info("Factom Asset Token Daemon stopped.")
 
 	// Engine
@@ -68,6 +96,17 @@ func _main() (ret int) {
 	}()
 	log.Info("State engine started.")
 
+	// Outbox
+	outboxDone := outbox.Start(ctx, flag.FactomClient)
+	if outboxDone == nil {
+		return 1
+	}
+	defer func() {
+		<-outboxDone // Wait for outbox to stop.
+		log.Info("Submission outbox stopped.")
+	}()
+	log.Info("Submission outbox started.")
+
 	// Server
 	srvDone := srv.Start(ctx)
 	if srvDone == nil {